@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var errConfigSyntax = errors.New("syntax error")
+
+// configEntry is a key and the values set in the configuration file.
+type configEntry struct {
+	line   int
+	key    string
+	values []string
+	list   bool
+}
+
+// loadConfig sets options from a configuration file. Options set on the
+// command line take precedence over the configuration file. The command
+// set by the "command" key is returned.
+//
+// The configuration file is a subset of TOML: one option per line using
+// the option name as the key, e.g.:
+//
+//	# goreap.toml
+//	signal = "INT"
+//	deadline = "10s"
+//	env = ["PORT=8080", "DEBUG=1"]
+//	command = ["nc", "-l", "8080"]
+//
+// Arrays set options which may be repeated.
+func loadConfig(fs *flag.FlagSet, path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := parseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var argv []string
+
+	for _, e := range entries {
+		if e.key == "command" {
+			argv = e.values
+			continue
+		}
+
+		f := fs.Lookup(e.key)
+		if f == nil || e.key == "config" {
+			return nil, fmt.Errorf("%s:%d: unknown option: %s", path, e.line, e.key)
+		}
+
+		if _, ok := f.Value.(*stringList); e.list && !ok {
+			return nil, fmt.Errorf("%s:%d: %s: option cannot be repeated", path, e.line, e.key)
+		}
+
+		if set[e.key] {
+			continue
+		}
+
+		for _, v := range e.values {
+			if err := fs.Set(e.key, v); err != nil {
+				return nil, fmt.Errorf("%s:%d: %s: %w", path, e.line, e.key, err)
+			}
+		}
+	}
+
+	return argv, nil
+}
+
+// parseConfig reads the keys and values from a configuration file.
+func parseConfig(r io.Reader) ([]configEntry, error) {
+	entries := make([]configEntry, 0)
+	keys := make(map[string]struct{})
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t[]\"'") {
+			return nil, fmt.Errorf("%d: %w: %s", n, errConfigSyntax, line)
+		}
+
+		if _, ok := keys[key]; ok {
+			return nil, fmt.Errorf("%d: duplicate key: %s", n, key)
+		}
+		keys[key] = struct{}{}
+
+		e, err := parseConfigValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s: %w", n, key, err)
+		}
+		e.line = n
+		e.key = key
+
+		entries = append(entries, e)
+	}
+
+	return entries, s.Err()
+}
+
+// parseConfigValue parses a value: a quoted string, an array of quoted
+// strings or a bare value such as a number or boolean. The value may be
+// followed by a comment.
+func parseConfigValue(s string) (configEntry, error) {
+	var e configEntry
+
+	if !strings.HasPrefix(s, "[") {
+		v, rest, err := configString(s)
+		if err != nil {
+			return e, err
+		}
+		if err := configComment(rest); err != nil {
+			return e, err
+		}
+		e.values = []string{v}
+		return e, nil
+	}
+
+	e.list = true
+	e.values = make([]string, 0)
+
+	rest := strings.TrimSpace(s[1:])
+	for !strings.HasPrefix(rest, "]") {
+		v, r, err := configString(rest)
+		if err != nil {
+			return e, err
+		}
+		e.values = append(e.values, v)
+
+		rest = strings.TrimSpace(r)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		} else if !strings.HasPrefix(rest, "]") {
+			return e, fmt.Errorf("%w: %s", errConfigSyntax, s)
+		}
+	}
+
+	return e, configComment(rest[1:])
+}
+
+// configString returns a quoted string or a bare value from the start of
+// the input and the remaining input.
+func configString(s string) (string, string, error) {
+	if s == "" {
+		return "", "", fmt.Errorf("%w: missing value", errConfigSyntax)
+	}
+
+	switch s[0] {
+	case '"':
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				v, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					return "", "", fmt.Errorf("%w: %s", errConfigSyntax, s[:i+1])
+				}
+				return v, s[i+1:], nil
+			}
+		}
+	case '\'':
+		if i := strings.IndexByte(s[1:], '\''); i != -1 {
+			return s[1 : i+1], s[i+2:], nil
+		}
+	default:
+		i := strings.IndexAny(s, " \t,]#")
+		if i == -1 {
+			i = len(s)
+		}
+		if i > 0 {
+			return s[:i], s[i:], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%w: %s", errConfigSyntax, s)
+}
+
+// configComment returns an error if the input is not empty or a comment.
+func configComment(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" || s[0] == '#' {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errConfigSyntax, s)
+}
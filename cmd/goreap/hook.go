@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/msantos/goreap/reap"
+)
+
+// hookCommand returns a hook running a command using the shell. The
+// event is described by environment variables:
+//
+//	GOREAP_EVENT: pre-start, post-stop or child-exit
+//	GOREAP_PID: the pid of the exited process
+//	GOREAP_STATUS: the exit status of the exited process or 128 plus
+//	  the signal number if the process was terminated by a signal
+func hookCommand(command string) func(reap.Event) {
+	return func(ev reap.Event) {
+		cmd := exec.Command(shell(), "-c", command)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), "GOREAP_EVENT="+ev.Hook.String())
+
+		if ev.Pid > 0 {
+			status := ev.Status.ExitStatus()
+			if ev.Status.Signaled() {
+				status = 128 + int(ev.Status.Signal())
+			}
+			cmd.Env = append(cmd.Env,
+				"GOREAP_PID="+strconv.Itoa(ev.Pid),
+				"GOREAP_STATUS="+strconv.Itoa(status),
+			)
+		}
+
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", ev.Hook, err)
+		}
+	}
+}
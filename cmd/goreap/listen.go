@@ -0,0 +1,118 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/msantos/goreap/subreaper"
+)
+
+// listenExecEnv is set in the environment of the foreground process
+// started by the goreap executable to set LISTEN_PID to the pid of the
+// command.
+const listenExecEnv = "GOREAP_LISTEN_EXEC"
+
+// listenFdsStart is the first file descriptor passed by socket
+// activation (SD_LISTEN_FDS_START).
+const listenFdsStart = 3
+
+// listenFds returns the file descriptors passed by socket activation
+// (see sd_listen_fds(3)) and the environment describing the file
+// descriptors. The file descriptors are set close-on-exec and the
+// socket activation variables are removed from the environment:
+// processes other than the foreground process, e.g., health checks, do
+// not inherit the file descriptors.
+func listenFds() ([]*os.File, []string, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	nfds := os.Getenv("LISTEN_FDS")
+	if nfds == "" {
+		return nil, nil, nil
+	}
+
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil, nil
+	}
+
+	n, err := strconv.Atoi(nfds)
+	if err != nil || n < 0 {
+		return nil, nil, fmt.Errorf("invalid LISTEN_FDS: %s", nfds)
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	if len(names) != n {
+		names = nil
+	}
+
+	files := make([]*os.File, 0, n)
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if names != nil {
+			name = names[fd-listenFdsStart]
+		}
+		syscall.CloseOnExec(fd)
+		files = append(files, os.NewFile(uintptr(fd), name))
+	}
+
+	env := []string{"LISTEN_FDS=" + nfds, listenExecEnv + "=1"}
+	if names != nil {
+		env = append(env, "LISTEN_FDNAMES="+os.Getenv("LISTEN_FDNAMES"))
+	}
+
+	return files, env, nil
+}
+
+// listenCommand returns the command run by the goreap executable to set
+// LISTEN_PID (see listenExec).
+func listenCommand(argv []string) ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{exe}, argv...), nil
+}
+
+// listenExec runs the command with LISTEN_PID set to the pid of the
+// command if goreap was run by listenCommand. The pid of the foreground
+// process is not known until the process is started: the process sets
+// LISTEN_PID and executes the command.
+func listenExec() {
+	if os.Getenv(listenExecEnv) == "" {
+		return
+	}
+
+	os.Unsetenv(listenExecEnv)
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	if len(os.Args) < 2 {
+		os.Exit(127)
+	}
+
+	// The subreaper attribute set by the reap package is inherited by
+	// the command.
+	var err error
+	if subreaper.Get() {
+		err = subreaper.Release()
+	}
+
+	arg0 := ""
+	if err == nil {
+		arg0, err = exec.LookPath(os.Args[1])
+	}
+	if err == nil {
+		err = syscall.Exec(arg0, os.Args[1:], os.Environ())
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[1], err)
+	os.Exit(127)
+}
@@ -0,0 +1,15 @@
+package main
+
+import "os"
+
+// listenFds returns the file descriptors passed by socket activation:
+// socket activation is not supported on Windows.
+func listenFds() ([]*os.File, []string, error) {
+	return nil, nil, nil
+}
+
+func listenCommand(argv []string) ([]string, error) {
+	return argv, nil
+}
+
+func listenExec() {}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/msantos/goreap/process"
 	"github.com/msantos/goreap/reap"
 )
 
@@ -15,18 +19,249 @@ var version = "0.11.0"
 func usage() {
 	fmt.Fprintf(os.Stderr, `%s v%s
 Usage: %s [options] <command> <...>
+       %s [options] -c <command string> [<arg0> <...>]
+       %s [options] -adopt <pid>
 
 Options:
-`, path.Base(os.Args[0]), version, os.Args[0])
+`, path.Base(os.Args[0]), version, os.Args[0], os.Args[0], os.Args[0])
 	flag.PrintDefaults()
 }
 
+// stringList is a flag which can be set multiple times.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// signalFlag is a flag set to a signal name ("TERM" or "SIGTERM") or
+// number.
+type signalFlag syscall.Signal
+
+func (s *signalFlag) String() string {
+	return strconv.Itoa(int(*s))
+}
+
+func (s *signalFlag) Set(v string) error {
+	sig, err := reap.ParseSignal(v)
+	if err != nil {
+		return err
+	}
+	*s = signalFlag(sig)
+	return nil
+}
+
+func shell() string {
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return sh
+	}
+	return "/bin/sh"
+}
+
+// parseSignalMap parses a comma separated list of signal mappings:
+// INT:TERM,HUP:USR1
+func parseSignalMap(s string) (map[os.Signal]os.Signal, error) {
+	m := make(map[os.Signal]os.Signal)
+	if s == "" {
+		return m, nil
+	}
+
+	for _, kv := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(kv, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid signal mapping: %s", kv)
+		}
+		fromSig, err := reap.ParseSignal(from)
+		if err != nil {
+			return nil, err
+		}
+		toSig, err := reap.ParseSignal(to)
+		if err != nil {
+			return nil, err
+		}
+		m[fromSig] = toSig
+	}
+
+	return m, nil
+}
+
+// parseOOMScoreAdj parses the OOM score adjustment of goreap and the
+// command: self[:child]. The score adjustment of the command defaults
+// to 0.
+func parseOOMScoreAdj(s string) (int, int, error) {
+	self, child, ok := strings.Cut(s, ":")
+	if !ok {
+		child = "0"
+	}
+
+	a, err := strconv.Atoi(self)
+	if err != nil || a < -1000 || a > 1000 {
+		return 0, 0, fmt.Errorf("invalid score adjustment: %s", self)
+	}
+
+	b, err := strconv.Atoi(child)
+	if err != nil || b < -1000 || b > 1000 {
+		return 0, 0, fmt.Errorf("invalid score adjustment: %s", child)
+	}
+
+	return a, b, nil
+}
+
+// parseNamespaces parses a comma separated list of namespaces: mount,
+// uts or ipc.
+func parseNamespaces(s string) (reap.Namespace, error) {
+	var ns reap.Namespace
+	for _, name := range strings.Split(s, ",") {
+		switch name {
+		case "mount":
+			ns |= reap.NamespaceMount
+		case "uts":
+			ns |= reap.NamespaceUTS
+		case "ipc":
+			ns |= reap.NamespaceIPC
+		default:
+			return 0, fmt.Errorf("invalid namespace: %s", name)
+		}
+	}
+	return ns, nil
+}
+
+// capabilityList splits comma separated lists of capabilities.
+func capabilityList(s []string) []string {
+	caps := make([]string, 0, len(s))
+	for _, v := range s {
+		caps = append(caps, strings.Split(v, ",")...)
+	}
+	return caps
+}
+
+// excludeFilter returns a function matching processes by name from a
+// comma separated list. Process names are truncated to 15 characters
+// by the kernel.
+func excludeFilter(s string) func(process.PID) bool {
+	if s == "" {
+		return nil
+	}
+
+	names := make(map[string]struct{})
+	for _, name := range strings.Split(s, ",") {
+		names[name] = struct{}{}
+	}
+
+	return func(p process.PID) bool {
+		_, ok := names[p.Comm]
+		return ok
+	}
+}
+
+// parseEscalation parses a comma separated list of signals and
+// timeouts: TERM:10s,INT:5s,KILL
+func parseEscalation(s string) ([]reap.Step, error) {
+	steps := make([]reap.Step, 0)
+	if s == "" {
+		return steps, nil
+	}
+
+	for _, v := range strings.Split(s, ",") {
+		name, timeout, ok := strings.Cut(v, ":")
+		sig, err := reap.ParseSignal(name)
+		if err != nil {
+			return nil, err
+		}
+		step := reap.Step{Signal: sig}
+		if ok {
+			step.Timeout, err = time.ParseDuration(timeout)
+			if err != nil {
+				return nil, err
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
 func main() {
+	listenExec()
+
 	flag.Usage = func() { usage() }
 
-	sig := flag.Int("signal", 15, "signal sent to supervised processes")
+	command := flag.String("c", "",
+		"run command using the shell ($SHELL or /bin/sh)")
+	config := flag.String("config", "",
+		"read options and the command from a file: options set on the command line take precedence")
+	adopt := flag.Int("adopt", 0,
+		"supervise a running process instead of running a command")
+	sig := signalFlag(syscall.SIGTERM)
+	flag.Var(&sig, "signal",
+		"`signal` name or number sent to supervised processes (0 to check processes are running)")
+	signalMap := flag.String("signal-map", "",
+		"translate signals forwarded to processes, e.g., INT:TERM,HUP:USR1")
+	doubleSignal := flag.Bool("double-signal", false,
+		"send SIGKILL when a shutdown signal (SIGINT, SIGTERM, SIGQUIT) is repeated")
+	escalation := flag.String("escalation", "",
+		"sequence of signals and timeouts sent to processes, e.g., TERM:10s,INT:5s,KILL")
+	var setenv, unsetenv stringList
+	flag.Var(&setenv, "env",
+		"set an environment variable for the command: KEY=VALUE (may be repeated)")
+	flag.Var(&unsetenv, "unsetenv",
+		"remove an environment variable from the environment of the command (may be repeated)")
+	clearenv := flag.Bool("clearenv", false,
+		"start the command with an empty environment")
+	envMarker := flag.Bool("env-marker", false,
+		"signal processes with the GOREAP_JOB environment marker")
+	oomScoreAdj := flag.String("oom-score-adj", "",
+		"OOM killer score adjustment of goreap and the command: self[:child], e.g., -1000:0")
+	var rlimits stringList
+	flag.Var(&rlimits, "rlimit",
+		"set a resource limit of the command: name=soft:hard, e.g., nofile=1024:4096 or core=unlimited (may be repeated)")
+	var successStatus stringList
+	flag.Var(&successStatus, "e",
+		"exit with status 0 if the command exits with the status, e.g., 143 (may be repeated)")
+	signalStatus := flag.Int("exit-status-on-signal", -1,
+		"exit status if the command is terminated by a signal (-1 to disable)")
+	excludeComm := flag.String("exclude-comm", "",
+		"comma separated list of process names which are never signaled")
+	var forceSig signalFlag
+	flag.Var(&forceSig, "force-shutdown-signal",
+		"`signal` name or number triggering termination of all processes including the foreground process (0 to disable)")
+	chroot := flag.String("chroot", "",
+		"change the root directory of the command")
+	cgroup := flag.String("cgroup", "",
+		"run the command in a cgroup v2 cgroup, created if it does not exist")
+	continueStopped := flag.Bool("continue-stopped", false,
+		"send SIGCONT to stopped processes before signaling")
+	checkpoint := flag.Duration(
+		"checkpoint-log",
+		0,
+		"log processes remaining at intervals while reaping (requires -verbose) (0 to disable)",
+	)
+	descendantEvents := flag.Bool("descendant-events", false,
+		"log changes in the number of subprocesses (requires -verbose)")
+	dir := flag.String("dir", "",
+		"working directory of the command")
 	disableSetuid := flag.Bool("disable-setuid", false,
 		"disallow setuid (unkillable) subprocesses")
+	var capDrop, capAdd stringList
+	flag.Var(&capDrop, "cap-drop",
+		"comma separated list of capabilities dropped from the command or ALL (may be repeated)")
+	flag.Var(&capAdd, "cap-add",
+		"comma separated list of capabilities kept and raised in the ambient set of the command (may be repeated)")
+	seccomp := flag.String("seccomp", "",
+		"install a seccomp filter in the command from a JSON profile")
+	pidns := flag.Bool("pidns", false,
+		"run the command in a new PID namespace with goreap as the init process")
+	unshare := flag.String("unshare", "",
+		"run the command in new namespaces: comma separated list of mount, uts and ipc")
+	hostname := flag.String("hostname", "",
+		"set the hostname of the command in a new UTS namespace (implies -pidns)")
+	reexec := flag.Bool("reexec", false,
+		"re-execute in a PID namespace if not a subreaper")
 	wait := flag.Bool("wait", false, "wait for subprocesses to exit")
 	deadline := flag.Duration(
 		"deadline",
@@ -38,6 +273,90 @@ func main() {
 		1*time.Second,
 		"delay between signals (0 to disable)",
 	)
+	forward := flag.String("forward", "descendants",
+		"processes receiving forwarded signals: descendants, child, group")
+	forcedKillStatus := flag.Int("forced-kill-status", -1,
+		"exit status if processes were sent SIGKILL (-1 to disable)")
+	healthCmd := flag.String("health-cmd", "",
+		"command checking the health of the command, run using the shell at each interval")
+	healthInterval := flag.Duration(
+		"health-interval",
+		30*time.Second,
+		"interval between health checks: a health check running longer than the interval fails",
+	)
+	healthRetries := flag.Int("health-retries", 3,
+		"number of consecutive health check failures before taking the health check action")
+	healthAction := flag.String("health-action", "signal",
+		"action taken when the health check fails: signal, restart, exit")
+	ignoreSigpipe := flag.Bool("ignore-sigpipe", false,
+		"ignore SIGPIPE in the foreground process")
+	maxDepth := flag.Int("max-depth", 0,
+		"depth of the process tree signaled: 1 signals children only (0 to signal all descendants)")
+	maxSignalPasses := flag.Int("max-signal-passes", 0,
+		"send SIGKILL after signalling processes the number of times (0 to disable)")
+	minGrace := flag.Duration(
+		"min-grace",
+		0,
+		"minimum time after the first signal before sending SIGKILL",
+	)
+	noEscalate := flag.Bool("no-escalate", false,
+		"do not send SIGKILL after the deadline")
+	pidfile := flag.String("pidfile", "",
+		"write the pid of goreap to a file, removed on exit")
+	childPidfile := flag.String("child-pidfile", "",
+		"write the pid of the command to a file, removed on exit")
+	pidFd := flag.Int("pid-fd", -1,
+		"write the pid of the command to an open file descriptor (-1 to disable)")
+	progressDeadline := flag.Bool("progress-deadline", false,
+		"restart the deadline when processes exit")
+	reapTimeout := flag.Duration(
+		"reap-timeout",
+		0,
+		"exit if processes running after timeout (0 to disable)",
+	)
+	reapTimeoutStatus := flag.Int("reap-timeout-status", 112,
+		"exit status if processes running after reap timeout")
+	restart := flag.String("restart", "never",
+		"restart the command when it exits: never, on-failure, always")
+	restartBackoff := flag.Duration(
+		"restart-backoff",
+		1*time.Second,
+		"delay before restarting the command, doubled for each restart",
+	)
+	preHook := flag.String("pre", "",
+		"command run using the shell before the command is started")
+	postHook := flag.String("post", "",
+		"command run using the shell after the command exits")
+	childExitHook := flag.String("on-child-exit", "",
+		"command run using the shell when a subprocess is reaped")
+	restartMaxRetries := flag.Int("restart-max-retries", 0,
+		"maximum number of restarts (0 for unlimited)")
+	var restartSig signalFlag
+	flag.Var(&restartSig, "restart-signal",
+		"`signal` name or number restarting goreap without terminating processes (requires -state-file) (0 to disable)")
+	stateFile := flag.String("state-file", "",
+		"path to file saving process state when restarting")
+	shutdownBudget := flag.Duration(
+		"shutdown-budget",
+		0,
+		"total shutdown time: sets the deadline to a fraction of the budget (0 to disable)",
+	)
+	shutdownBudgetFraction := flag.Float64("shutdown-budget-fraction", 0.8,
+		"fraction of the shutdown budget before sending SIGKILL")
+	stdin := flag.String("stdin", "",
+		"read standard input of the command from a file")
+	stats := flag.Bool("stats", false,
+		"print a summary, the exit statuses and resource usage of reaped processes on exit")
+	summary := flag.Bool("summary", false,
+		"print a one-line summary on exit")
+	trigger := flag.String("shutdown-trigger", "exit",
+		"event terminating processes: exit, failure, child-exit, signal")
+	tty := flag.Bool("tty", false,
+		"run the command in a pseudo-terminal")
+	umask := flag.Int("umask", -1,
+		"file mode creation mask of the command, e.g., 022 (-1 to inherit)")
+	runAs := flag.String("user", "",
+		"run the command as a user and group: name[:group]")
 	showVersion := flag.Bool("version", false, "display version and exit")
 	verbose := flag.Bool("verbose", false, "debug output")
 
@@ -48,27 +367,321 @@ func main() {
 		os.Exit(0)
 	}
 
-	if flag.NArg() < 1 {
+	argv := flag.Args()
+
+	if *config != "" {
+		// A command set on the command line replaces the command in
+		// the configuration file.
+		cmdline := len(argv) > 0 || *command != ""
+		configArgv, err := loadConfig(flag.CommandLine, *config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "config: %s\n", err)
+			os.Exit(2)
+		}
+		if !cmdline {
+			argv = configArgv
+		}
+	}
+
+	if os.Getenv(reap.ReexecEnv) != "" {
+		// The pid files are written by the process re-executing goreap:
+		// pids in the new PID namespace are not visible to the host.
+		*pidfile = ""
+		*childPidfile = ""
+		*pidFd = -1
+	}
+
+	triggers := map[string]reap.Trigger{
+		"exit":       reap.TriggerExit,
+		"failure":    reap.TriggerFailure,
+		"child-exit": reap.TriggerChildExit,
+		"signal":     reap.TriggerSignal,
+	}
+
+	shutdownTrigger, ok := triggers[*trigger]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "invalid shutdown trigger: %s\n", *trigger)
+		os.Exit(2)
+	}
+
+	signals, err := parseSignalMap(*signalMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "signal-map: %s\n", err)
+		os.Exit(2)
+	}
+
+	steps, err := parseEscalation(*escalation)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "escalation: %s\n", err)
+		os.Exit(2)
+	}
+
+	for _, kv := range setenv {
+		if key, _, ok := strings.Cut(kv, "="); !ok || key == "" {
+			fmt.Fprintf(os.Stderr, "invalid environment variable: %s\n", kv)
+			os.Exit(2)
+		}
+	}
+
+	successStatuses := make([]int, 0, len(successStatus))
+	for _, v := range successStatus {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 255 {
+			fmt.Fprintf(os.Stderr, "invalid exit status: %s\n", v)
+			os.Exit(2)
+		}
+		successStatuses = append(successStatuses, n)
+	}
+
+	forwards := map[string]reap.Forward{
+		"descendants": reap.ForwardDescendants,
+		"child":       reap.ForwardChild,
+		"group":       reap.ForwardGroup,
+	}
+
+	forwardTo, ok := forwards[*forward]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "invalid forward: %s\n", *forward)
+		os.Exit(2)
+	}
+
+	restarts := map[string]reap.RestartMode{
+		"never":      reap.RestartNever,
+		"on-failure": reap.RestartOnFailure,
+		"always":     reap.RestartAlways,
+	}
+
+	restartMode, ok := restarts[*restart]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "invalid restart policy: %s\n", *restart)
+		os.Exit(2)
+	}
+
+	actions := map[string]reap.Action{
+		"signal":  reap.ActionSignal,
+		"restart": reap.ActionRestart,
+		"exit":    reap.ActionExit,
+	}
+
+	onFail, ok := actions[*healthAction]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "invalid health action: %s\n", *healthAction)
+		os.Exit(2)
+	}
+
+	if *healthCmd != "" && (*healthInterval <= 0 || *healthRetries < 1) {
+		fmt.Fprintf(os.Stderr, "health check: invalid interval or retries: %s, %d\n",
+			*healthInterval, *healthRetries)
+		os.Exit(2)
+	}
+
+	if *command != "" {
+		argv = append([]string{shell(), "-c", *command}, argv...)
+	}
+
+	if len(argv) < 1 && *adopt <= 0 {
 		flag.Usage()
 		os.Exit(2)
 	}
 
-	r := reap.New(
+	listen, listenEnv, err := listenFds()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "socket activation: %s\n", err)
+		os.Exit(2)
+	}
+
+	if len(listen) > 0 && *adopt <= 0 {
+		if *chroot != "" {
+			fmt.Fprintf(os.Stderr, "socket activation: not supported with -chroot\n")
+			os.Exit(2)
+		}
+		argv, err = listenCommand(argv)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "socket activation: %s\n", err)
+			os.Exit(111)
+		}
+		setenv = append(setenv, listenEnv...)
+	}
+
+	opts := []reap.Option{
+		reap.WithAddCapabilities(capabilityList(capAdd)),
+		reap.WithCgroup(*cgroup),
+		reap.WithCheckpointLog(*checkpoint),
+		reap.WithChroot(*chroot),
+		reap.WithClearenv(*clearenv),
+		reap.WithContinueStopped(*continueStopped),
 		reap.WithDeadline(*deadline),
 		reap.WithDelay(*delay),
+		reap.WithDescendantEvents(*descendantEvents),
+		reap.WithDir(*dir),
 		reap.WithDisableSetuid(*disableSetuid),
-		reap.WithSignal(*sig),
+		reap.WithDoubleSignal(*doubleSignal),
+		reap.WithDropCapabilities(capabilityList(capDrop)),
+		reap.WithEnv(setenv),
+		reap.WithEnvMarker(*envMarker),
+		reap.WithEscalation(steps),
+		reap.WithExcludeFilter(excludeFilter(*excludeComm)),
+		reap.WithExitStatusOnSignal(*signalStatus),
+		reap.WithForceShutdownSignal(int(forceSig)),
+		reap.WithForcedKillExitCode(*forcedKillStatus),
+		reap.WithForward(forwardTo),
+		reap.WithHostname(*hostname),
+		reap.WithIgnoreSigpipe(*ignoreSigpipe),
+		reap.WithInheritFds(listen),
+		reap.WithMaxDepth(*maxDepth),
+		reap.WithMaxSignalPasses(*maxSignalPasses),
+		reap.WithMinGrace(*minGrace),
+		reap.WithNoEscalate(*noEscalate),
+		reap.WithPidFd(*pidFd),
+		reap.WithProgressDeadline(*progressDeadline),
+		reap.WithPTY(*tty),
+		reap.WithReapTimeout(*reapTimeout),
+		reap.WithReapTimeoutStatus(*reapTimeoutStatus),
+		reap.WithNewPidNamespace(*pidns),
+		reap.WithReexec(*reexec),
+		reap.WithRestart(reap.RestartPolicy{
+			Mode:       restartMode,
+			MaxRetries: *restartMaxRetries,
+			Backoff:    *restartBackoff,
+		}),
+		reap.WithRestartSignal(int(restartSig)),
+		reap.WithShutdownTrigger(shutdownTrigger),
+		reap.WithSignal(int(sig)),
+		reap.WithSignalMap(signals),
+		reap.WithStateFile(*stateFile),
+		reap.WithStdinFile(*stdin),
+		reap.WithSuccessStatuses(successStatuses),
+		reap.WithUmask(*umask),
+		reap.WithUnsetenv(unsetenv),
 		reap.WithWait(*wait),
 		reap.WithLog(func(err error) {
 			if *verbose {
 				fmt.Println(err)
 			}
 		}),
-	)
+	}
 
-	status, err := r.Supervise(flag.Args(), os.Environ())
+	childPid := 0
+
+	if *childPidfile != "" {
+		opts = append(opts, reap.WithStartHandler(func(pid int) {
+			childPid = pid
+			if err := replacePidfile(*childPidfile, pid); err != nil {
+				fmt.Fprintf(os.Stderr, "child-pidfile: %s\n", err)
+			}
+		}))
+	}
+
+	if *healthCmd != "" {
+		opts = append(opts,
+			reap.WithHealthCheck([]string{shell(), "-c", *healthCmd}, *healthInterval, onFail),
+			reap.WithHealthRetries(*healthRetries),
+		)
+	}
+
+	for hook, command := range map[reap.Hook]string{
+		reap.HookPreStart:  *preHook,
+		reap.HookPostStop:  *postHook,
+		reap.HookChildExit: *childExitHook,
+	} {
+		if command != "" {
+			opts = append(opts, reap.WithHook(hook, hookCommand(command)))
+		}
+	}
+
+	if *seccomp != "" {
+		profile, err := reap.LoadSeccompProfile(*seccomp)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "seccomp: %s\n", err)
+			os.Exit(2)
+		}
+		opts = append(opts, reap.WithSeccompProfile(profile))
+	}
+
+	if *unshare != "" {
+		ns, err := parseNamespaces(*unshare)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unshare: %s\n", err)
+			os.Exit(2)
+		}
+		opts = append(opts, reap.WithNamespaces(ns))
+	}
+
+	if *oomScoreAdj != "" {
+		self, child, err := parseOOMScoreAdj(*oomScoreAdj)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "oom-score-adj: %s\n", err)
+			os.Exit(2)
+		}
+		opts = append(opts, reap.WithOOMScoreAdj(self, child))
+	}
+
+	if len(rlimits) > 0 && *chroot != "" {
+		fmt.Fprintf(os.Stderr, "rlimit: not supported with -chroot\n")
+		os.Exit(2)
+	}
+
+	for _, v := range rlimits {
+		resource, soft, hard, err := parseRlimit(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "rlimit: %s\n", err)
+			os.Exit(2)
+		}
+		opts = append(opts, reap.WithRlimit(resource, soft, hard))
+	}
+
+	if *shutdownBudget > 0 {
+		opts = append(opts, reap.WithShutdownBudget(*shutdownBudget, *shutdownBudgetFraction))
+	}
+
+	if *runAs != "" {
+		uid, gid, groups, err := lookupUser(*runAs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "user: %s\n", err)
+			os.Exit(2)
+		}
+		opts = append(opts, reap.WithUser(uid, gid, groups))
+	}
+
+	r := reap.New(opts...)
+
+	if *pidfile != "" {
+		if err := createPidfile(*pidfile, os.Getpid()); err != nil {
+			fmt.Fprintf(os.Stderr, "pidfile: %s\n", err)
+			os.Exit(111)
+		}
+	}
+
+	var status int
+
+	if *adopt > 0 {
+		argv = []string{strconv.Itoa(*adopt)}
+		status, err = r.Adopt(*adopt)
+	} else {
+		status, err = r.Supervise(argv, os.Environ())
+	}
+
+	if *pidfile != "" {
+		removePidfile(*pidfile, os.Getpid())
+	}
+
+	if *childPidfile != "" {
+		removePidfile(*childPidfile, childPid)
+	}
 	if err != nil {
-		fmt.Printf("%s: %s\n", flag.Arg(0), err)
+		fmt.Printf("%s: %s\n", argv[0], err)
+	}
+
+	if *summary || *stats {
+		fmt.Printf("goreap: %s\n", r.Stats())
+	}
+
+	if *stats {
+		st := r.Stats()
+		fmt.Printf("goreap: restarts: %d\n", st.Restarts)
+		fmt.Printf("goreap: reaped: %s\n", st.Statuses())
+		fmt.Printf("goreap: rusage: user %s, system %s, maxrss %d KB\n",
+			st.UserTime, st.SystemTime, st.MaxRSS/1024)
 	}
 
 	os.Exit(status)
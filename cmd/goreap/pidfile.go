@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var errPidfileRunning = errors.New("process running")
+
+// createPidfile atomically creates a file containing the pid. If the
+// file exists and the process in the file is not running, the stale
+// file is replaced. The file may contain the pid if the process was
+// re-executed (see -restart-signal).
+func createPidfile(path string, pid int) error {
+	tmp, err := writeTemp(path, pid)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	for i := 0; i < 2; i++ {
+		err = os.Link(tmp, path)
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+
+		old, rerr := readPidfile(path)
+		if rerr == nil && old == pid {
+			return nil
+		}
+		if rerr == nil && running(old) {
+			return fmt.Errorf("%s: %w: %d", path, errPidfileRunning, old)
+		}
+
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// replacePidfile atomically writes a file containing the pid,
+// replacing any existing file.
+func replacePidfile(path string, pid int) error {
+	tmp, err := writeTemp(path, pid)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// removePidfile removes the file if it contains the pid.
+func removePidfile(path string, pid int) {
+	if p, err := readPidfile(path); err == nil && p == pid {
+		_ = os.Remove(path)
+	}
+}
+
+func writeTemp(path string, pid int) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = fmt.Fprintf(f, "%d\n", pid)
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+func readPidfile(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
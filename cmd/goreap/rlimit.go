@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/msantos/goreap/reap"
+)
+
+// parseRlimit parses a resource limit: name=soft:hard or name=limit to
+// set the soft and hard limits to the same value, e.g., nofile=1024:4096
+// or core=unlimited.
+func parseRlimit(s string) (int, uint64, uint64, error) {
+	name, limits, ok := strings.Cut(s, "=")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("invalid resource limit: %s", s)
+	}
+
+	resource, ok := rlimitResources[strings.ToLower(name)]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("unsupported resource: %s", name)
+	}
+
+	soft, hard, ok := strings.Cut(limits, ":")
+	if !ok {
+		hard = soft
+	}
+
+	cur, err := parseRlimitValue(soft)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	max, err := parseRlimitValue(hard)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if cur > max {
+		return 0, 0, 0, fmt.Errorf("soft limit exceeds hard limit: %s", s)
+	}
+
+	return resource, cur, max, nil
+}
+
+func parseRlimitValue(s string) (uint64, error) {
+	switch s {
+	case "unlimited", "infinity":
+		return reap.RlimitInfinity, nil
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resource limit: %s", s)
+	}
+
+	return n, nil
+}
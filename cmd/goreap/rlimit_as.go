@@ -0,0 +1,10 @@
+//go:build !windows && !openbsd
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// RLIMIT_AS is not defined on OpenBSD.
+func init() {
+	rlimitResources["as"] = unix.RLIMIT_AS
+}
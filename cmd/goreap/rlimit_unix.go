@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// rlimitResources are the resource names accepted by -rlimit.
+var rlimitResources = map[string]int{
+	"core":    unix.RLIMIT_CORE,
+	"cpu":     unix.RLIMIT_CPU,
+	"data":    unix.RLIMIT_DATA,
+	"fsize":   unix.RLIMIT_FSIZE,
+	"memlock": unix.RLIMIT_MEMLOCK,
+	"nofile":  unix.RLIMIT_NOFILE,
+	"nproc":   unix.RLIMIT_NPROC,
+	"rss":     unix.RLIMIT_RSS,
+	"stack":   unix.RLIMIT_STACK,
+}
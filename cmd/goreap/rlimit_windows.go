@@ -0,0 +1,5 @@
+package main
+
+// rlimitResources are the resource names accepted by -rlimit: resource
+// limits are not supported on Windows.
+var rlimitResources = map[string]int{}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// lookupUser returns the uid, gid and supplementary groups for a user
+// specified as name[:group]. The user and group may be a name or a
+// numeric id. If the group is not set, the primary group of the user is
+// used. A numeric user without a passwd entry runs with the group set
+// to the uid and no supplementary groups.
+func lookupUser(s string) (uid, gid int, groups []int, err error) {
+	name, group, _ := strings.Cut(s, ":")
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		n, aerr := strconv.Atoi(name)
+		if aerr != nil {
+			return 0, 0, nil, err
+		}
+		if u, err = user.LookupId(name); err != nil {
+			u = &user.User{Uid: name, Gid: strconv.Itoa(n)}
+		}
+	}
+
+	if uid, err = strconv.Atoi(u.Uid); err != nil {
+		return 0, 0, nil, err
+	}
+
+	if gid, err = strconv.Atoi(u.Gid); err != nil {
+		return 0, 0, nil, err
+	}
+
+	if group != "" {
+		if gid, err = lookupGroup(group); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	groups = []int{gid}
+
+	if u.Username == "" {
+		return uid, gid, groups, nil
+	}
+
+	ids, err := u.GroupIds()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	for _, id := range ids {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if n != gid {
+			groups = append(groups, n)
+		}
+	}
+
+	return uid, gid, groups, nil
+}
+
+// lookupGroup returns the gid of a group name or numeric id.
+func lookupGroup(s string) (int, error) {
+	g, err := user.LookupGroup(s)
+	if err == nil {
+		return strconv.Atoi(g.Gid)
+	}
+
+	if n, aerr := strconv.Atoi(s); aerr == nil {
+		return n, nil
+	}
+
+	return 0, err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/msantos/goreap/process"
+)
+
+// node is an entry in the process tree.
+type node struct {
+	Pid   int    `json:"pid"`
+	PPid  int    `json:"ppid"`
+	Comm  string `json:"comm"`
+	State string `json:"state"`
+
+	// set by -details
+	Uid       *int    `json:"uid,omitempty"`
+	Gid       *int    `json:"gid,omitempty"`
+	Pgrp      *int    `json:"pgrp,omitempty"`
+	Session   *int    `json:"session,omitempty"`
+	StartTime *uint64 `json:"starttime,omitempty"`
+
+	Children []*node `json:"children"`
+}
+
+// newNode returns the tree of nodes for a process tree.
+func newNode(t *process.Node, details bool) *node {
+	n := &node{
+		Pid:      t.Pid,
+		PPid:     t.PPid,
+		Comm:     t.Comm,
+		State:    string(t.State),
+		Children: make([]*node, 0, len(t.Children)),
+	}
+
+	if t.State == 0 {
+		// the process is not in the process table
+		n.State = ""
+	}
+
+	if details {
+		p := t.PID
+		n.Uid = &p.Uid
+		n.Gid = &p.Gid
+		n.Pgrp = &p.Pgrp
+		n.Session = &p.Session
+		n.StartTime = &p.StartTime
+	}
+
+	for _, c := range t.Children {
+		n.Children = append(n.Children, newNode(c, details))
+	}
+
+	return n
+}
+
+func writeJSON(w io.Writer, root *node) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(root)
+}
+
+func writeYAML(w io.Writer, root *node) error {
+	var b strings.Builder
+	yamlNode(&b, root, "")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// yamlNode writes a node as a YAML mapping. Strings are written as
+// double quoted scalars.
+func yamlNode(b *strings.Builder, n *node, indent string) {
+	fmt.Fprintf(b, "pid: %d\n", n.Pid)
+	fmt.Fprintf(b, "%sppid: %d\n", indent, n.PPid)
+	fmt.Fprintf(b, "%scomm: %s\n", indent, strconv.Quote(n.Comm))
+	fmt.Fprintf(b, "%sstate: %s\n", indent, strconv.Quote(n.State))
+
+	if n.Uid != nil {
+		fmt.Fprintf(b, "%suid: %d\n", indent, *n.Uid)
+		fmt.Fprintf(b, "%sgid: %d\n", indent, *n.Gid)
+		fmt.Fprintf(b, "%spgrp: %d\n", indent, *n.Pgrp)
+		fmt.Fprintf(b, "%ssession: %d\n", indent, *n.Session)
+		fmt.Fprintf(b, "%sstarttime: %d\n", indent, *n.StartTime)
+	}
+
+	if len(n.Children) == 0 {
+		fmt.Fprintf(b, "%schildren: []\n", indent)
+		return
+	}
+
+	fmt.Fprintf(b, "%schildren:\n", indent)
+	for _, c := range n.Children {
+		fmt.Fprintf(b, "%s  - ", indent)
+		yamlNode(b, c, indent+"    ")
+	}
+}
+
+func writeDOT(w io.Writer, root *node) error {
+	var b strings.Builder
+
+	b.WriteString("digraph pstree {\n")
+
+	var walk func(n *node)
+	walk = func(n *node) {
+		fmt.Fprintf(&b, "  %d [label=%s];\n", n.Pid,
+			strconv.Quote(fmt.Sprintf("%d %s %s", n.Pid, n.Comm, n.State)))
+		for _, c := range n.Children {
+			fmt.Fprintf(&b, "  %d -> %d;\n", n.Pid, c.Pid)
+			walk(c)
+		}
+	}
+	walk(root)
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// prune removes descendants below depth. A depth of 0 keeps the
+// complete tree.
+func prune(n *process.Node, depth int) *process.Node {
+	if depth <= 0 {
+		return n
+	}
+
+	var walk func(n *process.Node, level int)
+	walk = func(n *process.Node, level int) {
+		if level >= depth {
+			n.Children = nil
+			return
+		}
+		for _, c := range n.Children {
+			walk(c, level+1)
+		}
+	}
+	walk(n, 0)
+
+	return n
+}
+
+// writeText draws the process tree using branch characters. Unless
+// always is set, nothing is written if the process has no children.
+func writeText(w io.Writer, root *process.Node, describe func(int) string, always bool) error {
+	if len(root.Children) == 0 && !always {
+		return nil
+	}
+
+	_, err := root.Draw(w, func(n *process.Node) string {
+		return describe(n.Pid)
+	})
+	return err
+}
@@ -1,50 +1,203 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
 	"strconv"
 
 	"github.com/msantos/goreap/process"
 )
 
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: %s [<option>] <pid> [<snapshot: %s | %s>]
+       %s [<option>] -name <regexp> [<snapshot: %s | %s>]
+`,
+		os.Args[0], process.SnapshotPs, process.SnapshotChildren,
+		os.Args[0], process.SnapshotPs, process.SnapshotChildren,
+	)
+	flag.PrintDefaults()
+}
+
+// match returns the pids of processes with a command name matching a
+// regular expression. Matching processes descended from a matching
+// process are displayed in the tree of the ancestor and are not
+// returned.
+func match(pids []process.PID, re *regexp.Regexp) []int {
+	ppids := make(map[int]int, len(pids))
+	matched := make(map[int]bool)
+	for _, p := range pids {
+		ppids[p.Pid] = p.PPid
+		if re.MatchString(p.Comm) {
+			matched[p.Pid] = true
+		}
+	}
+
+	nested := func(pid int) bool {
+		seen := map[int]bool{pid: true}
+		for {
+			ppid, ok := ppids[pid]
+			if !ok || seen[ppid] {
+				return false
+			}
+			if matched[ppid] {
+				return true
+			}
+			seen[ppid] = true
+			pid = ppid
+		}
+	}
+
+	matches := make([]int, 0)
+	for _, p := range pids {
+		if matched[p.Pid] && !nested(p.Pid) {
+			matches = append(matches, p.Pid)
+		}
+	}
+	return matches
+}
+
 func main() {
-	snapshot := "any"
+	flag.Usage = func() { usage() }
+
+	details := flag.Bool("details", false,
+		"display the command name, state, owner, process group, session and start time")
+	format := flag.String("format", "text",
+		"output format for the process tree: text, json, yaml, dot")
+	depth := flag.Int("depth", 0,
+		"limit the depth of the process tree (0 for unlimited)")
+	preview := flag.Bool("reap-preview", false,
+		"list the processes signaled by goreap")
+	name := flag.String("name", "",
+		"display a process tree for each process with a command name matching a regular expression")
+
+	flag.Parse()
+
+	var write func(io.Writer, *node) error
 
-	switch len(os.Args) {
-	case 3:
-		snapshot = os.Args[2]
-	case 2:
+	switch *format {
+	case "text":
+	case "json":
+		write = writeJSON
+	case "yaml":
+		write = writeYAML
+	case "dot":
+		write = writeDOT
 	default:
-		fmt.Fprintf(os.Stderr, "usage: <pid> [<snapshot: %s | %s>]",
-			process.SnapshotPs, process.SnapshotChildren,
-		)
+		fmt.Fprintf(os.Stderr, "invalid format: %s\n", *format)
 		os.Exit(1)
 	}
 
-	pid, err := strconv.Atoi(os.Args[1])
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	args := flag.Args()
+	if *name == "" {
+		if len(args) == 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		args = args[1:]
+	}
+
+	snapshot := "any"
+
+	switch len(args) {
+	case 1:
+		snapshot = args[0]
+	case 0:
+	default:
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	ps := process.New(
-		process.WithPid(pid),
-		process.WithSnapshot(process.SnapshotStrategy(snapshot)),
-	)
+	newProcess := func(pid int) process.Process {
+		return process.New(
+			process.WithPid(pid),
+			process.WithSnapshot(process.SnapshotStrategy(snapshot)),
+			process.WithDetails(*details),
+		)
+	}
 
-	children, err := ps.Children()
+	pids, err := newProcess(os.Getpid()).Snapshot()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	if len(children) == 0 {
+	var roots []int
+
+	if *name != "" {
+		re, err := regexp.Compile(*name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "name: %s\n", err)
+			os.Exit(1)
+		}
+		roots = match(pids, re)
+		if len(roots) == 0 {
+			fmt.Fprintf(os.Stderr, "name: no process matching: %s\n", *name)
+			os.Exit(1)
+		}
+	} else {
+		pid, err := strconv.Atoi(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		roots = []int{pid}
+	}
+
+	describe := func(pid int) string { return strconv.Itoa(pid) }
+
+	if *details {
+		table := make(map[int]process.PID, len(pids))
+		for _, p := range pids {
+			table[p.Pid] = p
+		}
+
+		describe = func(pid int) string {
+			p, ok := table[pid]
+			if !ok {
+				return strconv.Itoa(pid)
+			}
+			return fmt.Sprintf("%d %s %c uid=%d gid=%d pgrp=%d sid=%d start=%d",
+				p.Pid, p.Comm, p.State, p.Uid, p.Gid, p.Pgrp, p.Session, p.StartTime)
+		}
+	}
+
+	if *preview {
+		for _, pid := range roots {
+			targets, err := process.Targets(newProcess(pid))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			for _, pid := range targets {
+				fmt.Println(describe(pid))
+			}
+		}
+
 		os.Exit(0)
 	}
 
-	fmt.Println(pid)
-	for _, cld := range children {
-		fmt.Printf("|-%v\n", cld)
+	for i, pid := range roots {
+		root := prune(process.NewTree(pids, pid), *depth)
+
+		if write == nil {
+			// a process matching the name is displayed without
+			// children
+			err = writeText(os.Stdout, root, describe, *name != "")
+		} else {
+			if i > 0 && *format == "yaml" {
+				// a YAML stream of documents
+				fmt.Println("---")
+			}
+			err = write(os.Stdout, newNode(root, *details))
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+
+	"github.com/msantos/goreap/process"
+	"github.com/msantos/goreap/subreaper"
+)
+
+// descendants counts the children and descendants of the process using
+// a snapshot of the process table: the counts are not reported by the
+// subreaper status on Linux.
+func descendants(s *subreaper.ReapStatus) {
+	snapshot, err := process.New().Snapshot()
+	if err != nil {
+		return
+	}
+
+	pid := os.Getpid()
+
+	for _, p := range snapshot {
+		if p.PPid != pid {
+			continue
+		}
+		if s.Children == 0 || int32(p.Pid) < s.Pid {
+			s.Pid = int32(p.Pid)
+		}
+		s.Children++
+	}
+
+	s.Descendants = uint32(len(process.Descendants(snapshot, pid)))
+}
@@ -0,0 +1,29 @@
+// Command subreaper reports whether the process is a subreaper.
+//
+// The subreaper attribute is preserved across exec(2): run subreaper
+// using exec to report the status of the calling process.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/msantos/goreap/subreaper"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s\n", path.Base(os.Args[0]))
+		os.Exit(2)
+	}
+
+	ok := subreaper.Get()
+
+	fmt.Printf("subreaper: %t\n", ok)
+	status()
+
+	if !ok {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/msantos/goreap/subreaper"
+)
+
+func status() {
+	s, err := subreaper.Status()
+	if err != nil {
+		return
+	}
+	descendants(s)
+	fmt.Printf("reaper: %d\n", s.Reaper)
+	fmt.Printf("realinit: %t\n", s.RealInit())
+	fmt.Printf("children: %d\n", s.Children)
+	fmt.Printf("descendants: %d\n", s.Descendants)
+}
@@ -0,0 +1,27 @@
+package process
+
+import "syscall"
+
+// EventType is the type of a process event (see Watch).
+type EventType int
+
+const (
+	// EventFork is sent when a process is created.
+	EventFork EventType = iota + 1
+	// EventExec is sent when a process executes a new program.
+	EventExec
+	// EventExit is sent when a process exits.
+	EventExit
+)
+
+// Event is a process event.
+type Event struct {
+	Type EventType
+	// Pid is the process ID. For EventFork, the process ID of the new
+	// process.
+	Pid int
+	// PPid is the parent process ID for EventFork.
+	PPid int
+	// Status is the exit status for EventExit.
+	Status syscall.WaitStatus
+}
@@ -25,3 +25,18 @@ func ExamplePs_Snapshot() {
 	}
 	fmt.Printf("%+v\n", pids)
 }
+
+func ExampleNewTree() {
+	pids := []process.PID{
+		{Pid: 1, Comm: "init"},
+		{Pid: 10, PPid: 1, Comm: "sh"},
+		{Pid: 11, PPid: 10, Comm: "sleep"},
+		{Pid: 12, PPid: 1, Comm: "cat"},
+	}
+	fmt.Print(process.NewTree(pids, 1))
+	// Output:
+	// 1 init
+	// |-10 sh
+	// | `-11 sleep
+	// `-12 cat
+}
@@ -0,0 +1,37 @@
+package process
+
+// SetReadFile replaces the function used to read procfs files. The
+// returned function restores the original.
+func SetReadFile(f func(string) ([]byte, error)) func() {
+	orig, origStat := readFile, readStatFile
+	readFile = f
+	readStatFile = func(name string, buf []byte) ([]byte, error) {
+		b, err := f(name)
+		return append(buf, b...), err
+	}
+	return func() {
+		readFile, readStatFile = orig, origStat
+	}
+}
+
+// NewProcfs returns a process for a procfs directory without checking
+// the directory is a procfs mount.
+func NewProcfs(procfs string, opts ...Option) Process {
+	ps := &Ps{
+		procfs:   procfs,
+		snapshot: SnapshotPs,
+	}
+	for _, opt := range opts {
+		opt(ps)
+	}
+	return ps
+}
+
+// NewProcChildren returns a process reading the task children files of
+// a procfs directory.
+func NewProcChildren(procfs string, opts ...Option) Process {
+	return &ProcChildren{Ps: NewProcfs(procfs, opts...).(*Ps)}
+}
+
+// ProcNames returns the process directories in a procfs directory.
+var ProcNames = procNames
@@ -0,0 +1,116 @@
+package process
+
+import (
+	"strconv"
+	"strings"
+)
+
+// WithStateFilter limits the processes returned by Snapshot and
+// Children to processes in one of the states, e.g., "Z" for zombie
+// processes or "TZ" for stopped and zombie processes.
+//
+// Filters are applied to the results: Children returns the descendants
+// matching the filters, including descendants of processes not matching
+// the filters. Filtering the pids returned by Children requires procfs.
+func WithStateFilter(states string) Option {
+	return func(ps *Ps) {
+		ps.filters = append(ps.filters, func(p PID) bool {
+			return strings.IndexByte(states, p.State) != -1
+		})
+	}
+}
+
+// WithUIDFilter limits the processes returned by Snapshot and Children
+// to processes with the effective user ID. See WithStateFilter.
+func WithUIDFilter(uid int) Option {
+	return func(ps *Ps) {
+		ps.owner = true
+		ps.filters = append(ps.filters, func(p PID) bool {
+			return p.Uid == uid
+		})
+	}
+}
+
+// WithCommFilter limits the processes returned by Snapshot and Children
+// to processes with the command name. See WithStateFilter.
+func WithCommFilter(comm string) Option {
+	return func(ps *Ps) {
+		ps.filters = append(ps.filters, func(p PID) bool {
+			return p.Comm == comm
+		})
+	}
+}
+
+// match returns true if the process matches all filters.
+func (ps *Ps) match(p PID) bool {
+	for _, f := range ps.filters {
+		if !f(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterTable removes processes not matching the filters from the
+// process table. The process table is modified in place.
+func (ps *Ps) filterTable(p []PID) []PID {
+	if len(ps.filters) == 0 {
+		return p
+	}
+
+	n := 0
+	for _, v := range p {
+		if ps.match(v) {
+			p[n] = v
+			n++
+		}
+	}
+
+	return p[:n]
+}
+
+// filterPids removes processes not matching the filters. Processes
+// which cannot be read, e.g., processes which have exited, are removed.
+func (ps *Ps) filterPids(pids []int) []int {
+	if len(ps.filters) == 0 {
+		return pids
+	}
+
+	n := 0
+	for _, pid := range pids {
+		p, err := readProcStat(ps.procfs+"/"+strconv.Itoa(pid)+"/stat", ps.fields()&fieldOwner)
+		if err != nil || !ps.match(p) {
+			continue
+		}
+		pids[n] = pid
+		n++
+	}
+
+	return pids[:n]
+}
+
+// descendantsOf returns the descendants of the process matching the
+// filters from a process table. ErrSearch is returned if the process is
+// not in the process table.
+func (ps *Ps) descendantsOf(p []PID) ([]int, error) {
+	table := make(map[int]PID, len(p))
+	for _, v := range p {
+		table[v.Pid] = v
+	}
+
+	if _, ok := table[ps.pid]; !ok {
+		return nil, ErrSearch
+	}
+
+	pids := Descendants(p, ps.pid)
+
+	n := 0
+	for _, pid := range pids {
+		if ps.match(table[pid]) {
+			pids[n] = pid
+			n++
+		}
+	}
+
+	return pids[:n], nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package process
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileOwner returns the uid and gid of the file owner or -1 if the owner
+// is unknown.
+func fileOwner(fi fs.FileInfo) (int, int) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid), int(st.Gid)
+	}
+	return -1, -1
+}
@@ -1,9 +1,9 @@
 package process
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
 	"strconv"
 	"strings"
 )
@@ -23,7 +23,8 @@ type ProcChildren struct {
 // /proc/self/task/*/children.
 //
 // If CONFIG_PROC_CHILDREN is not enabled, the error is set to
-// ErrNotExist.
+// ErrNotExist. Task children files which cannot be read, e.g., due to
+// permissions, are skipped.
 func (ps *ProcChildren) Children() ([]int, error) {
 	if !exists(ps.procfs, ps.pid) {
 		return nil, ErrSearch
@@ -31,41 +32,55 @@ func (ps *ProcChildren) Children() ([]int, error) {
 
 	pids := make([]int, 0)
 
-	paths, err := filepath.Glob(
-		fmt.Sprintf("%s/%d/task/*/children", ps.procfs, ps.pid),
-	)
-	if err != nil {
-		return pids, err
-	}
-	if len(paths) == 0 {
+	task := fmt.Sprintf("%s/%d/task", ps.procfs, ps.pid)
+
+	tids, err := procNames(task)
+	if err != nil || len(tids) == 0 {
 		return pids, ErrNotExist
 	}
 
-	for _, v := range paths {
-		pid, err := ps.readChildren(v)
-		if err != nil {
+	missing := 0
+
+	for _, tid := range tids {
+		pid, err := ps.readChildren(task + "/" + tid + "/children")
+		switch {
+		case err == nil:
+		case errors.Is(err, fs.ErrNotExist):
+			// the task exited or the kernel does not support the
+			// children file
+			missing++
+			continue
+		case errors.Is(err, fs.ErrPermission), errors.Is(err, ErrSearch):
+			// the children of the task are not readable: return the
+			// children of other tasks
+			continue
+		default:
 			return pids, err
 		}
 		pids = append(pids, pid...)
 	}
 
-	return pids, nil
+	if missing == len(tids) {
+		return pids, ErrNotExist
+	}
+
+	return ps.filterPids(pids), nil
 }
 
 func (ps *ProcChildren) readChildren(path string) ([]int, error) {
-	b, err := os.ReadFile(path)
+	b, err := readFile(path)
 	if err != nil {
 		return nil, err
 	}
 
 	pids := strings.Fields(string(b))
-	children := make([]int, len(pids))
-	for i, s := range pids {
+	children := make([]int, 0, len(pids))
+	for _, s := range pids {
 		pid, err := strconv.Atoi(s)
 		if err != nil {
 			continue
 		}
-		children[i] = pid
+		children = append(children, pid)
 	}
 
 	return children, nil
@@ -7,10 +7,10 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"strings"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"syscall"
-
-	"golang.org/x/sys/unix"
 )
 
 const (
@@ -18,7 +18,7 @@ const (
 	// mountpoint can be changed by setting the PROC environment variable.
 	Procfs = "/proc"
 
-	ErrSearch = unix.ESRCH // No such process
+	ErrSearch = syscall.ESRCH // No such process
 )
 
 var (
@@ -26,6 +26,8 @@ var (
 	ErrNotExist = fs.ErrNotExist // "file does not exist"
 )
 
+var readFile = os.ReadFile
+
 type Process interface {
 	Pid() int
 	Children() ([]int, error)
@@ -34,8 +36,19 @@ type Process interface {
 
 // PID contains the contents of /proc/stat for a process.
 type PID struct {
-	Pid  int // process ID
-	PPid int // parent process ID
+	Pid   int    // process ID
+	PPid  int    // parent process ID
+	State byte   // process state: R, S, D, Z, T, ...
+	Comm  string // command name
+
+	// set if enabled by WithDetails or WithUIDFilter
+	Uid int // effective user ID
+
+	// set if enabled by WithDetails
+	Gid       int    // effective group ID
+	Pgrp      int    // process group ID
+	Session   int    // session ID
+	StartTime uint64 // start time after system boot in clock ticks
 }
 
 func getenv(s, def string) string {
@@ -63,6 +76,12 @@ func New(opts ...Option) Process {
 		return ps
 	}
 
+	if ps.snapshot == "" {
+		if p, ok := platformProcess(ps); ok {
+			return p
+		}
+	}
+
 	if !procChildrenExists(ps.procfs, ps.pid) {
 		if ps.snapshot == "" {
 			return ps
@@ -92,6 +111,35 @@ func WithProcfs(procfs string) Option {
 	}
 }
 
+// WithReuseBuffers reuses the process table between snapshots to reduce
+// allocations on systems with many processes. The process table
+// returned by Snapshot is overwritten by the next call to Snapshot.
+func WithReuseBuffers(b bool) Option {
+	return func(ps *Ps) {
+		ps.reuse = b
+	}
+}
+
+// WithConcurrency reads the process table using n goroutines when
+// taking a snapshot of procfs. The default (0 or 1) reads the process
+// table sequentially. Reading concurrently reduces the time to take a
+// snapshot on systems with many processes.
+func WithConcurrency(n int) Option {
+	return func(ps *Ps) {
+		ps.concurrency = n
+	}
+}
+
+// WithDetails includes the user ID, group ID, process group, session
+// and start time of processes in the process table returned by
+// Snapshot. Reading the details requires parsing more of
+// /proc/<pid>/stat and reading the owner of the file.
+func WithDetails(b bool) Option {
+	return func(ps *Ps) {
+		ps.details = b
+	}
+}
+
 // WithSnapshot sets the method for discovering subprocesses.
 func WithSnapshot(snapshot SnapshotStrategy) Option {
 	return func(ps *Ps) {
@@ -111,70 +159,133 @@ func procChildrenExists(procfs string, pid int) bool {
 	return err == nil
 }
 
-func isProcMounted(procfs string) bool {
-	var buf syscall.Statfs_t
-	if err := syscall.Statfs(procfs, &buf); err != nil {
-		return false
-	}
-	return buf.Type == unix.PROC_SUPER_MAGIC
+func exists(procfs string, pid int) bool {
+	_, err := os.Stat(fmt.Sprintf("%s/%d", procfs, pid))
+	return err == nil
 }
 
-func readProcStat(name string) (PID, error) {
-	b, err := os.ReadFile(name)
+// Snapshot returns a snapshot of the system process table by walking
+// through /proc. Processes which cannot be read, e.g., processes which
+// have exited or are not accessible, are skipped.
+func Snapshot(procfs string) ([]PID, error) {
+	return snapshot(procfs, nil, 0, 0)
+}
+
+// snapshot appends the system process table to p. The capacity of p is
+// increased to the number of entries in procfs before reading the
+// process table. The fields read for each process are selected by f.
+// If concurrency is greater than 1, the process table is read by
+// concurrency goroutines (see WithConcurrency).
+func snapshot(procfs string, p []PID, f fields, concurrency int) ([]PID, error) {
+	names, err := procNames(procfs)
 	if err != nil {
-		return PID{}, err
+		return p, err
+	}
+
+	if n := len(p) + len(names); cap(p) < n {
+		p = append(make([]PID, 0, n), p...)
+	}
+
+	if concurrency > 1 {
+		return readStats(procfs, p, names, f, concurrency), nil
+	}
+
+	for _, name := range names {
+		pid, err := readProcStat(procfs+"/"+name+"/stat", f)
+		if err != nil {
+			continue
+		}
+		p = append(p, pid)
 	}
 
-	// <pid> (<comm>) <state> <ppid> ...
-	// 21230 (cat) R 9985
-	//
-	// comm may contain spaces, brackets and newlines
-	// 21230 (cat foo) R ...
-	// 21230 (cat (foo) S) R ...
-	// 21230 (cat (foo)
-	// S) R ...
-	stat := string(b)
+	return p, nil
+}
 
-	var pid int
+// readStats appends the processes in procfs to p using a pool of
+// goroutines. Processes are appended in the order of names. The capacity
+// of p must be at least the length of p plus the number of names.
+func readStats(procfs string, p []PID, names []string, f fields, concurrency int) []PID {
+	base := len(p)
+	p = p[:base+len(names)]
+	found := make([]bool, len(names))
 
-	if n, err := fmt.Sscanf(stat, "%d ", &pid); err != nil || n != 1 {
-		return PID{}, ErrInvalid
+	if concurrency > len(names) {
+		concurrency = len(names)
 	}
 
-	bracket := strings.LastIndexByte(stat, ')')
-	if bracket == -1 {
-		return PID{}, ErrInvalid
+	var next atomic.Int64
+	var wg sync.WaitGroup
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(names) {
+					return
+				}
+				pid, err := readProcStat(procfs+"/"+names[i]+"/stat", f)
+				if err != nil {
+					continue
+				}
+				p[base+i] = pid
+				found[i] = true
+			}
+		}()
 	}
 
-	var state byte
-	var ppid int
+	wg.Wait()
 
-	if n, err := fmt.Sscanf(stat[bracket+1:], " %c %d", &state, &ppid); err != nil || n != 2 {
-		return PID{}, ErrInvalid
+	n := base
+	for i, ok := range found {
+		if ok {
+			p[n] = p[base+i]
+			n++
+		}
 	}
-	return PID{Pid: pid, PPid: ppid}, nil
-}
 
-func exists(procfs string, pid int) bool {
-	_, err := os.Stat(fmt.Sprintf("%s/%d", procfs, pid))
-	return err == nil
+	return p[:n]
 }
 
-// Snapshot returns a snapshot of the system process table by walking
-// through /proc.
-func Snapshot(procfs string) (p []PID, err error) {
-	matches, err := filepath.Glob(
-		fmt.Sprintf("%s/[0-9]*/stat", procfs),
-	)
+// procNames returns the process directories in a procfs directory,
+// e.g., /proc or /proc/<pid>/task. Entries which are not processes are
+// skipped. Processes are returned in lexical order of the directory
+// name.
+func procNames(procfs string) ([]string, error) {
+	dir, err := os.Open(procfs)
 	if err != nil {
-		return p, err
+		return nil, err
 	}
-	for _, stat := range matches {
-		pid, err := readProcStat(stat)
-		if err != nil {
-			continue
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for _, name := range names {
+		if isPid(name) {
+			names[n] = name
+			n++
+		}
+	}
+
+	names = names[:n]
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// isPid returns true if a directory name is a process ID.
+func isPid(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] < '0' || name[i] > '9' {
+			return false
 		}
-		p = append(p, pid)
 	}
-	return p, err
+	return true
 }
@@ -1,11 +1,25 @@
+//go:build !windows
+
 package process_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/msantos/goreap/process"
+	"golang.org/x/sys/unix"
 )
 
 func TestNew(t *testing.T) {
@@ -68,3 +82,851 @@ func TestErrSearch(t *testing.T) {
 		return
 	}
 }
+
+func TestEnviron(t *testing.T) {
+	ps := process.New()
+	e, ok := ps.(interface {
+		Environ(int) ([]string, error)
+	})
+	if !ok {
+		t.Fatalf("Environ not supported: %T", ps)
+	}
+
+	env, err := e.Environ(os.Getpid())
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if len(env) == 0 {
+		t.Errorf("environment is empty")
+		return
+	}
+	for _, kv := range env {
+		if !strings.Contains(kv, "=") {
+			t.Errorf("invalid environment: %q", kv)
+		}
+	}
+}
+
+func TestStartTime(t *testing.T) {
+	ps := process.New()
+	st, ok := ps.(interface {
+		StartTime(int) (uint64, error)
+	})
+	if !ok {
+		t.Fatalf("StartTime not supported: %T", ps)
+	}
+
+	self, err := st.StartTime(os.Getpid())
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+
+	init, err := st.StartTime(1)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+
+	if self < init {
+		t.Errorf("start time = %d, init = %d", self, init)
+	}
+}
+
+func TestStat(t *testing.T) {
+	ps := process.New()
+	st, ok := ps.(interface {
+		Stat(int) (process.PID, error)
+	})
+	if !ok {
+		t.Fatalf("Stat not supported: %T", ps)
+	}
+
+	p, err := st.Stat(os.Getpid())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if p.Pid != os.Getpid() || p.PPid != os.Getppid() || p.Uid != os.Geteuid() {
+		t.Errorf("stat = %+v", p)
+	}
+
+	if _, err := st.Stat(-1); err == nil {
+		t.Errorf("stat: invalid pid: no error")
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	pids := []process.PID{
+		{Pid: 30, PPid: 20}, // listed before parent
+		{Pid: 10, PPid: 1},
+		{Pid: 20, PPid: 10},
+		{Pid: 11, PPid: 1},
+		{Pid: 40, PPid: 30},
+		{Pid: 50, PPid: 2},
+	}
+
+	cld := process.Descendants(pids, 10)
+	sort.Ints(cld)
+
+	if want := []int{20, 30, 40}; !reflect.DeepEqual(cld, want) {
+		t.Errorf("descendants = %v, want %v", cld, want)
+	}
+}
+
+type fakeProcess struct {
+	pid      int
+	children []int
+}
+
+func (p *fakeProcess) Pid() int                         { return p.pid }
+func (p *fakeProcess) Children() ([]int, error)         { return p.children, nil }
+func (p *fakeProcess) Snapshot() ([]process.PID, error) { return nil, nil }
+
+func TestTargets(t *testing.T) {
+	ps := &fakeProcess{pid: 1, children: []int{0, 10, os.Getpid(), 20, -1}}
+
+	got, err := process.Targets(ps)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if want := []int{10, 20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %v, want %v", got, want)
+	}
+}
+
+func TestDescendantsDepth(t *testing.T) {
+	// deep tree: 1 -> 2 -> 3 -> ... -> 1000, each process also has a
+	// leaf child numbered pid+10000
+	pids := make([]process.PID, 0, 2000)
+	for i := 2; i <= 1000; i++ {
+		pids = append(pids, process.PID{Pid: i, PPid: i - 1})
+		pids = append(pids, process.PID{Pid: i + 10000, PPid: i - 1})
+	}
+
+	for _, tt := range []struct {
+		depth int
+		want  []int
+	}{
+		{1, []int{2, 10002}},
+		{2, []int{2, 3, 10002, 10003}},
+		{3, []int{2, 3, 4, 10002, 10003, 10004}},
+	} {
+		got := process.DescendantsDepth(pids, 1, tt.depth)
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("depth %d: descendants = %v, want %v", tt.depth, got, tt.want)
+		}
+	}
+
+	all := process.Descendants(pids, 1)
+	sort.Ints(all)
+
+	for _, depth := range []int{0, 1000} {
+		got := process.DescendantsDepth(pids, 1, depth)
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, all) {
+			t.Errorf("depth %d: descendants = %d, want %d", depth, len(got), len(all))
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	pids := []process.PID{
+		{Pid: 10, PPid: 1},  // foreground
+		{Pid: 11, PPid: 10}, // foreground subprocess
+		{Pid: 12, PPid: 11},
+		{Pid: 20, PPid: 1}, // orphan
+		{Pid: 21, PPid: 20},
+		{Pid: 30, PPid: 2}, // unrelated
+	}
+
+	tree, other := process.Partition(pids, 1, 10)
+	sort.Ints(tree)
+	sort.Ints(other)
+
+	if want := []int{10, 11, 12}; !reflect.DeepEqual(tree, want) {
+		t.Errorf("tree = %v, want %v", tree, want)
+	}
+	if want := []int{20, 21}; !reflect.DeepEqual(other, want) {
+		t.Errorf("other = %v, want %v", other, want)
+	}
+}
+
+func benchTable(n int) []process.PID {
+	pids := make([]process.PID, 0, n)
+	for i := 2; i < n+2; i++ {
+		pids = append(pids, process.PID{Pid: i, PPid: i / 2})
+	}
+	return pids
+}
+
+func BenchmarkDescendants(b *testing.B) {
+	pids := benchTable(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if n := len(process.Descendants(pids, 4)); n == 0 {
+			b.Fatalf("descendants = %d", n)
+		}
+	}
+}
+
+func BenchmarkChildren(b *testing.B) {
+	ps := process.New(
+		process.WithPid(1),
+		process.WithSnapshot(process.SnapshotPs),
+	)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ps.Children(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPermissionDenied(t *testing.T) {
+	denied := func(match func(string) bool) func() {
+		return process.SetReadFile(func(name string) ([]byte, error) {
+			if match(name) {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EACCES}
+			}
+			return os.ReadFile(name)
+		})
+	}
+
+	t.Run("snapshot", func(t *testing.T) {
+		defer denied(func(name string) bool {
+			return name == "/proc/1/stat"
+		})()
+
+		pids, err := process.Snapshot(process.Procfs)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		var found bool
+		for _, p := range pids {
+			switch p.Pid {
+			case 1:
+				t.Errorf("unreadable process included in snapshot")
+			case os.Getpid():
+				found = true
+				if p.PPid != os.Getppid() {
+					t.Errorf("ppid = %d, want %d", p.PPid, os.Getppid())
+				}
+			}
+		}
+
+		if !found {
+			t.Errorf("process not found in snapshot")
+		}
+	})
+
+	t.Run("children", func(t *testing.T) {
+		ps := process.New(process.WithSnapshot(process.SnapshotChildren))
+		if _, ok := ps.(*process.ProcChildren); !ok {
+			t.Skip("task children not supported")
+		}
+
+		defer denied(func(name string) bool {
+			return strings.HasSuffix(name, "/children")
+		})()
+
+		pids, err := ps.Children()
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+		if len(pids) != 0 {
+			t.Errorf("children = %v, want none", pids)
+		}
+	})
+
+	t.Run("environ", func(t *testing.T) {
+		defer denied(func(name string) bool {
+			return strings.HasSuffix(name, "/environ")
+		})()
+
+		ps := process.New(process.WithSnapshot(process.SnapshotPs))
+		e := ps.(interface {
+			Environ(int) ([]string, error)
+		})
+
+		if _, err := e.Environ(os.Getpid()); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("error = %v, want %v", err, fs.ErrPermission)
+		}
+	})
+}
+
+// fakeProcfs creates a procfs directory containing n processes.
+func fakeProcfs(tb testing.TB, n int) string {
+	tb.Helper()
+
+	dir := tb.TempDir()
+
+	for _, name := range []string{"self", "sys"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "uptime"), []byte("1.0 1.0\n"), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+
+	for i := 1; i <= n; i++ {
+		pid := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.Mkdir(pid, 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		stat := fmt.Sprintf("%d (proc %d) S %d 1 1 0 -1\n", i, i, i/2)
+		if err := os.WriteFile(filepath.Join(pid, "stat"), []byte(stat), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestSnapshotProcfs(t *testing.T) {
+	dir := fakeProcfs(t, 100)
+
+	pids, err := process.Snapshot(dir)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(pids) != 100 {
+		t.Fatalf("snapshot = %d processes, want 100", len(pids))
+	}
+
+	names := make([]string, 0, len(pids))
+	for _, p := range pids {
+		if p.PPid != p.Pid/2 || p.Comm != fmt.Sprintf("proc %d", p.Pid) || p.State != 'S' {
+			t.Errorf("unexpected process: %+v", p)
+		}
+		names = append(names, strconv.Itoa(p.Pid))
+	}
+
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("processes not in lexical order: %v", names)
+	}
+
+	ps := process.NewProcfs(dir, process.WithReuseBuffers(true))
+
+	for i := 0; i < 2; i++ {
+		reused, err := ps.Snapshot()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if !reflect.DeepEqual(reused, pids) {
+			t.Errorf("reused snapshot differs")
+		}
+	}
+}
+
+func BenchmarkSnapshotProcfs(b *testing.B) {
+	dir := fakeProcfs(b, 10000)
+
+	for _, reuse := range []bool{false, true} {
+		b.Run(fmt.Sprintf("reuse=%t", reuse), func(b *testing.B) {
+			ps := process.NewProcfs(dir, process.WithReuseBuffers(reuse))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := ps.Snapshot(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshotConcurrency(t *testing.T) {
+	dir := fakeProcfs(t, 100)
+
+	if err := os.Mkdir(filepath.Join(dir, "101"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "101", "stat"), []byte("invalid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := process.NewProcfs(dir).Snapshot()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(want) != 100 {
+		t.Fatalf("snapshot = %d processes, want 100", len(want))
+	}
+
+	for _, n := range []int{2, 8, 1000} {
+		for _, reuse := range []bool{false, true} {
+			ps := process.NewProcfs(dir, process.WithConcurrency(n), process.WithReuseBuffers(reuse))
+			for i := 0; i < 2; i++ {
+				pids, err := ps.Snapshot()
+				if err != nil {
+					t.Fatalf("%v", err)
+				}
+				if !reflect.DeepEqual(pids, want) {
+					t.Errorf("concurrency=%d reuse=%t: snapshot differs", n, reuse)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkSnapshotConcurrency(b *testing.B) {
+	dir := fakeProcfs(b, 50000)
+
+	for _, n := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("concurrency=%d", n), func(b *testing.B) {
+			ps := process.NewProcfs(dir, process.WithConcurrency(n), process.WithReuseBuffers(true))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := ps.Snapshot(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	dir := fakeProcfs(t, 20)
+
+	// zombie descendants of a process not matching the filter
+	for _, pid := range []int{9, 10} {
+		stat := fmt.Sprintf("%d (proc %d) Z %d 1 1 0 -1\n", pid, pid, pid/2)
+		if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(pid), "stat"), []byte(stat), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		opts     []process.Option
+		snapshot int
+		children []int
+	}{
+		{[]process.Option{process.WithStateFilter("Z")}, 2, []int{9, 10}},
+		{[]process.Option{process.WithStateFilter("SZ")}, 20, nil},
+		{[]process.Option{process.WithCommFilter("proc 9")}, 1, []int{9}},
+		{[]process.Option{process.WithStateFilter("Z"), process.WithCommFilter("proc 4")}, 0, []int{}},
+		{[]process.Option{process.WithUIDFilter(os.Geteuid())}, 20, nil},
+		{[]process.Option{process.WithUIDFilter(os.Geteuid() + 1)}, 0, []int{}},
+	} {
+		ps := process.NewProcfs(dir, append(tt.opts, process.WithPid(1))...)
+
+		pids, err := ps.Snapshot()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if len(pids) != tt.snapshot {
+			t.Errorf("snapshot = %d processes, want %d", len(pids), tt.snapshot)
+		}
+
+		children, err := ps.Children()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if tt.children == nil {
+			continue
+		}
+
+		sort.Ints(children)
+		if !reflect.DeepEqual(children, tt.children) {
+			t.Errorf("children = %v, want %v", children, tt.children)
+		}
+	}
+}
+
+func TestSnapshotDetails(t *testing.T) {
+	ps := process.New(process.WithDetails(true))
+
+	pids, err := ps.Snapshot()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	pid := os.Getpid()
+
+	sid, err := unix.Getsid(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, p := range pids {
+		if p.Pid != pid {
+			continue
+		}
+
+		if p.Pgrp != syscall.Getpgrp() || p.Session != sid || p.Gid != os.Getegid() {
+			t.Errorf("unexpected details: %+v", p)
+		}
+
+		st, ok := ps.(interface{ StartTime(int) (uint64, error) })
+		if !ok {
+			t.Fatalf("StartTime not supported")
+		}
+
+		start, err := st.StartTime(pid)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if p.StartTime != start {
+			t.Errorf("start time = %d, want %d", p.StartTime, start)
+		}
+
+		return
+	}
+
+	t.Errorf("process %d not found", pid)
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events, err := process.Watch(ctx)
+	if err != nil {
+		t.Skipf("proc connector not supported: %v", err)
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", "exit 3")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	pid := cmd.Process.Pid
+	defer func() { _ = cmd.Wait() }()
+
+	seen := make(map[process.EventType]process.Event)
+
+	for ev := range events {
+		if ev.Pid != pid {
+			continue
+		}
+		seen[ev.Type] = ev
+		if ev.Type == process.EventExit {
+			break
+		}
+	}
+
+	if ev, ok := seen[process.EventFork]; !ok || ev.PPid != os.Getpid() {
+		t.Errorf("fork event: %+v", ev)
+	}
+
+	if _, ok := seen[process.EventExec]; !ok {
+		t.Errorf("exec event not received")
+	}
+
+	ev, ok := seen[process.EventExit]
+	if !ok {
+		t.Fatalf("exit event not received")
+	}
+
+	if ev.Status.ExitStatus() != 3 {
+		t.Errorf("exit status = %d, want 3", ev.Status.ExitStatus())
+	}
+}
+
+func TestTracker(t *testing.T) {
+	dir := fakeProcfs(t, 10)
+
+	tracker := process.NewTracker(process.NewProcfs(dir, process.WithPid(1)))
+
+	diff := func(added, removed []int) {
+		t.Helper()
+
+		if _, err := tracker.Snapshot(); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		a, r := tracker.Diff()
+		if !reflect.DeepEqual(a, added) {
+			t.Errorf("added = %v, want %v", a, added)
+		}
+		if !reflect.DeepEqual(r, removed) {
+			t.Errorf("removed = %v, want %v", r, removed)
+		}
+	}
+
+	diff([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, []int{})
+	diff([]int{}, []int{})
+
+	if err := os.RemoveAll(filepath.Join(dir, "3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "11"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "11", "stat"), []byte("11 (proc 11) S 5 1 1 0 -1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff([]int{11}, []int{3})
+
+	// pid reused by a process with a different start time
+	stat := "7 (proc 7) S 3 1 1 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 4242 0 0\n"
+	if err := os.WriteFile(filepath.Join(dir, "7", "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff([]int{7}, []int{7})
+
+	// the owner is read by each poll
+	owners := process.NewTracker(process.NewProcfs(dir, process.WithPid(1), process.WithUIDFilter(os.Geteuid())))
+	if _, err := owners.Snapshot(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if os.Geteuid() == 0 {
+		if err := os.Chown(filepath.Join(dir, "5", "stat"), 1000, -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pids, err := owners.Snapshot()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if want := 9; os.Geteuid() == 0 && len(pids) != want {
+		t.Errorf("processes = %d, want %d", len(pids), want)
+	}
+
+	for _, p := range pids {
+		if p.Uid != os.Geteuid() {
+			t.Errorf("%d: uid = %d, want %d", p.Pid, p.Uid, os.Geteuid())
+		}
+	}
+
+	children, err := tracker.Children()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	sort.Ints(children)
+	// the parent of 6 and 7 exited
+	if want := []int{2, 4, 5, 8, 9, 10, 11}; !reflect.DeepEqual(children, want) {
+		t.Errorf("children = %v, want %v", children, want)
+	}
+}
+
+func BenchmarkTracker(b *testing.B) {
+	dir := fakeProcfs(b, 10000)
+
+	tracker := process.NewTracker(process.NewProcfs(dir))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tracker.Snapshot(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// fakeTasks creates the task directories of a process in a procfs
+// directory. The children file of each task contains a child process.
+func fakeTasks(tb testing.TB, dir string, pid, n int) {
+	tb.Helper()
+
+	for i := 0; i < n; i++ {
+		task := filepath.Join(dir, strconv.Itoa(pid), "task", strconv.Itoa(pid+i))
+		if err := os.MkdirAll(task, 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		child := fmt.Sprintf("%d ", 100000+i)
+		if err := os.WriteFile(filepath.Join(task, "children"), []byte(child), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestProcChildren(t *testing.T) {
+	dir := fakeProcfs(t, 2)
+	fakeTasks(t, dir, 1, 3)
+
+	if err := os.Mkdir(filepath.Join(dir, "1", "task", "self"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	children, err := process.NewProcChildren(dir, process.WithPid(1)).Children()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	sort.Ints(children)
+	if want := []int{100000, 100001, 100002}; !reflect.DeepEqual(children, want) {
+		t.Errorf("children = %v, want %v", children, want)
+	}
+
+	// kernel without CONFIG_PROC_CHILDREN
+	if err := os.MkdirAll(filepath.Join(dir, "2", "task", "2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := process.NewProcChildren(dir, process.WithPid(2)).Children(); !errors.Is(err, process.ErrNotExist) {
+		t.Errorf("children: err = %v, want %v", err, process.ErrNotExist)
+	}
+}
+
+func BenchmarkProcNames(b *testing.B) {
+	dir := fakeProcfs(b, 10000)
+
+	b.Run("glob", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := filepath.Glob(dir + "/[0-9]*/stat"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("readdir", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := process.ProcNames(dir); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkProcChildren(b *testing.B) {
+	dir := fakeProcfs(b, 1)
+	fakeTasks(b, dir, 1, 1000)
+
+	ps := process.NewProcChildren(dir, process.WithPid(1))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ps.Children(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseStat(t *testing.T) {
+	const tail = " 1 1 0 -1 4194560 100 0 0 0 1 2 0 0 20 0 1 0 4242 1000 10"
+
+	for _, tt := range []struct {
+		stat string
+		want process.PID
+		err  error
+	}{
+		{
+			"21230 (cat) R 9985" + tail + "\n",
+			process.PID{Pid: 21230, PPid: 9985, State: 'R', Comm: "cat", Pgrp: 1, Session: 1, StartTime: 4242},
+			nil,
+		},
+		{
+			"21230 (cat (foo)\nS) S 9985" + tail,
+			process.PID{Pid: 21230, PPid: 9985, State: 'S', Comm: "cat (foo)\nS", Pgrp: 1, Session: 1, StartTime: 4242},
+			nil,
+		},
+		{"21230 (cat) R 9985 1 1 0 -1\n", process.PID{}, process.ErrInvalid},
+		{"x (cat) R 9985" + tail, process.PID{}, process.ErrInvalid},
+		{"21230 (cat) R -" + tail, process.PID{}, process.ErrInvalid},
+		{"21230 cat R 9985", process.PID{}, process.ErrInvalid},
+		{"", process.PID{}, process.ErrInvalid},
+	} {
+		p, err := process.ParseStat([]byte(tt.stat))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: err = %v, want %v", tt.stat, err, tt.err)
+			continue
+		}
+		if p != tt.want {
+			t.Errorf("%q: pid = %+v, want %+v", tt.stat, p, tt.want)
+		}
+	}
+}
+
+func TestParseStatAllocs(t *testing.T) {
+	stat := []byte("21230 (cat) R 9985 1 1 0 -1 4194560 100 0 0 0 1 2 0 0 20 0 1 0 4242 1000 10\n")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := process.ParseStat(stat); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// the command name
+	if allocs > 1 {
+		t.Errorf("allocs = %.0f, want 1", allocs)
+	}
+}
+
+func BenchmarkParseStat(b *testing.B) {
+	stat := []byte("21230 (cat) R 9985 1 1 0 -1 4194560 100 0 0 0 1 2 0 0 20 0 1 0 4242 1000 10\n")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := process.ParseStat(stat); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestNewTree(t *testing.T) {
+	pids := []process.PID{
+		{Pid: 1, PPid: 0, Comm: "init"},
+		{Pid: 20, PPid: 10, Comm: "sleep"},
+		{Pid: 10, PPid: 1, Comm: "sh"},
+		{Pid: 12, PPid: 10, Comm: "cat"},
+		{Pid: 11, PPid: 1, Comm: "goreap"},
+		{Pid: 30, PPid: 99, Comm: "orphan"},
+	}
+
+	want := "1 init\n|-10 sh\n| |-12 cat\n| `-20 sleep\n`-11 goreap\n"
+	if tree := process.NewTree(pids, 1).String(); tree != want {
+		t.Errorf("tree = %q, want %q", tree, want)
+	}
+
+	root := process.NewTree(pids, 10)
+	if root.Comm != "sh" || len(root.Children) != 2 || root.Children[0].Pid != 12 {
+		t.Errorf("subtree = %+v", root)
+	}
+
+	if tree := process.NewTree(pids, 2).String(); tree != "2\n" {
+		t.Errorf("not found: tree = %q", tree)
+	}
+}
+
+func TestTree(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	root, err := process.Tree(os.Getpid())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	found := false
+	for _, c := range root.Children {
+		if c.Pid == cmd.Process.Pid {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("%d: child not found: %s", cmd.Process.Pid, root)
+	}
+
+	if _, err := process.Tree(-1); !errors.Is(err, process.ErrSearch) {
+		t.Errorf("invalid pid: %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !freebsd
+
+package process
+
+func isProcMounted(procfs string) bool {
+	return false
+}
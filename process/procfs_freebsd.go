@@ -0,0 +1,13 @@
+package process
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func isProcMounted(procfs string) bool {
+	var buf unix.Statfs_t
+	if err := unix.Statfs(procfs, &buf); err != nil {
+		return false
+	}
+	return unix.ByteSliceToString(buf.Fstypename[:]) == "procfs"
+}
@@ -0,0 +1,15 @@
+package process
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func isProcMounted(procfs string) bool {
+	var buf syscall.Statfs_t
+	if err := syscall.Statfs(procfs, &buf); err != nil {
+		return false
+	}
+	return buf.Type == unix.PROC_SUPER_MAGIC
+}
@@ -1,5 +1,18 @@
 package process
 
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+var descendantsPool = sync.Pool{
+	New: func() interface{} {
+		return make(map[int]struct{})
+	},
+}
+
 type SnapshotStrategy string
 
 const (
@@ -10,9 +23,18 @@ const (
 
 // Ps contains the state for a process when scanning /proc.
 type Ps struct {
-	pid      int
-	procfs   string
-	snapshot SnapshotStrategy
+	pid         int
+	procfs      string
+	snapshot    SnapshotStrategy
+	reuse       bool
+	details     bool
+	owner       bool // read the owner of processes (see WithUIDFilter)
+	concurrency int
+	filters     []func(PID) bool
+
+	mu   sync.Mutex
+	buf  []PID // process table buffer reused by Children
+	snap []PID // process table buffer reused by Snapshot
 }
 
 // Pid retrieves the process identifier.
@@ -22,7 +44,39 @@ func (ps *Ps) Pid() int {
 
 // Snapshot returns a snapshot of the system process table.
 func (ps *Ps) Snapshot() ([]PID, error) {
-	return Snapshot(ps.procfs)
+	return ps.read(ps.fields())
+}
+
+// fields returns the fields read for each process.
+func (ps *Ps) fields() fields {
+	var f fields
+	if ps.details {
+		f |= fieldDetails | fieldOwner
+	}
+	if ps.owner {
+		f |= fieldOwner
+	}
+	return f
+}
+
+// read returns a snapshot of the system process table, reading the
+// fields f of each process.
+func (ps *Ps) read(f fields) ([]PID, error) {
+	if !ps.reuse {
+		p, err := snapshot(ps.procfs, nil, f, ps.concurrency)
+		return ps.filterTable(p), err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, err := snapshot(ps.procfs, ps.snap[:0], f, ps.concurrency)
+	if err != nil {
+		return nil, err
+	}
+	ps.snap = p
+
+	return ps.filterTable(p), nil
 }
 
 // Children returns a snapshot of the list of subprocesses for a PID by
@@ -32,39 +86,178 @@ func (ps *Ps) Children() ([]int, error) {
 		return nil, ErrSearch
 	}
 
-	p, err := ps.Snapshot()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, err := snapshot(ps.procfs, ps.buf[:0], 0, ps.concurrency)
 	if err != nil {
 		return nil, err
 	}
-	return descendants(p, ps.pid), nil
+	ps.buf = p
+
+	return ps.filterPids(Descendants(p, ps.pid)), nil
 }
 
-func descendants(pids []PID, pid int) []int {
-	children := make(map[int]struct{})
-	walk(pids, pid, children)
-	cld := make([]int, 0, len(children))
-	for p := range children {
-		cld = append(cld, p)
+// Descendants returns the pids of all descendants of a process in a
+// process table.
+//
+// The process table is scanned until no new descendants are found. The
+// set of discovered descendants is reused between calls.
+func Descendants(pids []PID, pid int) []int {
+	seen := descendantsPool.Get().(map[int]struct{})
+	defer func() {
+		for k := range seen {
+			delete(seen, k)
+		}
+		descendantsPool.Put(seen)
+	}()
+
+	cld := make([]int, 0)
+
+	for n := -1; n != len(cld); {
+		n = len(cld)
+		for _, p := range pids {
+			if _, ok := seen[p.Pid]; ok {
+				continue
+			}
+			if _, ok := seen[p.PPid]; !ok && p.PPid != pid {
+				continue
+			}
+			seen[p.Pid] = struct{}{}
+			cld = append(cld, p.Pid)
+		}
 	}
+
 	return cld
 }
 
-func subprocs(pids []PID, pid int) (cld []PID) {
-	for _, p := range pids {
-		if p.PPid != pid {
-			continue
+// DescendantsDepth returns the pids of the descendants of a process in
+// a process table up to a maximum depth: depth 1 returns the children of
+// the process, depth 2 the children and grandchildren and so on. A depth
+// of 0 or less returns all descendants.
+func DescendantsDepth(pids []PID, pid int, depth int) []int {
+	if depth <= 0 {
+		return Descendants(pids, pid)
+	}
+
+	level := map[int]struct{}{pid: {}}
+	seen := map[int]struct{}{pid: {}}
+
+	cld := make([]int, 0)
+
+	for d := 0; d < depth && len(level) > 0; d++ {
+		next := make(map[int]struct{})
+		for _, p := range pids {
+			if _, ok := level[p.PPid]; !ok {
+				continue
+			}
+			if _, ok := seen[p.Pid]; ok {
+				continue
+			}
+			seen[p.Pid] = struct{}{}
+			next[p.Pid] = struct{}{}
+			cld = append(cld, p.Pid)
 		}
-		cld = append(cld, p)
+		level = next
 	}
+
 	return cld
 }
 
-func walk(pids []PID, pid int, children map[int]struct{}) {
-	for _, p := range subprocs(pids, pid) {
-		if _, ok := children[p.Pid]; ok {
+// Targets returns the pids of the processes signaled by a supervisor
+// of the process when reaping with the default options: the
+// descendants of the process excluding the calling process. The process
+// table is read but no process is signaled.
+func Targets(ps Process) ([]int, error) {
+	pids, err := ps.Children()
+	if err != nil {
+		return nil, err
+	}
+
+	self := os.Getpid()
+
+	targets := make([]int, 0, len(pids))
+	for _, pid := range pids {
+		if pid <= 0 || pid == self {
 			continue
 		}
-		children[p.Pid] = struct{}{}
-		walk(pids, p.Pid, children)
+		targets = append(targets, pid)
+	}
+
+	return targets, nil
+}
+
+// Partition splits the descendants of a process into the descendants of
+// a child process (including the child) and the remaining descendants.
+// The remaining descendants are processes not started by the child such
+// as orphans reparented to a subreaper.
+func Partition(pids []PID, pid, child int) (tree []int, other []int) {
+	cld := make(map[int]struct{})
+	cld[child] = struct{}{}
+	for _, p := range Descendants(pids, child) {
+		cld[p] = struct{}{}
+	}
+
+	tree = make([]int, 0)
+	other = make([]int, 0)
+
+	for _, p := range Descendants(pids, pid) {
+		if _, ok := cld[p]; ok {
+			tree = append(tree, p)
+			continue
+		}
+		other = append(other, p)
+	}
+
+	return tree, other
+}
+
+// Environ returns the initial environment of a process.
+func (ps *Ps) Environ(pid int) ([]string, error) {
+	b, err := readFile(fmt.Sprintf("%s/%d/environ", ps.procfs, pid))
+	if err != nil {
+		return nil, err
 	}
+	env := strings.Split(string(b), "\x00")
+	if len(env) > 0 && env[len(env)-1] == "" {
+		env = env[:len(env)-1]
+	}
+	return env, nil
+}
+
+// Cmdline returns the command line arguments of a process. A process
+// may modify the arguments.
+func (ps *Ps) Cmdline(pid int) ([]string, error) {
+	b, err := readFile(fmt.Sprintf("%s/%d/cmdline", ps.procfs, pid))
+	if err != nil {
+		return nil, err
+	}
+	argv := strings.Split(string(b), "\x00")
+	if len(argv) > 0 && argv[len(argv)-1] == "" {
+		argv = argv[:len(argv)-1]
+	}
+	return argv, nil
+}
+
+// Exe returns the path of the executable of a process.
+func (ps *Ps) Exe(pid int) (string, error) {
+	return os.Readlink(fmt.Sprintf("%s/%d/exe", ps.procfs, pid))
+}
+
+// Stat returns the process table entry of a process, including the
+// effective user ID.
+func (ps *Ps) Stat(pid int) (PID, error) {
+	return readProcStat(fmt.Sprintf("%s/%d/stat", ps.procfs, pid), ps.fields()|fieldOwner)
+}
+
+// StartTime returns the time the process started after system boot in
+// clock ticks. The start time identifies a process: a pid reused by a
+// new process will have a different start time.
+func (ps *Ps) StartTime(pid int) (uint64, error) {
+	b, err := readFile(fmt.Sprintf("%s/%d/stat", ps.procfs, pid))
+	if err != nil {
+		return 0, err
+	}
+
+	return parseStartTime(b)
 }
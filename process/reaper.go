@@ -0,0 +1,7 @@
+//go:build !freebsd && !darwin && !windows
+
+package process
+
+func platformProcess(ps *Ps) (Process, bool) {
+	return nil, false
+}
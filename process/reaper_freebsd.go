@@ -0,0 +1,32 @@
+package process
+
+import (
+	"os"
+
+	"github.com/msantos/goreap/subreaper"
+)
+
+// Reaper sets the configuration for generating a process snapshot using
+// the procctl(2) reaper facility (PROC_REAP_GETPIDS). Reaper is used if
+// procfs is not mounted and the process is a reaper (see subreaper.Set).
+//
+// Snapshot requires procfs.
+type Reaper struct {
+	*Ps
+}
+
+// Children returns the list of descendants of the reaper.
+func (ps *Reaper) Children() ([]int, error) {
+	pids, err := subreaper.Pids()
+	if err != nil {
+		return nil, err
+	}
+	return ps.filterPids(pids), nil
+}
+
+func platformProcess(ps *Ps) (Process, bool) {
+	if ps.pid != os.Getpid() || isProcMounted(ps.procfs) || !subreaper.Get() {
+		return nil, false
+	}
+	return &Reaper{Ps: ps}, true
+}
@@ -0,0 +1,272 @@
+package process
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// statPool contains buffers for reading /proc/<pid>/stat.
+var statPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+// readStatFile reads a file into buf.
+var readStatFile = func(name string, buf []byte) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return buf, err
+	}
+	defer f.Close()
+
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		n, err := f.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		switch {
+		case err == io.EOF:
+			return buf, nil
+		case err != nil:
+			return buf, err
+		}
+	}
+}
+
+// readStat reads /proc/<pid>/stat into a buffer from statPool. The
+// buffer is returned to the pool by the caller.
+func readStat(name string) (*[]byte, error) {
+	bp := statPool.Get().(*[]byte)
+
+	b, err := readStatFile(name, (*bp)[:0])
+	*bp = b
+	if err != nil {
+		statPool.Put(bp)
+		return nil, err
+	}
+
+	return bp, nil
+}
+
+// fields selects the process attributes read from procfs.
+type fields uint8
+
+const (
+	// fieldDetails parses the process details (see WithDetails)
+	fieldDetails fields = 1 << iota
+	// fieldOwner reads the uid and gid from the owner of the stat file
+	fieldOwner
+	// fieldStart parses the start time
+	fieldStart
+)
+
+// readProcStat reads the fields of /proc/<pid>/stat.
+func readProcStat(name string, f fields) (PID, error) {
+	bp, err := readStat(name)
+	if err != nil {
+		return PID{}, err
+	}
+
+	details := f&fieldDetails != 0
+
+	p, err := parseProcStat(*bp, details)
+	if err == nil && !details && f&fieldStart != 0 {
+		// the start time is 0 if the stat file is truncated
+		p.StartTime, _ = parseStartTime(*bp)
+	}
+	statPool.Put(bp)
+	if err != nil {
+		return PID{}, err
+	}
+
+	if f&fieldOwner == 0 {
+		return p, nil
+	}
+
+	// the owner of the stat file is the effective uid of the process
+	fi, err := os.Stat(name)
+	if err != nil {
+		return PID{}, err
+	}
+
+	uid, gid := fileOwner(fi)
+
+	p.Uid = uid
+	if details {
+		p.Gid = gid
+	}
+
+	return p, nil
+}
+
+// ParseStat parses the contents of /proc/<pid>/stat, including the
+// process group, session and start time. The uid and gid are the owner
+// of the stat file and are not set.
+//
+// ParseStat does not allocate except for the command name.
+func ParseStat(b []byte) (PID, error) {
+	return parseProcStat(b, true)
+}
+
+// parseProcStat parses the contents of /proc/<pid>/stat. The uid and gid
+// are read from the owner of the file and are not set.
+func parseProcStat(b []byte, details bool) (PID, error) {
+	// <pid> (<comm>) <state> <ppid> ...
+	// 21230 (cat) R 9985
+	//
+	// comm may contain spaces, brackets and newlines
+	// 21230 (cat foo) R ...
+	// 21230 (cat (foo) S) R ...
+	// 21230 (cat (foo)
+	// S) R ...
+	sp := bytes.IndexByte(b, ' ')
+	if sp == -1 {
+		return PID{}, ErrInvalid
+	}
+
+	pid, ok := atoi(b[:sp])
+	if !ok {
+		return PID{}, ErrInvalid
+	}
+
+	open := bytes.IndexByte(b, '(')
+	bracket := bytes.LastIndexByte(b, ')')
+	if open == -1 || bracket < open {
+		return PID{}, ErrInvalid
+	}
+
+	// ) <state> <ppid> ...
+	rest := b[bracket+1:]
+	if len(rest) < 4 || rest[0] != ' ' || rest[2] != ' ' {
+		return PID{}, ErrInvalid
+	}
+
+	state := rest[1]
+
+	// <ppid> <pgrp> <session> <tty_nr> ... <starttime> ...
+	rest = rest[3:]
+
+	field, rest := nextField(rest)
+	ppid, ok := atoi(field)
+	if !ok {
+		return PID{}, ErrInvalid
+	}
+
+	p := PID{
+		Pid:   pid,
+		PPid:  ppid,
+		State: state,
+		Comm:  string(b[open+1 : bracket]),
+	}
+
+	if !details {
+		return p, nil
+	}
+
+	field, rest = nextField(rest)
+	if p.Pgrp, ok = atoi(field); !ok {
+		return PID{}, ErrInvalid
+	}
+
+	field, rest = nextField(rest)
+	if p.Session, ok = atoi(field); !ok {
+		return PID{}, ErrInvalid
+	}
+
+	// starttime is the 16th field following session
+	for i := 0; i < 15; i++ {
+		_, rest = nextField(rest)
+	}
+
+	field, _ = nextField(rest)
+	if p.StartTime, ok = parseUint(field); !ok {
+		return PID{}, ErrInvalid
+	}
+
+	return p, nil
+}
+
+// parseStartTime returns the start time from the contents of
+// /proc/<pid>/stat.
+func parseStartTime(b []byte) (uint64, error) {
+	bracket := bytes.LastIndexByte(b, ')')
+	if bracket == -1 {
+		return 0, ErrInvalid
+	}
+
+	// fields following comm: starttime is field 22 of stat
+	rest := b[bracket+1:]
+	for i := 0; i < 19; i++ {
+		_, rest = nextField(rest)
+	}
+
+	start, ok := parseUint(firstField(rest))
+	if !ok {
+		return 0, ErrInvalid
+	}
+
+	return start, nil
+}
+
+// nextField returns the first whitespace separated field and the
+// remaining input.
+func nextField(b []byte) ([]byte, []byte) {
+	i := 0
+	for i < len(b) && isSpace(b[i]) {
+		i++
+	}
+	j := i
+	for j < len(b) && !isSpace(b[j]) {
+		j++
+	}
+	return b[i:j], b[j:]
+}
+
+func firstField(b []byte) []byte {
+	field, _ := nextField(b)
+	return field
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
+
+// atoi parses a decimal integer without allocating.
+func atoi(b []byte) (int, bool) {
+	neg := len(b) > 0 && b[0] == '-'
+	if neg {
+		b = b[1:]
+	}
+
+	n, ok := parseUint(b)
+	if !ok || n > 1<<62 {
+		return 0, false
+	}
+
+	if neg {
+		return -int(n), true
+	}
+	return int(n), true
+}
+
+// parseUint parses an unsigned decimal integer without allocating.
+func parseUint(b []byte) (uint64, bool) {
+	if len(b) == 0 || len(b) > 19 {
+		return 0, false
+	}
+
+	var n uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + uint64(c-'0')
+	}
+
+	return n, true
+}
@@ -0,0 +1,96 @@
+package process
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// process states (see sys/proc.h)
+const (
+	sidl   = 1 // process being created by fork
+	srun   = 2 // currently runnable
+	ssleep = 3 // sleeping on an address
+	sstop  = 4 // process debugging or suspension
+	szomb  = 5 // awaiting collection by parent
+)
+
+// Sysctl sets the configuration for generating a process snapshot using
+// the kern.proc.all sysctl(3). Sysctl is used on platforms without
+// procfs.
+//
+// Environ, Cmdline and Exe require procfs.
+type Sysctl struct {
+	*Ps
+}
+
+func platformProcess(ps *Ps) (Process, bool) {
+	if isProcMounted(ps.procfs) {
+		return nil, false
+	}
+	return &Sysctl{Ps: ps}, true
+}
+
+// Snapshot returns a snapshot of the system process table.
+func (ps *Sysctl) Snapshot() ([]PID, error) {
+	p, err := ps.sysctl()
+	if err != nil {
+		return nil, err
+	}
+	return ps.filterTable(p), nil
+}
+
+// Children returns the descendants of the process.
+func (ps *Sysctl) Children() ([]int, error) {
+	p, err := ps.sysctl()
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.descendantsOf(p)
+}
+
+// sysctl reads the process table.
+func (ps *Sysctl) sysctl() ([]PID, error) {
+	kinfo, err := unix.SysctlKinfoProcSlice("kern.proc.all")
+	if err != nil {
+		return nil, err
+	}
+
+	p := make([]PID, 0, len(kinfo))
+
+	for i := range kinfo {
+		k := &kinfo[i]
+
+		v := PID{
+			Pid:   int(k.Proc.P_pid),
+			PPid:  int(k.Eproc.Ppid),
+			State: state(k.Proc.P_stat),
+			Comm:  unix.ByteSliceToString(k.Proc.P_comm[:]),
+			Uid:   int(k.Eproc.Ucred.Uid),
+		}
+
+		if ps.details {
+			v.Pgrp = int(k.Eproc.Pgid)
+			v.Gid = int(k.Eproc.Ucred.Groups[0])
+		}
+
+		p = append(p, v)
+	}
+
+	return p, nil
+}
+
+// state converts a process state to the procfs state.
+func state(stat int8) byte {
+	switch stat {
+	case sidl, srun:
+		return 'R'
+	case ssleep:
+		return 'S'
+	case sstop:
+		return 'T'
+	case szomb:
+		return 'Z'
+	default:
+		return '?'
+	}
+}
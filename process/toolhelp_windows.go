@@ -0,0 +1,72 @@
+package process
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Toolhelp sets the configuration for generating a process snapshot
+// using CreateToolhelp32Snapshot.
+//
+// Process IDs are reused on Windows: the parent process ID of a
+// process whose parent has exited may refer to an unrelated process.
+// The process state is always 'R' and the uid is -1. Environ, Cmdline
+// and Exe require procfs.
+type Toolhelp struct {
+	*Ps
+}
+
+func platformProcess(ps *Ps) (Process, bool) {
+	return &Toolhelp{Ps: ps}, true
+}
+
+// Snapshot returns a snapshot of the system process table.
+func (ps *Toolhelp) Snapshot() ([]PID, error) {
+	p, err := ps.toolhelp()
+	if err != nil {
+		return nil, err
+	}
+	return ps.filterTable(p), nil
+}
+
+// Children returns the descendants of the process.
+func (ps *Toolhelp) Children() ([]int, error) {
+	p, err := ps.toolhelp()
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.descendantsOf(p)
+}
+
+// toolhelp reads the process table.
+func (ps *Toolhelp) toolhelp() ([]PID, error) {
+	h, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	var e windows.ProcessEntry32
+	e.Size = uint32(unsafe.Sizeof(e))
+
+	p := make([]PID, 0)
+
+	for err = windows.Process32First(h, &e); err == nil; err = windows.Process32Next(h, &e) {
+		p = append(p, PID{
+			Pid:   int(e.ProcessID),
+			PPid:  int(e.ParentProcessID),
+			State: 'R',
+			Comm:  windows.UTF16ToString(e.ExeFile[:]),
+			Uid:   -1,
+			Gid:   -1,
+		})
+	}
+
+	if err != windows.ERROR_NO_MORE_FILES {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,97 @@
+package process
+
+import (
+	"sort"
+	"sync"
+)
+
+// Tracker polls the process table and records the processes added and
+// removed since the previous poll (see Diff).
+//
+// Processes are identified by pid and start time: a pid reused by a new
+// process between polls is reported as removed and added. Process tables
+// read from procfs (see New) include the start time of processes.
+type Tracker struct {
+	Process
+
+	ps *Ps // procfs process table or nil
+
+	mu      sync.Mutex
+	seen    map[int]uint64 // start time of processes by pid
+	added   []int
+	removed []int
+}
+
+// NewTracker returns a Tracker for a process table.
+func NewTracker(p Process) *Tracker {
+	t := &Tracker{Process: p}
+
+	switch v := p.(type) {
+	case *Ps:
+		t.ps = v
+	case *ProcChildren:
+		t.ps = v.Ps
+	}
+
+	return t
+}
+
+// Snapshot returns a snapshot of the process table and records the
+// processes added and removed since the previous snapshot.
+func (t *Tracker) Snapshot() ([]PID, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var p []PID
+	var err error
+
+	if t.ps != nil {
+		p, err = t.ps.read(t.ps.fields() | fieldStart)
+	} else {
+		p, err = t.Process.Snapshot()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[int]uint64, len(p))
+	for _, v := range p {
+		ids[v.Pid] = v.StartTime
+	}
+	t.diff(ids)
+
+	return p, nil
+}
+
+// Diff returns the pids of processes added and removed between the two
+// most recent snapshots. Processes in the first snapshot are added.
+func (t *Tracker) Diff() (added, removed []int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append(make([]int, 0, len(t.added)), t.added...),
+		append(make([]int, 0, len(t.removed)), t.removed...)
+}
+
+// diff records the changes from the previous snapshot.
+func (t *Tracker) diff(ids map[int]uint64) {
+	t.added = t.added[:0]
+	t.removed = t.removed[:0]
+
+	for pid, start := range ids {
+		if s, ok := t.seen[pid]; !ok || s != start {
+			t.added = append(t.added, pid)
+		}
+	}
+
+	for pid, start := range t.seen {
+		if s, ok := ids[pid]; !ok || s != start {
+			t.removed = append(t.removed, pid)
+		}
+	}
+
+	sort.Ints(t.added)
+	sort.Ints(t.removed)
+
+	t.seen = ids
+}
@@ -0,0 +1,112 @@
+package process
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Node is a process in a process tree.
+type Node struct {
+	PID
+	Children []*Node // subprocesses ordered by pid
+}
+
+// Tree returns the process tree rooted at a process from a snapshot of
+// the process table (see New for options). ErrSearch is returned if the
+// process is not running.
+func Tree(pid int, opts ...Option) (*Node, error) {
+	ps := New(append([]Option{WithPid(pid)}, opts...)...)
+
+	pids, err := ps.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range pids {
+		if p.Pid == pid {
+			return NewTree(pids, pid), nil
+		}
+	}
+
+	return nil, ErrSearch
+}
+
+// NewTree returns the process tree rooted at pid from a process table.
+// If the process is not in the table, the root node contains the pid
+// only.
+func NewTree(pids []PID, pid int) *Node {
+	nodes := make(map[int]*Node, len(pids))
+
+	for _, p := range pids {
+		nodes[p.Pid] = &Node{PID: p}
+	}
+
+	for _, n := range nodes {
+		if n.Pid == pid || n.PPid == n.Pid {
+			continue
+		}
+		if parent, ok := nodes[n.PPid]; ok {
+			parent.Children = append(parent.Children, n)
+		}
+	}
+
+	for _, n := range nodes {
+		sort.Slice(n.Children, func(i, j int) bool {
+			return n.Children[i].Pid < n.Children[j].Pid
+		})
+	}
+
+	root, ok := nodes[pid]
+	if !ok {
+		return &Node{PID: PID{Pid: pid}}
+	}
+
+	return root
+}
+
+// Draw writes the process tree using branch characters. label returns
+// the line written for a process.
+func (n *Node) Draw(w io.Writer, label func(*Node) string) (int64, error) {
+	var b strings.Builder
+
+	b.WriteString(label(n))
+	b.WriteByte('\n')
+
+	var walk func(n *Node, prefix string)
+	walk = func(n *Node, prefix string) {
+		for i, c := range n.Children {
+			branch, indent := "|-", "| "
+			if i == len(n.Children)-1 {
+				branch, indent = "`-", "  "
+			}
+			b.WriteString(prefix + branch + label(c) + "\n")
+			walk(c, prefix+indent)
+		}
+	}
+	walk(n, "")
+
+	written, err := io.WriteString(w, b.String())
+	return int64(written), err
+}
+
+// WriteTo writes the process tree labeled by the pid and command name of
+// each process.
+func (n *Node) WriteTo(w io.Writer) (int64, error) {
+	return n.Draw(w, label)
+}
+
+func (n *Node) String() string {
+	var b strings.Builder
+	_, _ = n.WriteTo(&b)
+	return b.String()
+}
+
+// label returns the pid and command name of a process.
+func label(n *Node) string {
+	if n.Comm == "" {
+		return strconv.Itoa(n.Pid)
+	}
+	return strconv.Itoa(n.Pid) + " " + n.Comm
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package process
+
+import (
+	"context"
+	"syscall"
+)
+
+// Watch is not supported on this platform.
+func Watch(ctx context.Context) (<-chan Event, error) {
+	return nil, syscall.ENOSYS
+}
@@ -0,0 +1,178 @@
+package process
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// proc connector constants (linux/cn_proc.h, linux/connector.h)
+const (
+	cnIdxProc = 1
+	cnValProc = 1
+
+	procCnMcastListen = 1
+
+	procEventFork = 0x00000001
+	procEventExec = 0x00000002
+	procEventExit = 0x80000000
+
+	// struct cn_msg
+	cnMsgSize = 20
+
+	// struct proc_event: what, cpu, timestamp_ns
+	procEventHeaderSize = 16
+)
+
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// Watch subscribes to process events using the Linux proc connector
+// (CONFIG_PROC_EVENTS). Events are sent for all processes on the system.
+// Thread events are ignored. Subscribing requires CAP_NET_ADMIN.
+//
+// The channel is closed when the context is canceled. Events are
+// dropped if the channel is not read: the channel is also closed if the
+// socket returns an error other than an overrun.
+func Watch(ctx context.Context) (<-chan Event, error) {
+	fd, err := unix.Socket(
+		unix.AF_NETLINK,
+		unix.SOCK_DGRAM|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK,
+		unix.NETLINK_CONNECTOR,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: cnIdxProc,
+	}); err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+
+	if err := unix.Sendto(fd, listenMsg(), 0, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+	}); err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(fd), "proc-connector")
+
+	ch := make(chan Event, 64)
+
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	go func() {
+		defer close(ch)
+
+		buf := make([]byte, os.Getpagesize())
+
+		for {
+			n, err := f.Read(buf)
+			switch {
+			case err == nil:
+			case errors.Is(err, syscall.ENOBUFS):
+				continue
+			default:
+				return
+			}
+
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+
+			for _, msg := range msgs {
+				ev, ok := parseEvent(msg.Data)
+				if !ok {
+					continue
+				}
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				default:
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// listenMsg returns the netlink message subscribing to process events.
+func listenMsg() []byte {
+	b := make([]byte, syscall.NLMSG_HDRLEN+cnMsgSize+4)
+
+	// struct nlmsghdr
+	nativeEndian.PutUint32(b[0:], uint32(len(b)))
+	nativeEndian.PutUint16(b[4:], syscall.NLMSG_DONE)
+
+	// struct cn_msg
+	msg := b[syscall.NLMSG_HDRLEN:]
+	nativeEndian.PutUint32(msg[0:], cnIdxProc)
+	nativeEndian.PutUint32(msg[4:], cnValProc)
+	nativeEndian.PutUint16(msg[16:], 4)
+
+	// enum proc_cn_mcast_op
+	nativeEndian.PutUint32(msg[cnMsgSize:], procCnMcastListen)
+
+	return b
+}
+
+// parseEvent decodes a struct proc_event contained in a struct cn_msg.
+func parseEvent(b []byte) (Event, bool) {
+	if len(b) < cnMsgSize+procEventHeaderSize {
+		return Event{}, false
+	}
+
+	what := nativeEndian.Uint32(b[cnMsgSize:])
+	data := b[cnMsgSize+procEventHeaderSize:]
+
+	u32 := func(i int) int {
+		return int(int32(nativeEndian.Uint32(data[i*4:])))
+	}
+
+	switch what {
+	case procEventFork:
+		// parent_pid, parent_tgid, child_pid, child_tgid
+		if len(data) < 16 || u32(2) != u32(3) {
+			return Event{}, false
+		}
+		return Event{Type: EventFork, Pid: u32(3), PPid: u32(1)}, true
+	case procEventExec:
+		// process_pid, process_tgid
+		if len(data) < 8 || u32(0) != u32(1) {
+			return Event{}, false
+		}
+		return Event{Type: EventExec, Pid: u32(1)}, true
+	case procEventExit:
+		// process_pid, process_tgid, exit_code, exit_signal
+		if len(data) < 16 || u32(0) != u32(1) {
+			return Event{}, false
+		}
+		return Event{
+			Type:   EventExit,
+			Pid:    u32(1),
+			Status: syscall.WaitStatus(u32(2)),
+		}, true
+	}
+
+	return Event{}, false
+}
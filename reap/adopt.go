@@ -0,0 +1,90 @@
+package reap
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// Adopt supervises a running process which was not started by
+// Supervise, e.g., a service started before the supervisor. When the
+// process exits, the descendants of the process are signaled and reaped
+// as if the process was the foreground process. Signals received while
+// the process is running are forwarded to the process and its
+// descendants.
+//
+// Descendants of a process which is not a child of this process are
+// not reparented to this process: descendants are recorded while the
+// process is running and signaled by pid (see WithReexec for the
+// limitations of tracking processes by pid).
+//
+// If the process is a child of this process, the exit status of the
+// process is returned. Otherwise, the exit status is not available and
+// Adopt returns 0 when the process exits.
+func (r *Reap) Adopt(pid int) (int, error) {
+	if err := kill(pid, 0); err != nil && !errors.Is(err, syscall.EPERM) {
+		return 111, fmt.Errorf("adopt %d: %w", pid, err)
+	}
+
+	r.adopted = pid
+	defer func() {
+		r.adopted = 0
+	}()
+
+	return r.supervise(nil, func(r *supervision) (int, error) {
+		return r.adopt(pid)
+	})
+}
+
+// adopt waits for an adopted process to exit.
+func (r *supervision) adopt(pid int) (int, error) {
+	r.notify("%d: adopt: foreground %d", r.Pid(), pid)
+
+	var ws syscall.WaitStatus
+	wpid, err := wait4(pid, &ws, wnohang, nil)
+
+	var waitch <-chan error
+
+	switch {
+	case err == nil && wpid == 0:
+		waitch = waitChild(pid)
+	case err == nil:
+		// the child exited before being adopted
+		ch := make(chan error, 1)
+		if !ws.Exited() || ws.ExitStatus() != 0 {
+			ch <- exitStatus(ws)
+		} else {
+			ch <- nil
+		}
+		waitch = ch
+	case errors.Is(err, syscall.ECHILD):
+		waitch = waitExit(pid)
+	default:
+		return 111, fmt.Errorf("adopt %d: %w", pid, err)
+	}
+
+	r.orphans.mu.Lock()
+	if r.orphans.pids == nil {
+		r.orphans.pids = make(map[int]struct{})
+	}
+	r.orphans.pids[pid] = struct{}{}
+	r.orphans.mu.Unlock()
+
+	r.track()
+
+	return r.waitpid(pid, waitch, nil)
+}
+
+// pollExit waits for a process to exit by periodically checking the
+// process exists.
+func pollExit(pid int) {
+	t := time.NewTicker(trackInterval)
+	defer t.Stop()
+
+	for range t.C {
+		if err := kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+			return
+		}
+	}
+}
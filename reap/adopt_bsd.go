@@ -0,0 +1,50 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package reap
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitExit waits for a process which is not a child of this process to
+// exit. The process is monitored using a kqueue(2) EVFILT_PROC filter
+// or, if the filter cannot be added, by pid.
+func waitExit(pid int) <-chan error {
+	waitch := make(chan error, 1)
+
+	go func() {
+		defer close(waitch)
+
+		kq, err := unix.Kqueue()
+		if err != nil {
+			pollExit(pid)
+			return
+		}
+		defer unix.Close(kq)
+
+		changes := make([]unix.Kevent_t, 1)
+		unix.SetKevent(&changes[0], pid, unix.EVFILT_PROC, unix.EV_ADD|unix.EV_ONESHOT)
+		changes[0].Fflags = unix.NOTE_EXIT
+
+		if _, err := unix.Kevent(kq, changes, nil, nil); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return
+			}
+			pollExit(pid)
+			return
+		}
+
+		events := make([]unix.Kevent_t, 1)
+		for {
+			_, err := unix.Kevent(kq, nil, events, nil)
+			if !errors.Is(err, syscall.EINTR) {
+				return
+			}
+		}
+	}()
+
+	return waitch
+}
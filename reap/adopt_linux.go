@@ -0,0 +1,39 @@
+package reap
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitExit waits for a process which is not a child of this process to
+// exit. The process is polled using a pidfd or, if pidfds are not
+// supported, by pid.
+func waitExit(pid int) <-chan error {
+	waitch := make(chan error, 1)
+
+	go func() {
+		defer close(waitch)
+
+		fd, err := unix.PidfdOpen(pid, 0)
+		if err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return
+			}
+			pollExit(pid)
+			return
+		}
+		defer unix.Close(fd)
+
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		for {
+			_, err := unix.Poll(fds, -1)
+			if !errors.Is(err, syscall.EINTR) {
+				return
+			}
+		}
+	}()
+
+	return waitch
+}
@@ -0,0 +1,26 @@
+package reap
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// waitExit waits for a process to exit using the process handle or, if
+// the process cannot be opened, by pid.
+func waitExit(pid int) <-chan error {
+	waitch := make(chan error, 1)
+
+	go func() {
+		defer close(waitch)
+
+		h, err := windows.OpenProcess(windows.SYNCHRONIZE, false, uint32(pid))
+		if err != nil {
+			pollExit(pid)
+			return
+		}
+		defer windows.CloseHandle(h)
+
+		_, _ = windows.WaitForSingleObject(h, windows.INFINITE)
+	}()
+
+	return waitch
+}
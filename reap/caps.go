@@ -0,0 +1,28 @@
+package reap
+
+// WithDropCapabilities drops capabilities from the bounding set of the
+// foreground process, e.g., []string{"NET_RAW", "SYS_ADMIN"}. "ALL" drops
+// all capabilities except capabilities added using WithAddCapabilities.
+// Capabilities are named with or without the CAP_ prefix. Dropping
+// capabilities requires CAP_SETPCAP. Capabilities are supported on Linux.
+//
+// The bounding set is reduced on a thread of this process dedicated to
+// starting the foreground process. The thread exits when supervision
+// ends. Capabilities cannot be dropped if restarting is enabled (see
+// WithRestartSignal).
+func WithDropCapabilities(caps []string) Option {
+	return func(r *Reap) {
+		r.capDrop = caps
+	}
+}
+
+// WithAddCapabilities keeps capabilities in the bounding set of the
+// foreground process when capabilities are dropped (see
+// WithDropCapabilities). The capabilities are raised in the ambient set:
+// the capabilities are retained by a foreground process running as a
+// user (see WithUser).
+func WithAddCapabilities(caps []string) Option {
+	return func(r *Reap) {
+		r.capAdd = caps
+	}
+}
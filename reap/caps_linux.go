@@ -0,0 +1,154 @@
+package reap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// capabilities maps capability names to numbers.
+var capabilities = map[string]uintptr{
+	"CAP_AUDIT_CONTROL":      unix.CAP_AUDIT_CONTROL,
+	"CAP_AUDIT_READ":         unix.CAP_AUDIT_READ,
+	"CAP_AUDIT_WRITE":        unix.CAP_AUDIT_WRITE,
+	"CAP_BLOCK_SUSPEND":      unix.CAP_BLOCK_SUSPEND,
+	"CAP_BPF":                unix.CAP_BPF,
+	"CAP_CHECKPOINT_RESTORE": unix.CAP_CHECKPOINT_RESTORE,
+	"CAP_CHOWN":              unix.CAP_CHOWN,
+	"CAP_DAC_OVERRIDE":       unix.CAP_DAC_OVERRIDE,
+	"CAP_DAC_READ_SEARCH":    unix.CAP_DAC_READ_SEARCH,
+	"CAP_FOWNER":             unix.CAP_FOWNER,
+	"CAP_FSETID":             unix.CAP_FSETID,
+	"CAP_IPC_LOCK":           unix.CAP_IPC_LOCK,
+	"CAP_IPC_OWNER":          unix.CAP_IPC_OWNER,
+	"CAP_KILL":               unix.CAP_KILL,
+	"CAP_LEASE":              unix.CAP_LEASE,
+	"CAP_LINUX_IMMUTABLE":    unix.CAP_LINUX_IMMUTABLE,
+	"CAP_MAC_ADMIN":          unix.CAP_MAC_ADMIN,
+	"CAP_MAC_OVERRIDE":       unix.CAP_MAC_OVERRIDE,
+	"CAP_MKNOD":              unix.CAP_MKNOD,
+	"CAP_NET_ADMIN":          unix.CAP_NET_ADMIN,
+	"CAP_NET_BIND_SERVICE":   unix.CAP_NET_BIND_SERVICE,
+	"CAP_NET_BROADCAST":      unix.CAP_NET_BROADCAST,
+	"CAP_NET_RAW":            unix.CAP_NET_RAW,
+	"CAP_PERFMON":            unix.CAP_PERFMON,
+	"CAP_SETFCAP":            unix.CAP_SETFCAP,
+	"CAP_SETGID":             unix.CAP_SETGID,
+	"CAP_SETPCAP":            unix.CAP_SETPCAP,
+	"CAP_SETUID":             unix.CAP_SETUID,
+	"CAP_SYSLOG":             unix.CAP_SYSLOG,
+	"CAP_SYS_ADMIN":          unix.CAP_SYS_ADMIN,
+	"CAP_SYS_BOOT":           unix.CAP_SYS_BOOT,
+	"CAP_SYS_CHROOT":         unix.CAP_SYS_CHROOT,
+	"CAP_SYS_MODULE":         unix.CAP_SYS_MODULE,
+	"CAP_SYS_NICE":           unix.CAP_SYS_NICE,
+	"CAP_SYS_PACCT":          unix.CAP_SYS_PACCT,
+	"CAP_SYS_PTRACE":         unix.CAP_SYS_PTRACE,
+	"CAP_SYS_RAWIO":          unix.CAP_SYS_RAWIO,
+	"CAP_SYS_RESOURCE":       unix.CAP_SYS_RESOURCE,
+	"CAP_SYS_TIME":           unix.CAP_SYS_TIME,
+	"CAP_SYS_TTY_CONFIG":     unix.CAP_SYS_TTY_CONFIG,
+	"CAP_WAKE_ALARM":         unix.CAP_WAKE_ALARM,
+}
+
+// capLast returns the highest capability supported by the kernel.
+func capLast() uintptr {
+	b, err := os.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return unix.CAP_LAST_CAP
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || n < 0 {
+		return unix.CAP_LAST_CAP
+	}
+	return uintptr(n)
+}
+
+// parseCapabilities returns the set of capabilities by name. The set
+// contains all capabilities if all is true and a name is "ALL".
+func parseCapabilities(names []string, all bool) (map[uintptr]struct{}, error) {
+	caps := make(map[uintptr]struct{}, len(names))
+	for _, name := range names {
+		name = strings.ToUpper(name)
+		if all && name == "ALL" {
+			for c := uintptr(0); c <= capLast(); c++ {
+				caps[c] = struct{}{}
+			}
+			continue
+		}
+		if !strings.HasPrefix(name, "CAP_") {
+			name = "CAP_" + name
+		}
+		c, ok := capabilities[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown capability: %s", name)
+		}
+		caps[c] = struct{}{}
+	}
+	return caps, nil
+}
+
+// checkCapabilities returns an error if a capability is invalid.
+func (r *Reap) checkCapabilities() error {
+	if _, err := parseCapabilities(r.capDrop, true); err != nil {
+		return err
+	}
+	_, err := parseCapabilities(r.capAdd, false)
+	return err
+}
+
+// dropCapabilities drops capabilities from the bounding and ambient sets
+// of the calling thread. Added capabilities are not dropped.
+func (r *Reap) dropCapabilities() error {
+	drop, err := parseCapabilities(r.capDrop, true)
+	if err != nil {
+		return err
+	}
+
+	add, err := parseCapabilities(r.capAdd, false)
+	if err != nil {
+		return err
+	}
+
+	for c := uintptr(0); c <= capLast(); c++ {
+		if _, ok := drop[c]; !ok {
+			continue
+		}
+		if _, ok := add[c]; ok {
+			continue
+		}
+		if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_LOWER, c, 0, 0); err != nil && !errors.Is(err, unix.EINVAL) {
+			return fmt.Errorf("prctl(PR_CAP_AMBIENT_LOWER): %d: %w", c, err)
+		}
+		ok, err := unix.PrctlRetInt(unix.PR_CAPBSET_READ, c, 0, 0, 0)
+		if err != nil {
+			return fmt.Errorf("prctl(PR_CAPBSET_READ): %d: %w", c, err)
+		}
+		if ok == 0 {
+			continue
+		}
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, c, 0, 0, 0); err != nil {
+			return fmt.Errorf("prctl(PR_CAPBSET_DROP): %d: %w", c, err)
+		}
+	}
+
+	return nil
+}
+
+// capabilityAttr raises the added capabilities in the ambient set of the
+// foreground process.
+func (r *Reap) capabilityAttr(attr *syscall.SysProcAttr) error {
+	add, err := parseCapabilities(r.capAdd, false)
+	if err != nil {
+		return fmt.Errorf("capabilities: %w", err)
+	}
+	for c := range add {
+		attr.AmbientCaps = append(attr.AmbientCaps, c)
+	}
+	return nil
+}
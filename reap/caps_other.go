@@ -0,0 +1,21 @@
+//go:build !linux
+
+package reap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func (r *Reap) checkCapabilities() error {
+	return nil
+}
+
+// capabilityAttr is not supported on this platform: capabilities are
+// specific to Linux.
+func (r *Reap) capabilityAttr(attr *syscall.SysProcAttr) error {
+	if len(r.capDrop) > 0 || len(r.capAdd) > 0 {
+		return fmt.Errorf("capabilities: %w", syscall.ENOSYS)
+	}
+	return nil
+}
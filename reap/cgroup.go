@@ -0,0 +1,112 @@
+package reap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// WithCgroup runs the foreground process in a cgroup v2 cgroup. The
+// cgroup is created if it does not exist and removed after
+// subprocesses have exited.
+//
+// When subprocesses are sent SIGKILL, all processes in the cgroup are
+// killed atomically by writing to cgroup.kill (Linux 5.14 or later)
+// before subprocesses are signaled by pid. Reaping completes when the
+// cgroup is empty (see cgroup.events).
+//
+// The foreground process is moved into the cgroup after it starts:
+// subprocesses forked before the move remain in the parent cgroup and
+// are signaled by pid.
+//
+// Cgroups are supported on Linux only.
+func WithCgroup(path string) Option {
+	return func(r *Reap) {
+		r.cgroup = path
+	}
+}
+
+// cgroupCreate creates the cgroup.
+func (r *Reap) cgroupCreate() error {
+	if r.cgroup == "" {
+		return nil
+	}
+
+	err := os.Mkdir(r.cgroup, 0o755)
+	switch {
+	case err == nil:
+		r.cgroupCreated = true
+	case errors.Is(err, os.ErrExist):
+	default:
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(r.cgroup, "cgroup.procs")); err != nil {
+		return fmt.Errorf("%s: not a cgroup: %w", r.cgroup, err)
+	}
+
+	return nil
+}
+
+// cgroupEnter moves a process into the cgroup.
+func (r *Reap) cgroupEnter(pid int) {
+	if r.cgroup == "" {
+		return
+	}
+
+	if err := os.WriteFile(
+		filepath.Join(r.cgroup, "cgroup.procs"),
+		[]byte(strconv.Itoa(pid)),
+		0o644,
+	); err != nil {
+		r.log(fmt.Errorf("%d: cgroup: %w", r.Pid(), err))
+	}
+}
+
+// cgroupKill sends SIGKILL to all processes in the cgroup.
+func (r *Reap) cgroupKill() {
+	if r.cgroup == "" || !r.killAll() {
+		return
+	}
+
+	r.notify("%d: cgroup kill %s", r.Pid(), r.cgroup)
+
+	if err := os.WriteFile(filepath.Join(r.cgroup, "cgroup.kill"), []byte("1"), 0o644); err != nil {
+		r.log(fmt.Errorf("%d: cgroup: %w", r.Pid(), err))
+	}
+}
+
+// cgroupRemove removes the cgroup if created by this process.
+func (r *Reap) cgroupRemove() {
+	if !r.cgroupCreated {
+		return
+	}
+
+	if err := os.Remove(r.cgroup); err != nil {
+		r.log(fmt.Errorf("%d: cgroup: %w", r.Pid(), err))
+		return
+	}
+
+	r.cgroupCreated = false
+}
+
+// populated returns true if any processes are running in the cgroup.
+func populated(path string) (bool, error) {
+	b, err := os.ReadFile(filepath.Join(path, "cgroup.events"))
+	if err != nil {
+		return false, err
+	}
+
+	prefix := []byte("populated ")
+
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if bytes.HasPrefix(line, prefix) {
+			return !bytes.Equal(line[len(prefix):], []byte("0")), nil
+		}
+	}
+
+	return false, fmt.Errorf("%s: cgroup.events: populated: not found", path)
+}
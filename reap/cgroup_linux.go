@@ -0,0 +1,58 @@
+package reap
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitCgroup blocks until the cgroup is empty or done is closed.
+func waitCgroup(path string, done <-chan struct{}) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+
+	f := os.NewFile(uintptr(fd), "inotify")
+
+	closed := make(chan struct{})
+	defer close(closed)
+
+	go func() {
+		select {
+		case <-done:
+		case <-closed:
+		}
+		f.Close()
+	}()
+
+	if _, err := unix.InotifyAddWatch(
+		fd,
+		filepath.Join(path, "cgroup.events"),
+		unix.IN_MODIFY,
+	); err != nil {
+		return err
+	}
+
+	buf := make([]byte, syscall.SizeofInotifyEvent+syscall.NAME_MAX+1)
+
+	for {
+		ok, err := populated(path)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if _, err := f.Read(buf); err != nil {
+			select {
+			case <-done:
+				return nil
+			default:
+				return err
+			}
+		}
+	}
+}
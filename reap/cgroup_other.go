@@ -0,0 +1,10 @@
+//go:build !linux
+
+package reap
+
+import "syscall"
+
+// waitCgroup is not supported on this platform.
+func waitCgroup(path string, done <-chan struct{}) error {
+	return syscall.ENOSYS
+}
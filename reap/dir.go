@@ -0,0 +1,41 @@
+package reap
+
+import "os/exec"
+
+// WithDir sets the working directory of the foreground process. If the
+// root directory is changed (see WithChroot), the directory is relative
+// to the new root.
+func WithDir(dir string) Option {
+	return func(r *Reap) {
+		r.dir = dir
+	}
+}
+
+// WithUmask sets the file mode creation mask of the foreground process.
+// A negative mask (the default) inherits the umask of this process.
+//
+// The umask is a process attribute: the umask of this process is set
+// while the foreground process is started and then restored.
+func WithUmask(mask int) Option {
+	return func(r *Reap) {
+		r.umask = mask
+	}
+}
+
+// WithChroot changes the root directory of the foreground process
+// (requires CAP_SYS_CHROOT). The command is not resolved using PATH: use
+// an absolute path to the executable in the new root.
+func WithChroot(dir string) Option {
+	return func(r *Reap) {
+		r.chroot = dir
+	}
+}
+
+// start starts the foreground process with the umask set by WithUmask.
+func (r *Reap) start(cmd *exec.Cmd) error {
+	if err := r.startProcess(cmd); err != nil {
+		return err
+	}
+
+	return r.addGroup(cmd)
+}
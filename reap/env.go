@@ -0,0 +1,52 @@
+package reap
+
+import "strings"
+
+// WithEnv sets environment variables for the foreground process. Each
+// entry has the form "key=value" and replaces any variable with the same
+// key in the environment passed to Supervise.
+func WithEnv(env []string) Option {
+	return func(r *Reap) {
+		r.setenv = append(r.setenv, env...)
+	}
+}
+
+// WithUnsetenv removes environment variables from the environment of
+// the foreground process.
+func WithUnsetenv(keys []string) Option {
+	return func(r *Reap) {
+		r.unsetenv = append(r.unsetenv, keys...)
+	}
+}
+
+// WithClearenv starts the foreground process with an empty environment.
+// Variables set by WithEnv are added to the empty environment.
+func WithClearenv(b bool) Option {
+	return func(r *Reap) {
+		r.clearenv = b
+	}
+}
+
+// environ returns the environment of the foreground process: the
+// environment is cleared, variables are removed and then set.
+func (r *Reap) environ(env []string) []string {
+	if !r.clearenv && len(r.unsetenv) == 0 && len(r.setenv) == 0 {
+		return env
+	}
+
+	e := make([]string, 0, len(env)+len(r.setenv))
+	if !r.clearenv {
+		e = append(e, env...)
+	}
+
+	for _, key := range r.unsetenv {
+		e = withoutEnv(e, key)
+	}
+
+	for _, kv := range r.setenv {
+		key, _, _ := strings.Cut(kv, "=")
+		e = append(withoutEnv(e, key), kv)
+	}
+
+	return e
+}
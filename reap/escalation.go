@@ -0,0 +1,72 @@
+package reap
+
+import (
+	"syscall"
+	"time"
+)
+
+// Step is a signal in an escalation sequence (see WithEscalation).
+type Step struct {
+	// Signal is sent at intervals (see WithDelay) while the step is
+	// active.
+	Signal syscall.Signal
+	// Timeout is the duration of the step before the next signal is
+	// sent. The timeout of the last step is ignored.
+	Timeout time.Duration
+}
+
+// WithEscalation sets a sequence of signals sent to subprocesses, e.g.,
+// SIGTERM, wait 10 seconds, SIGINT, wait 5 seconds, SIGKILL:
+//
+//	[]Step{
+//	  {Signal: syscall.SIGTERM, Timeout: 10 * time.Second},
+//	  {Signal: syscall.SIGINT, Timeout: 5 * time.Second},
+//	  {Signal: syscall.SIGKILL},
+//	}
+//
+// The first step replaces the signal set by WithSignal. The last signal
+// is sent until subprocesses exit. The deadline (see WithDeadline)
+// continues to apply: subprocesses running after the deadline are sent
+// SIGKILL.
+func WithEscalation(steps []Step) Option {
+	return func(r *Reap) {
+		r.escalation = make([]Step, 0, len(steps))
+		for _, s := range steps {
+			if s.Signal == 0 {
+				continue
+			}
+			r.escalation = append(r.escalation, s)
+		}
+	}
+}
+
+// escalationStep returns the signal for the time elapsed since
+// subprocesses were first signaled. escalationStep returns false if an
+// escalation sequence is not set.
+func (r *Reap) escalationStep(elapsed time.Duration) (syscall.Signal, bool) {
+	if len(r.escalation) == 0 {
+		return 0, false
+	}
+
+	var total time.Duration
+
+	for _, s := range r.escalation[:len(r.escalation)-1] {
+		total += s.Timeout
+		if elapsed < total {
+			return s.Signal, true
+		}
+	}
+
+	return r.escalation[len(r.escalation)-1].Signal, true
+}
+
+// escalates returns true if the signal is a step in the escalation
+// sequence.
+func (r *Reap) escalates(sig syscall.Signal) bool {
+	for _, s := range r.escalation {
+		if s.Signal == sig {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package reap
+
+import "syscall"
+
+// WithExitStatusFromChild returns the exit status of the foreground
+// process unchanged (the default), disabling the exit status options
+// set by WithExitStatusOnSignal and WithSuccessStatuses.
+func WithExitStatusFromChild() Option {
+	return func(r *Reap) {
+		r.signalStatus = -1
+		r.successStatus = nil
+	}
+}
+
+// WithExitStatusOnSignal sets the exit status returned by Supervise if
+// the foreground process was terminated by a signal. A process
+// terminated by a signal exits with 128 plus the signal number, e.g.,
+// 143 for SIGTERM: a shell exiting with the status of a signaled
+// process is treated as terminated by the signal. A negative value (the
+// default) returns the foreground process exit status.
+func WithExitStatusOnSignal(status int) Option {
+	return func(r *Reap) {
+		r.signalStatus = status
+	}
+}
+
+// WithSuccessStatuses sets exit statuses of the foreground process
+// returned as 0 by Supervise, e.g., 143 for a process expected to be
+// terminated by SIGTERM.
+func WithSuccessStatuses(statuses []int) Option {
+	return func(r *Reap) {
+		r.successStatus = make(map[int]struct{}, len(statuses))
+		for _, status := range statuses {
+			r.successStatus[status] = struct{}{}
+		}
+	}
+}
+
+// remapStatus returns the exit status reported for the foreground
+// process.
+func (r *Reap) remapStatus(status int) int {
+	if _, ok := r.successStatus[status]; ok {
+		r.notify("%d: exit status %d: replaced with 0", r.Pid(), status)
+		return 0
+	}
+
+	if r.signalStatus >= 0 && status > 128 && validSignal(syscall.Signal(status-128)) {
+		r.notify("%d: exit status %d: signaled: replaced with %d", r.Pid(), status, r.signalStatus)
+		return r.signalStatus
+	}
+
+	return status
+}
@@ -0,0 +1,82 @@
+//go:build !windows
+
+package reap
+
+import (
+	"os"
+	"syscall"
+)
+
+// SetWait4 replaces the function used to wait for subprocesses. The
+// returned function restores the original.
+func SetWait4(f func(int, *syscall.WaitStatus, int, *syscall.Rusage) (int, error)) func() {
+	orig := wait4
+	wait4 = f
+	return func() {
+		wait4 = orig
+	}
+}
+
+// SetSubReaper replaces the function used to check subreaper status. The
+// returned function restores the original.
+func SetSubReaper(f func() bool) func() {
+	orig := subreaperGet
+	subreaperGet = f
+	return func() {
+		subreaperGet = orig
+	}
+}
+
+// NeedsReexec indicates whether Supervise will re-execute the current
+// process.
+func (r *Reap) NeedsReexec() bool {
+	return r.needsReexec()
+}
+
+// SetSyslogAddr sets the address of the syslog service. The returned
+// function restores the original.
+func SetSyslogAddr(network, raddr string) func() {
+	origNetwork, origRaddr := syslogNetwork, syslogRaddr
+	syslogNetwork, syslogRaddr = network, raddr
+	return func() {
+		syslogNetwork, syslogRaddr = origNetwork, origRaddr
+	}
+}
+
+// SetKill replaces the function used to signal processes and disables
+// signaling using pidfds. The returned function restores the original.
+func SetKill(f func(int, syscall.Signal) error) func() {
+	orig, origPidfd := kill, nopidfd.Load()
+	kill = f
+	nopidfd.Store(true)
+	return func() {
+		kill = orig
+		nopidfd.Store(origPidfd)
+	}
+}
+
+// WaitCgroup blocks until the cgroup is empty.
+func WaitCgroup(path string) error {
+	return waitCgroup(path, nil)
+}
+
+// PidfdKill signals a process using a pidfd.
+func PidfdKill(pid int, sig syscall.Signal) error {
+	return pidfdKill(pid, sig)
+}
+
+// SignalWith signals all descendants, returning the number of processes
+// signaled.
+func (r *Reap) SignalWith(sig syscall.Signal) int {
+	return r.signalWith(sig)
+}
+
+// OpenPty opens a pseudo-terminal.
+func OpenPty() (*os.File, *os.File, error) {
+	return openPty()
+}
+
+// WaitExit waits for a process which is not a child to exit.
+func WaitExit(pid int) <-chan error {
+	return waitExit(pid)
+}
@@ -0,0 +1,266 @@
+package reap
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// ErrNoCommand is returned if no commands are supervised.
+var ErrNoCommand = errors.New("no command")
+
+// ExitPolicy sets the action taken when a command supervised by
+// SuperviseAll exits.
+type ExitPolicy int
+
+const (
+	// ExitStopAll terminates the remaining commands when a command
+	// exits (the default).
+	ExitStopAll ExitPolicy = iota
+
+	// ExitContinue continues running the remaining commands when a
+	// command exits. Supervision stops when all commands have exited.
+	ExitContinue
+
+	// ExitRestart restarts a command when it exits. The delay before
+	// restarting and the maximum number of restarts are set by the
+	// restart policy (see WithRestart). When the maximum number of
+	// restarts is reached, the remaining commands are terminated.
+	ExitRestart
+)
+
+// WithExitPolicy sets the action taken when a command supervised by
+// SuperviseAll exits.
+func WithExitPolicy(p ExitPolicy) Option {
+	return func(r *Reap) {
+		r.exitPolicy = p
+	}
+}
+
+// exited is the exit status of a command in a group.
+type exited struct {
+	n      int
+	pid    int
+	status int
+	err    error
+}
+
+// SuperviseAll runs a group of commands concurrently, terminating all
+// subprocesses when supervision stops. The action taken when a command
+// exits is set by the exit policy (see WithExitPolicy).
+//
+// The exit status is the status of the first command which exited and
+// was not restarted. Standard input is connected to the first command:
+// other commands read from /dev/null. WithPidFd writes the pid of the
+// first command: the start handler (see WithStartHandler) is called for
+// each command. Commands are not run in a pseudo-terminal (see WithPTY),
+// restored from a state file or re-executed in a PID namespace.
+func (r *Reap) SuperviseAll(argvs [][]string, env []string) (int, error) {
+	return r.supervise(nil, func(r *supervision) (int, error) {
+		return r.execAll(argvs, env)
+	})
+}
+
+// execAll starts and waits for a group of commands.
+func (r *supervision) execAll(argvs [][]string, env []string) (int, error) {
+	if len(argvs) == 0 {
+		return 127, ErrNoCommand
+	}
+
+	for _, argv := range argvs {
+		if len(argv) == 0 {
+			return 127, ErrNoCommand
+		}
+	}
+
+	restore, err := r.prepare()
+	if err != nil {
+		return 111, err
+	}
+	defer restore()
+
+	exitch := make(chan exited, len(argvs))
+	restartch := make(chan int, len(argvs))
+
+	// running commands: command index -> pid
+	running := make(map[int]int, len(argvs))
+	restarts := make([]int, len(argvs))
+	pending := 0
+
+	status, err := -1, error(nil)
+	last := exited{status: -1}
+
+	start := func(n int) error {
+		argv := argvs[n]
+
+		cmd, closer, err := r.command(argv[0], argv[1:], env, n == 0)
+		if err != nil {
+			r.onStartErr(err)
+			return err
+		}
+		defer closer()
+
+		if err := r.start(cmd); err != nil {
+			r.onStartErr(err)
+			return err
+		}
+
+		pid := cmd.Process.Pid
+
+		r.cgroupEnter(pid)
+		if n == 0 && restarts[n] == 0 {
+			r.writePid(pid)
+			r.stats.update(func(s *Stats) { s.Pid = pid })
+		}
+		r.startHandler(pid)
+
+		r.notify("%d: command %d: started %d", r.Pid(), n, pid)
+
+		running[n] = pid
+
+		go func() {
+			status, err := waitStatus(cmd.Wait())
+			exitch <- exited{n: n, pid: pid, status: status, err: err}
+		}()
+
+		return nil
+	}
+
+	var stop func()
+
+	shutdown := func(format string, a ...interface{}) {
+		if stop != nil {
+			return
+		}
+		r.notify(format, a...)
+		stop = r.startReaper(false)
+	}
+
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
+
+	for n := range argvs {
+		if e := start(n); e != nil {
+			status, err = 127, e
+			shutdown("%d: command %d: start failed: shutdown", r.Pid(), n)
+			break
+		}
+	}
+
+	var track <-chan time.Time
+	if r.tracking() {
+		t := time.NewTicker(trackInterval)
+		defer t.Stop()
+		track = t.C
+	}
+
+	shutdowns := 0
+	done := r.done
+
+	for len(running) > 0 || pending > 0 {
+		select {
+		case <-track:
+			r.track()
+		case <-done:
+			done = nil
+			r.stopping = true
+			shutdown("%d: supervision canceled: shutdown", r.Pid())
+		case sig := <-r.sigch:
+			switch sig {
+			case sigchld:
+				if r.trigger == TriggerChildExit {
+					shutdown("%d: subprocess exited: shutdown", r.Pid())
+				}
+			case sigio, syscall.SIGPIPE, sigurg:
+			case r.forceSig:
+				r.stopping = true
+				shutdown("%d: forced shutdown: %s", r.Pid(), sig)
+			case sigwinch:
+				r.forwardAll(running, sigwinch)
+			default:
+				if shutdownSignal(sig) {
+					r.stopping = true
+				}
+				if r.doubleSignal && shutdownSignal(sig) {
+					shutdowns++
+					if shutdowns > 1 {
+						r.kill9(sig)
+						continue
+					}
+				}
+				r.forwardAll(running, r.translate(sig))
+			}
+		case e := <-exitch:
+			delete(running, e.n)
+			last = e
+
+			r.notify("%d: command %d: exited %d: status %d", r.Pid(), e.n, e.pid, e.status)
+
+			if r.exitPolicy == ExitRestart && e.err == nil && !r.stopping && stop == nil &&
+				(r.restartPolicy.MaxRetries <= 0 || restarts[e.n] < r.restartPolicy.MaxRetries) {
+				d := r.restartBackoff(restarts[e.n])
+				restarts[e.n]++
+				r.stats.update(func(s *Stats) { s.Restarts++ })
+				r.notify("%d: command %d: restarting (%d): backoff %s", r.Pid(), e.n, restarts[e.n], d)
+				pending++
+				n := e.n
+				time.AfterFunc(d, func() { restartch <- n })
+				continue
+			}
+
+			if status < 0 {
+				status, err = e.status, e.err
+			}
+
+			if r.exitPolicy != ExitContinue {
+				shutdown("%d: command %d exited: shutdown", r.Pid(), e.n)
+			}
+		case n := <-restartch:
+			pending--
+			if r.stopping || stop != nil {
+				continue
+			}
+			if e := start(n); e != nil {
+				if status < 0 {
+					status, err = 127, e
+				}
+				shutdown("%d: command %d: restart failed: shutdown", r.Pid(), n)
+			}
+		}
+	}
+
+	if status < 0 {
+		// the last command exited while waiting to be restarted
+		status, err = last.status, last.err
+	}
+
+	return status, err
+}
+
+// forwardAll sends a signal received while a group of commands is
+// running.
+func (r *Reap) forwardAll(running map[int]int, sig syscall.Signal) {
+	if r.forwardTo == ForwardDescendants {
+		r.signalWith(sig)
+		return
+	}
+
+	for _, pid := range running {
+		r.forward(pid, sig)
+	}
+}
+
+// restartBackoff returns the delay before the nth restart.
+func (r *Reap) restartBackoff(n int) time.Duration {
+	d := r.restartPolicy.Backoff
+	for i := 0; i < n; i++ {
+		d *= 2
+		if r.restartPolicy.MaxBackoff > 0 && d > r.restartPolicy.MaxBackoff {
+			return r.restartPolicy.MaxBackoff
+		}
+	}
+	return d
+}
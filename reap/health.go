@@ -0,0 +1,156 @@
+package reap
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// Action is the action taken when the health check of the foreground
+// process fails.
+type Action int
+
+const (
+	// ActionSignal sends the termination signal (see WithSignal) to
+	// the foreground process. The foreground process is restarted if
+	// required by the restart policy (see WithRestart).
+	ActionSignal Action = iota
+
+	// ActionRestart sends the termination signal to the foreground
+	// process and restarts the process regardless of the restart mode.
+	// The maximum number of restarts and the backoff are set by the
+	// restart policy.
+	ActionRestart
+
+	// ActionExit stops supervision: the foreground process and
+	// subprocesses are signaled and reaped.
+	ActionExit
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionSignal:
+		return "signal"
+	case ActionRestart:
+		return "restart"
+	case ActionExit:
+		return "exit"
+	default:
+		return fmt.Sprintf("action(%d)", int(a))
+	}
+}
+
+// healthCheck is the command checking the health of the foreground
+// process.
+type healthCheck struct {
+	argv     []string
+	interval time.Duration
+	retries  int
+	onFail   Action
+}
+
+// WithHealthCheck runs a command at each interval while the foreground
+// process is running. The health check fails if the command exits with a
+// non-zero status or runs longer than the interval. When the number of
+// consecutive failures reaches the retry limit (see WithHealthRetries),
+// the onFail action is taken.
+//
+// The command is a child of the supervisor: with TriggerChildExit, the
+// health check command exiting terminates subprocesses. Health checks
+// are not run for commands supervised by SuperviseAll.
+func WithHealthCheck(cmd []string, interval time.Duration, onFail Action) Option {
+	return func(r *Reap) {
+		r.health.argv = cmd
+		r.health.interval = interval
+		r.health.onFail = onFail
+	}
+}
+
+// WithHealthRetries sets the number of consecutive health check failures
+// before the failure action is taken (default: 3).
+func WithHealthRetries(n int) Option {
+	return func(r *Reap) {
+		r.health.retries = n
+	}
+}
+
+// startHealthCheck runs the health check command at each interval and
+// sends the result to the returned channel. The returned function stops
+// the health checks.
+func (r *Reap) startHealthCheck() (<-chan error, func()) {
+	if len(r.health.argv) == 0 || r.health.interval <= 0 {
+		return nil, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	healthch := make(chan error)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		t := time.NewTicker(r.health.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			err := r.runHealthCheck(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case healthch <- err:
+			}
+		}
+	}()
+
+	return healthch, func() {
+		cancel()
+		<-done
+	}
+}
+
+// runHealthCheck runs the health check command once.
+func (r *Reap) runHealthCheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.health.interval)
+	defer cancel()
+
+	argv := r.health.argv
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.SysProcAttr = sysProcAttr()
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timeout: %s", r.health.interval)
+	}
+
+	return err
+}
+
+// unhealthy handles a failed health check of the foreground process.
+// unhealthy returns true if supervision is stopped.
+func (r *supervision) unhealthy(fg int) bool {
+	r.notify("%d: health check: foreground %d: %s", r.Pid(), fg, r.health.onFail)
+
+	switch r.health.onFail {
+	case ActionExit:
+		r.stopping = true
+		return true
+	case ActionRestart:
+		r.healthRestart = true
+	}
+
+	r.event("signal sent", []Field{{"pid", fg}, {"signal", sysSignalName(r.sig)}},
+		"%d: kill %d %d", r.Pid(), r.sig, fg)
+	if err := r.kill(fg, -1, r.sig); err != nil {
+		r.log(fmt.Errorf("%d: health check: %w", r.Pid(), err))
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package reap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Hook is a lifecycle event of the supervised processes.
+type Hook int
+
+const (
+	// HookPreStart runs before the foreground process is started,
+	// including when the process is restarted (see WithRestart).
+	HookPreStart Hook = iota
+
+	// HookPostStop runs after the foreground process exits.
+	// Subprocesses may be running: subprocesses are signaled after the
+	// hook returns.
+	HookPostStop
+
+	// HookChildExit runs when a subprocess is reaped. The hook is not
+	// run for the foreground process (see HookPostStop).
+	HookChildExit
+)
+
+func (h Hook) String() string {
+	switch h {
+	case HookPreStart:
+		return "pre-start"
+	case HookPostStop:
+		return "post-stop"
+	case HookChildExit:
+		return "child-exit"
+	default:
+		return fmt.Sprintf("hook(%d)", int(h))
+	}
+}
+
+// Event describes a lifecycle event passed to a hook.
+type Event struct {
+	Hook Hook
+	// Pid is the pid of the exited process (0 for HookPreStart).
+	Pid int
+	// Status is the wait status of the exited process.
+	Status syscall.WaitStatus
+}
+
+// WithHook sets a function called for a lifecycle event. Hooks are
+// called synchronously: supervision continues when the function returns.
+// A nil function removes the hook.
+//
+// When this process is re-executed (see ReexecEnv), hooks are called by
+// the re-executed process: process IDs are in the new PID namespace.
+func WithHook(hook Hook, f func(Event)) Option {
+	return func(r *Reap) {
+		if f == nil {
+			delete(r.hooks, hook)
+			return
+		}
+		if r.hooks == nil {
+			r.hooks = make(map[Hook]func(Event))
+		}
+		r.hooks[hook] = f
+	}
+}
+
+// hook calls the function set for the lifecycle event.
+func (r *Reap) hook(ev Event) {
+	if f, ok := r.hooks[ev.Hook]; ok {
+		f(ev)
+	}
+}
@@ -0,0 +1,158 @@
+package reap
+
+import (
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/msantos/goreap/process"
+)
+
+// Ladder is a sequence of signals sent to subprocesses with a name
+// matching a pattern, e.g., nginx. A process name is either the command
+// name (comm) or the base name of the first command line argument (see
+// also WithMatchExe):
+//
+//	Ladder{Pattern: "nginx", Signals: []syscall.Signal{syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGKILL}}
+//
+// The deadline is divided between the signals: each signal is sent at
+// intervals (see WithDelay) until the next step is reached. The last
+// signal is sent when the deadline is reached.
+type Ladder struct {
+	// Pattern is a shell pattern matching the command name (see
+	// path.Match).
+	Pattern string
+	// Signals is the sequence of signals.
+	Signals []syscall.Signal
+}
+
+type cmdliner interface {
+	Cmdline(pid int) ([]string, error)
+}
+
+type exer interface {
+	Exe(pid int) (string, error)
+}
+
+// WithMatchExe matches the name of the executable of a process when
+// matching command names (see Ladder). A process can change the command
+// name (comm) and the command line arguments but not the executable.
+func WithMatchExe(b bool) Option {
+	return func(r *Reap) {
+		r.matchExe = b
+	}
+}
+
+// WithLadders sets the sequence of signals sent to subprocesses with
+// matching command names. The first matching ladder is used.
+// Subprocesses without a matching ladder are sent the signal (see
+// WithSignal) and SIGKILL after the deadline.
+func WithLadders(ladders ...Ladder) Option {
+	return func(r *Reap) {
+		r.ladders = make([]Ladder, 0, len(ladders))
+		for _, l := range ladders {
+			if len(l.Signals) == 0 {
+				continue
+			}
+			r.ladders = append(r.ladders, l)
+		}
+	}
+}
+
+// names returns the names of a process: the command name (comm), the
+// base name of the first command line argument and, if enabled, the
+// base name of the executable.
+//
+// A process can change the command name (prctl(PR_SET_NAME)) to evade
+// matching.
+func (r *Reap) names(p process.PID) []string {
+	names := []string{p.Comm}
+
+	if c, ok := r.Process.(cmdliner); ok {
+		if argv, err := c.Cmdline(p.Pid); err == nil && len(argv) > 0 {
+			names = append(names, path.Base(argv[0]))
+		}
+	}
+
+	if !r.matchExe {
+		return names
+	}
+
+	if e, ok := r.Process.(exer); ok {
+		if exe, err := e.Exe(p.Pid); err == nil {
+			names = append(names, path.Base(exe))
+		}
+	}
+
+	return names
+}
+
+// ladder returns the ladder matching the names of a process.
+func (r *Reap) ladder(p process.PID) (Ladder, bool) {
+	names := r.names(p)
+	for _, l := range r.ladders {
+		for _, name := range names {
+			if ok, _ := path.Match(l.Pattern, name); ok {
+				return l, true
+			}
+		}
+	}
+	return Ladder{}, false
+}
+
+// step returns the signal for the elapsed time since subprocesses were
+// first signaled.
+func (r *Reap) step(l Ladder, elapsed time.Duration) syscall.Signal {
+	n := len(l.Signals)
+	if n == 1 {
+		return l.Signals[0]
+	}
+
+	interval := r.deadline / time.Duration(n-1)
+	if interval <= 0 {
+		return l.Signals[n-1]
+	}
+
+	i := int(elapsed / interval)
+	if i >= n {
+		i = n - 1
+	}
+
+	return l.Signals[i]
+}
+
+// signalLadder signals subprocesses matching a ladder with the signal
+// for the current step. Other subprocesses are sent sig. SIGKILL is not
+// sent before the minimum grace period has passed.
+func (r *Reap) signalLadder(sig syscall.Signal, elapsed, grace time.Duration) int {
+	if len(r.ladders) == 0 || sig == 0 || sig == syscall.SIGKILL {
+		return r.signalWith(sig)
+	}
+
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		r.log(err)
+		return -1
+	}
+
+	table := make(map[int]process.PID, len(snapshot))
+	for _, p := range snapshot {
+		table[p.Pid] = p
+	}
+
+	return r.signalEach(func(pid int) syscall.Signal {
+		p, ok := table[pid]
+		if !ok {
+			return sig
+		}
+		l, ok := r.ladder(p)
+		if !ok {
+			return sig
+		}
+		s := r.step(l, elapsed)
+		if s == syscall.SIGKILL && grace < r.minGrace {
+			return sig
+		}
+		return s
+	})
+}
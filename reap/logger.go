@@ -0,0 +1,120 @@
+package reap
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// Level is the severity of a log event.
+type Level int
+
+const (
+	// LevelInfo is an informational event such as a signal sent or a
+	// process reaped.
+	LevelInfo Level = iota
+	// LevelWarn is an error which does not stop supervision.
+	LevelWarn
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// Field is a key/value pair describing a log event.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// LogEvent is a structured log event.
+type LogEvent struct {
+	Level Level
+	// Msg is the name of the event, e.g., "signal sent", "child reaped"
+	// or "deadline reached". Events without a name use the log message.
+	Msg string
+	// Fields describe the event: for example, the pid, the signal and
+	// the exit status.
+	Fields []Field
+	// Err is the error passed to the log function (see WithLog).
+	Err error
+}
+
+// Logger receives structured log events.
+type Logger interface {
+	Log(LogEvent)
+}
+
+// LoggerFunc is a function implementing Logger.
+type LoggerFunc func(LogEvent)
+
+// Log calls f(ev).
+func (f LoggerFunc) Log(ev LogEvent) {
+	f(ev)
+}
+
+// WithLogger sets a structured logger. Events are sent to both the
+// logger and the log function (see WithLog).
+func WithLogger(l Logger) Option {
+	return func(r *Reap) {
+		r.structLog = l
+	}
+}
+
+// event is a named log event.
+type event struct {
+	error
+	msg    string
+	fields []Field
+}
+
+func (e event) Unwrap() error {
+	return e.error
+}
+
+// event logs an informational message with the event name and fields
+// passed to the structured logger.
+func (r *Reap) event(msg string, fields []Field, format string, a ...interface{}) {
+	r.log(event{error: notice{fmt.Errorf(format, a...)}, msg: msg, fields: fields})
+}
+
+// logEvent converts an error passed to the log function to a structured
+// log event.
+func logEvent(err error) LogEvent {
+	ev := LogEvent{Level: LevelWarn, Msg: err.Error(), Err: err}
+
+	var n notice
+	if errors.As(err, &n) {
+		ev.Level = LevelInfo
+	}
+
+	var e event
+	if errors.As(err, &e) {
+		ev.Msg = e.msg
+		ev.Fields = e.fields
+	}
+
+	return ev
+}
+
+// statusFields returns the fields describing the wait status of a
+// process: the exit status or the signal terminating the process.
+func statusFields(pid int, ws syscall.WaitStatus) []Field {
+	fields := []Field{{"pid", pid}}
+
+	switch {
+	case ws.Exited():
+		fields = append(fields, Field{"status", ws.ExitStatus()})
+	case ws.Signaled():
+		fields = append(fields, Field{"signal", sysSignalName(ws.Signal())})
+	}
+
+	return fields
+}
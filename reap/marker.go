@@ -0,0 +1,93 @@
+package reap
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// EnvMarker is the environment variable identifying processes started by
+// the foreground process.
+//
+// Processes inherit the environment of their parent process. Processes
+// with the marker in their environment are signaled even if they are no
+// longer descendants of this process, for example, if the process was
+// reparented to another subreaper.
+//
+// Matching processes requires reading the environment
+// (/proc/<pid>/environ) of every process in the process table during each
+// signal pass. Processes can remove the marker by changing their
+// environment. The environment of processes owned by other users may not
+// be readable.
+const EnvMarker = "GOREAP_JOB"
+
+type environer interface {
+	Environ(pid int) ([]string, error)
+}
+
+// WithEnvMarker sets a unique marker in the environment of the
+// foreground process. See EnvMarker.
+func WithEnvMarker(b bool) Option {
+	return func(r *Reap) {
+		if !b {
+			r.marker = ""
+			return
+		}
+		id, err := uuid()
+		if err != nil {
+			r.log(fmt.Errorf("%s: %w", EnvMarker, err))
+			return
+		}
+		r.marker = id
+	}
+}
+
+// Marker returns the value of the environment marker or an empty string
+// if the environment marker is disabled.
+func (r *Reap) Marker() string {
+	return r.marker
+}
+
+// marked returns running processes with the environment marker.
+func (r *Reap) marked() []int {
+	e, ok := r.Process.(environer)
+	if !ok {
+		return nil
+	}
+
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		r.log(fmt.Errorf("%d: %w", r.Pid(), err))
+		return nil
+	}
+
+	want := EnvMarker + "=" + r.marker
+	pids := make([]int, 0)
+
+	for _, p := range snapshot {
+		if p.State == 'Z' || p.Pid == r.Pid() {
+			continue
+		}
+		env, err := e.Environ(p.Pid)
+		if err != nil {
+			continue
+		}
+		for _, kv := range env {
+			if kv == want {
+				pids = append(pids, p.Pid)
+				break
+			}
+		}
+	}
+
+	return pids
+}
+
+func uuid() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,98 @@
+//go:build ignore
+
+// mkseccomp generates the tables of system call names used by seccomp
+// filters from the system call numbers defined by golang.org/x/sys/unix:
+//
+//	go generate ./reap
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type arch struct {
+	goarch string
+	audit  string // AUDIT_ARCH_* of the architecture
+	limit  string // lowest system call number rejected by the filter
+	doc    string // suffix of the limit doc comment
+}
+
+var arches = []arch{
+	{"amd64", "AUDIT_ARCH_X86_64", "0x40000000", ": x32 system calls have bit 30 set"},
+	{"arm64", "AUDIT_ARCH_AARCH64", "0", " (0 to disable)"},
+}
+
+var sysnum = regexp.MustCompile(`^\s+(SYS_[A-Z0-9_]+)\s+= \d+`)
+
+func main() {
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "golang.org/x/sys").Output()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir := filepath.Join(strings.TrimSpace(string(out)), "unix")
+
+	for _, a := range arches {
+		if err := generate(dir, a); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func generate(dir string, a arch) error {
+	f, err := os.Open(filepath.Join(dir, "zsysnum_linux_"+a.goarch+".go"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	names := make([]string, 0)
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if m := sysnum.FindStringSubmatch(s.Text()); m != nil {
+			names = append(names, m[1])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "// Code generated by mkseccomp.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package reap\n\n")
+	fmt.Fprintf(&b, "import \"golang.org/x/sys/unix\"\n\n")
+	fmt.Fprintf(&b, "// seccompAuditArch is the architecture of system calls checked by the\n")
+	fmt.Fprintf(&b, "// seccomp filter.\n")
+	fmt.Fprintf(&b, "const seccompAuditArch = unix.%s\n\n", a.audit)
+	fmt.Fprintf(&b, "// seccompSyscallLimit is the lowest system call number rejected by the\n")
+	fmt.Fprintf(&b, "// seccomp filter%s.\n", a.doc)
+	fmt.Fprintf(&b, "const seccompSyscallLimit = %s\n\n", a.limit)
+	fmt.Fprintf(&b, "// seccompSyscalls maps system call names to numbers.\n")
+	fmt.Fprintf(&b, "var seccompSyscalls = map[string]uint32{\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\t%q: unix.%s,\n", strings.ToLower(strings.TrimPrefix(name, "SYS_")), name)
+	}
+	fmt.Fprintf(&b, "}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile("zseccomp_linux_"+a.goarch+".go", src, 0o644)
+}
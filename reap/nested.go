@@ -0,0 +1,221 @@
+package reap
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/msantos/goreap/process"
+)
+
+// Orphaned subprocesses are reparented to the nearest subreaper
+// ancestor. If the process is not a subreaper (for example, if setting
+// the subreaper failed while running under another subreaper or init
+// process), orphans cannot be waited for and are not found by walking
+// the process tree.
+//
+// While the foreground process is running, descendants are periodically
+// recorded. When reaping, recorded descendants are signaled by pid until
+// they exit. A recorded pid may be reused by an unrelated process if the
+// descendant exits before it is signaled.
+
+const trackInterval = 100 * time.Millisecond
+
+type orphans struct {
+	mu   sync.Mutex
+	pids map[int]struct{}
+}
+
+// nested indicates orphaned subprocesses will be reparented to another
+// process.
+func (r *Reap) nested() bool {
+	return !subreaperGet()
+}
+
+// tracking returns true if descendants are periodically checked while
+// the foreground process is running.
+func (r *Reap) tracking() bool {
+	return r.descEvents || r.nested() || r.adopted != 0
+}
+
+// recording returns true if descendants are signaled by pid: orphans are
+// not reparented to this process.
+func (r *Reap) recording() bool {
+	return r.nested() || r.adopted != 0
+}
+
+// track records the current descendants of the process and reports
+// changes in the number of descendants.
+func (r *Reap) track() {
+	snapshot, err := r.tracker.Snapshot()
+	if err != nil {
+		r.log(fmt.Errorf("%d: %w", r.Pid(), err))
+		return
+	}
+
+	pids := process.Descendants(snapshot, r.Pid())
+
+	if r.descEvents {
+		r.countDescendants(snapshot, pids)
+	}
+
+	if !r.recording() {
+		return
+	}
+
+	if r.adopted != 0 {
+		pids = append(pids, process.Descendants(snapshot, r.adopted)...)
+	}
+
+	r.orphans.mu.Lock()
+	defer r.orphans.mu.Unlock()
+
+	if r.orphans.pids == nil {
+		r.orphans.pids = make(map[int]struct{})
+	}
+
+	for _, pid := range pids {
+		r.orphans.pids[pid] = struct{}{}
+	}
+}
+
+// countDescendants reports a change in the number of running
+// descendants.
+func (r *Reap) countDescendants(snapshot []process.PID, pids []int) {
+	zombies := make(map[int]struct{})
+	for _, p := range snapshot {
+		if p.State == 'Z' {
+			zombies[p.Pid] = struct{}{}
+		}
+	}
+
+	n := 0
+	for _, pid := range pids {
+		if _, ok := zombies[pid]; !ok {
+			n++
+		}
+	}
+
+	if n == r.descendants {
+		return
+	}
+
+	r.notify("%d: descendants: %d -> %d", r.Pid(), r.descendants, n)
+	r.descendants = n
+}
+
+// logRemaining logs the subprocesses which have not exited. Zombie
+// descendants of subprocesses are logged as defunct: zombies are removed
+// when waited for by the parent process or the parent process exits.
+func (r *Reap) logRemaining() {
+	pids, err := r.Targets()
+	if err != nil {
+		r.log(err)
+		return
+	}
+
+	if len(pids) == 0 {
+		return
+	}
+
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		r.log(fmt.Errorf("%d: %w", r.Pid(), err))
+		return
+	}
+
+	table := make(map[int]process.PID, len(snapshot))
+	for _, p := range snapshot {
+		table[p.Pid] = p
+	}
+
+	remaining := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		p, ok := table[pid]
+		if !ok {
+			// exited
+			continue
+		}
+		remaining = append(remaining, fmt.Sprintf("%d (%s)", pid, p.Comm))
+	}
+
+	for _, pid := range process.Descendants(snapshot, r.Pid()) {
+		p := table[pid]
+		if p.State != 'Z' || p.PPid == r.Pid() {
+			continue
+		}
+		remaining = append(remaining, fmt.Sprintf("%d (%s, defunct: parent %d)", pid, p.Comm, p.PPid))
+	}
+
+	if len(remaining) == 0 {
+		return
+	}
+
+	r.notify("%d: remaining: %s", r.Pid(), strings.Join(remaining, ", "))
+}
+
+// running removes exited processes from the recorded descendants and
+// returns the pids of running processes.
+func (r *Reap) running() []int {
+	snapshot, err := r.tracker.Snapshot()
+	if err != nil {
+		r.log(fmt.Errorf("%d: %w", r.Pid(), err))
+		return nil
+	}
+
+	active := make(map[int]struct{}, len(snapshot))
+	for _, p := range snapshot {
+		if p.State == 'Z' {
+			continue
+		}
+		active[p.Pid] = struct{}{}
+	}
+
+	r.orphans.mu.Lock()
+	defer r.orphans.mu.Unlock()
+
+	pids := make([]int, 0, len(r.orphans.pids))
+	for pid := range r.orphans.pids {
+		if _, ok := active[pid]; !ok {
+			delete(r.orphans.pids, pid)
+			continue
+		}
+		pids = append(pids, pid)
+	}
+
+	return pids
+}
+
+// unreachable returns running processes which cannot be found by
+// walking the process tree: orphans reparented to another process and
+// processes matching the environment marker.
+func (r *Reap) unreachable() []int {
+	var pids []int
+	if r.recording() {
+		pids = r.running()
+	}
+	if r.marker != "" {
+		pids = union(pids, r.marked())
+	}
+	return pids
+}
+
+// union returns the pids in both lists with duplicates removed. A pid
+// may be listed more than once, e.g., in multiple task children files.
+func union(pids []int, extra []int) []int {
+	seen := make(map[int]struct{}, len(pids)+len(extra))
+	u := make([]int, 0, len(pids)+len(extra))
+
+	for _, list := range [][]int{pids, extra} {
+		for _, pid := range list {
+			if _, ok := seen[pid]; ok {
+				continue
+			}
+			seen[pid] = struct{}{}
+			u = append(u, pid)
+		}
+	}
+
+	return u
+}
@@ -0,0 +1,65 @@
+package reap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Namespace is a set of Linux namespaces created for the foreground
+// process.
+type Namespace int
+
+const (
+	// NamespaceMount creates a mount namespace: mounts are private to
+	// the foreground process and subprocesses.
+	NamespaceMount Namespace = 1 << iota
+
+	// NamespaceUTS creates a UTS namespace: the hostname is private to
+	// the foreground process and subprocesses (see WithHostname).
+	NamespaceUTS
+
+	// NamespaceIPC creates an IPC namespace for System V IPC objects and
+	// POSIX message queues.
+	NamespaceIPC
+)
+
+func (ns Namespace) String() string {
+	names := make([]string, 0, 3)
+	for _, v := range []struct {
+		ns   Namespace
+		name string
+	}{
+		{NamespaceMount, "mount"},
+		{NamespaceUTS, "uts"},
+		{NamespaceIPC, "ipc"},
+	} {
+		if ns&v.ns != 0 {
+			names = append(names, v.name)
+			ns &^= v.ns
+		}
+	}
+	if ns != 0 {
+		names = append(names, fmt.Sprintf("namespace(%#x)", int(ns)))
+	}
+	return strings.Join(names, ",")
+}
+
+// WithNamespaces runs the foreground process in new namespaces, e.g.,
+// NamespaceMount|NamespaceIPC. Creating namespaces requires privileges
+// (CAP_SYS_ADMIN) unless the process is re-executed in a PID namespace
+// (see WithNewPidNamespace). Namespaces are supported on Linux.
+func WithNamespaces(ns Namespace) Option {
+	return func(r *Reap) {
+		r.namespaces = ns
+	}
+}
+
+// WithHostname sets the hostname of the foreground process. The current
+// process is re-executed as the init process of new PID and UTS
+// namespaces (see WithNewPidNamespace) and sets the hostname before
+// running the foreground process.
+func WithHostname(name string) Option {
+	return func(r *Reap) {
+		r.hostname = name
+	}
+}
@@ -0,0 +1,46 @@
+package reap
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// cloneflags returns the clone flags creating the namespaces.
+func cloneflags(ns Namespace) uintptr {
+	var flags uintptr
+	if ns&NamespaceMount != 0 {
+		flags |= syscall.CLONE_NEWNS
+	}
+	if ns&NamespaceUTS != 0 {
+		flags |= syscall.CLONE_NEWUTS
+	}
+	if ns&NamespaceIPC != 0 {
+		flags |= syscall.CLONE_NEWIPC
+	}
+	return flags
+}
+
+// namespaceAttr sets the namespaces of the foreground process. The
+// namespaces are unshared after the process is forked: mounts in a new
+// mount namespace are not propagated to the parent namespace. A
+// re-executed process is running in the namespaces (see reexecv).
+func (r *Reap) namespaceAttr(attr *syscall.SysProcAttr) error {
+	if os.Getenv(ReexecEnv) != "" {
+		return nil
+	}
+	attr.Unshareflags |= cloneflags(r.namespaces)
+	return nil
+}
+
+// sethostname sets the hostname in the UTS namespace of a re-executed
+// process.
+func (r *Reap) sethostname() error {
+	if r.hostname == "" {
+		return nil
+	}
+	if err := syscall.Sethostname([]byte(r.hostname)); err != nil {
+		return fmt.Errorf("hostname: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package reap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// namespaceAttr is not supported on this platform: namespaces are
+// specific to Linux.
+func (r *Reap) namespaceAttr(attr *syscall.SysProcAttr) error {
+	if r.namespaces != 0 {
+		return fmt.Errorf("namespaces: %s: %w", r.namespaces, syscall.ENOSYS)
+	}
+	return nil
+}
+
+func (r *Reap) sethostname() error {
+	if r.hostname != "" {
+		return fmt.Errorf("hostname: %w", syscall.ENOSYS)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package reap
+
+import "fmt"
+
+// oomScoreAdj is the OOM killer score adjustment of this process and the
+// foreground process.
+type oomScoreAdj struct {
+	self  int
+	child int
+}
+
+// WithOOMScoreAdj sets the OOM killer score adjustment (-1000 to 1000) of
+// this process and the foreground process, e.g., -1000 for this process
+// to prevent the supervisor being killed before subprocesses. See
+// oom_score_adj in proc(5).
+//
+// The score adjustment is set when the foreground process is started:
+// the score adjustment of this process is set to the foreground process
+// value while the process is started and then restored. Lowering the
+// score adjustment requires privileges (CAP_SYS_RESOURCE). Setting the
+// score adjustment is supported on Linux.
+func WithOOMScoreAdj(self, child int) Option {
+	return func(r *Reap) {
+		r.oom = &oomScoreAdj{self: self, child: child}
+	}
+}
+
+// setChildOOMScoreAdj sets the score adjustment of this process to the
+// foreground process value. The returned function restores the score
+// adjustment.
+func (r *Reap) setChildOOMScoreAdj() (func(), error) {
+	if r.oom == nil || r.oom.self == r.oom.child {
+		return func() {}, nil
+	}
+
+	if err := setOOMScoreAdj(r.oom.child); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := setOOMScoreAdj(r.oom.self); err != nil {
+			r.log(fmt.Errorf("%d: %w", r.Pid(), err))
+		}
+	}, nil
+}
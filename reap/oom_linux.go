@@ -0,0 +1,15 @@
+package reap
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// setOOMScoreAdj sets the OOM killer score adjustment of this process.
+func setOOMScoreAdj(adj int) error {
+	if err := os.WriteFile("/proc/self/oom_score_adj", []byte(strconv.Itoa(adj)), 0); err != nil {
+		return fmt.Errorf("oom score adj: %w", err)
+	}
+	return nil
+}
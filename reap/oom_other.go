@@ -0,0 +1,13 @@
+//go:build !linux
+
+package reap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setOOMScoreAdj is not supported on this platform.
+func setOOMScoreAdj(adj int) error {
+	return fmt.Errorf("oom score adj: %w", syscall.ENOSYS)
+}
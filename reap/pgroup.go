@@ -0,0 +1,65 @@
+//go:build !windows
+
+package reap
+
+import (
+	"errors"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// pgroups is the set of process groups of started processes.
+type pgroups struct {
+	mu    sync.Mutex
+	pgids map[int]struct{}
+}
+
+// addGroup records the process group of a process started in a new
+// process group.
+func (r *Reap) addGroup(cmd *exec.Cmd) error {
+	if !groupKill {
+		return nil
+	}
+
+	r.pgroups.mu.Lock()
+	defer r.pgroups.mu.Unlock()
+
+	if r.pgroups.pgids == nil {
+		r.pgroups.pgids = make(map[int]struct{})
+	}
+	r.pgroups.pgids[cmd.Process.Pid] = struct{}{}
+
+	return nil
+}
+
+// signalGroups signals the process groups of started processes.
+// Process groups are not signaled if the processes signaled are limited
+// by the exclude options, the reap predicate or the maximum depth:
+// processes in a process group are not filtered.
+func (r *Reap) signalGroups(signalFor func(pid int) syscall.Signal) {
+	if !groupKill || !r.killAll() {
+		return
+	}
+
+	r.pgroups.mu.Lock()
+	defer r.pgroups.mu.Unlock()
+
+	for pgid := range r.pgroups.pgids {
+		sig := signalFor(pgid)
+		if sig == 0 {
+			continue
+		}
+		r.event("signal sent", []Field{{"pgid", pgid}, {"signal", sysSignalName(sig)}},
+			"%d: kill %d -%d", r.Pid(), sig, pgid)
+		if err := syscall.Kill(-pgid, sig); errors.Is(err, syscall.ESRCH) {
+			delete(r.pgroups.pgids, pgid)
+		}
+	}
+}
+
+// waitGroups returns immediately: processes in process groups are
+// waited for by pid (see waitAll).
+func (r *Reap) waitGroups(done <-chan struct{}) error {
+	return nil
+}
@@ -0,0 +1,205 @@
+package reap
+
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// jobObjectBasicAccountingInformation is the information class of
+// jobAccounting.
+const jobObjectBasicAccountingInformation = 1
+
+// jobAccounting is JOBOBJECT_BASIC_ACCOUNTING_INFORMATION.
+type jobAccounting struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// pgroups is the job object containing started processes and their
+// descendants. The job is created with JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE:
+// processes in the job are terminated when the supervisor exits.
+type pgroups struct {
+	mu  sync.Mutex
+	job windows.Handle
+	pid int // last process assigned to the job
+}
+
+// newJob creates a job object terminating processes in the job when the
+// job is closed.
+func newJob() (windows.Handle, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+
+	if _, err := windows.SetInformationJobObject(
+		job,
+		windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)),
+		uint32(unsafe.Sizeof(info)),
+	); err != nil {
+		_ = windows.CloseHandle(job)
+		return 0, err
+	}
+
+	return job, nil
+}
+
+// addGroup assigns a process started suspended to the job object and
+// resumes the process: descendants of the process are created in the
+// job. If the process cannot be assigned to the job, the process is
+// terminated.
+func (r *Reap) addGroup(cmd *exec.Cmd) error {
+	if err := r.assign(cmd.Process.Pid); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("job object: %w", err)
+	}
+	return nil
+}
+
+func (r *Reap) assign(pid int) error {
+	r.pgroups.mu.Lock()
+	defer r.pgroups.mu.Unlock()
+
+	if r.pgroups.job == 0 {
+		job, err := newJob()
+		if err != nil {
+			return err
+		}
+		r.pgroups.job = job
+	}
+
+	h, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	if err := windows.AssignProcessToJobObject(r.pgroups.job, h); err != nil {
+		return err
+	}
+
+	r.pgroups.pid = pid
+
+	return resumeProcess(pid)
+}
+
+// resumeProcess resumes the threads of a suspended process.
+func resumeProcess(pid int) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var e windows.ThreadEntry32
+	e.Size = uint32(unsafe.Sizeof(e))
+
+	for err = windows.Thread32First(snapshot, &e); err == nil; err = windows.Thread32Next(snapshot, &e) {
+		if e.OwnerProcessID != uint32(pid) {
+			continue
+		}
+
+		t, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, e.ThreadID)
+		if err != nil {
+			return err
+		}
+
+		_, err = windows.ResumeThread(t)
+		_ = windows.CloseHandle(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err != windows.ERROR_NO_MORE_FILES {
+		return err
+	}
+
+	return nil
+}
+
+// signalGroups terminates the processes in the job object, including
+// processes which cannot be found by walking the process tree. The job
+// is not terminated if the processes signaled are limited by the
+// exclude options, the reap predicate or the maximum depth.
+func (r *Reap) signalGroups(signalFor func(pid int) syscall.Signal) {
+	if !r.killAll() {
+		return
+	}
+
+	r.pgroups.mu.Lock()
+	defer r.pgroups.mu.Unlock()
+
+	if r.pgroups.job == 0 {
+		return
+	}
+
+	sig := signalFor(r.pgroups.pid)
+	switch sig {
+	case 0, sigchld, sigcont, sigurg, sigwinch:
+		return
+	}
+
+	r.event("signal sent", []Field{{"job", r.pgroups.pid}, {"signal", sysSignalName(sig)}},
+		"%d: kill %d job %d", r.Pid(), sig, r.pgroups.pid)
+	if err := windows.TerminateJobObject(r.pgroups.job, 128+uint32(sig)); err != nil {
+		r.log(fmt.Errorf("%d: job object: %w", r.Pid(), err))
+	}
+}
+
+// waitGroups waits for the processes in the job object to exit.
+func (r *Reap) waitGroups(done <-chan struct{}) error {
+	r.pgroups.mu.Lock()
+	job := r.pgroups.job
+	r.pgroups.mu.Unlock()
+
+	if job == 0 {
+		return nil
+	}
+
+	t := time.NewTicker(trackInterval)
+	defer t.Stop()
+
+	for {
+		var info jobAccounting
+		if err := windows.QueryInformationJobObject(
+			job,
+			jobObjectBasicAccountingInformation,
+			uintptr(unsafe.Pointer(&info)),
+			uint32(unsafe.Sizeof(info)),
+			nil,
+		); err != nil {
+			return fmt.Errorf("job object: %w", err)
+		}
+
+		if info.ActiveProcesses == 0 {
+			return nil
+		}
+
+		select {
+		case <-done:
+			return nil
+		case <-t.C:
+		}
+	}
+}
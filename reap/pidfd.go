@@ -0,0 +1,90 @@
+//go:build !windows
+
+package reap
+
+import (
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// nopidfd is set if the kernel does not support pidfds.
+var nopidfd atomic.Bool
+
+func closePidfds(fds map[int]int) {
+	for _, fd := range fds {
+		if fd >= 0 {
+			_ = unix.Close(fd)
+		}
+	}
+}
+
+// pin opens a pidfd for each process, returning the processes
+// remaining after the pidfds are opened.
+//
+// A pid read from the process table may exit and be reused before the
+// pidfd is opened. After the pidfds are opened, the parent of each
+// process is re-read if supported by the process table: a process
+// whose parent is not this process or another target is not a
+// descendant and is dropped. Processes tracked by pid have been
+// reparented and are not checked.
+func (r *Reap) pin(pids []int, tracked map[int]struct{}) ([]int, map[int]int) {
+	fds := openPidfds(pids)
+	if fds == nil {
+		return pids, nil
+	}
+
+	s, ok := r.Process.(statter)
+
+	parents := make(map[int]struct{}, len(pids)+1)
+	parents[r.Pid()] = struct{}{}
+	for _, pid := range pids {
+		parents[pid] = struct{}{}
+	}
+
+	pinned := make([]int, 0, len(pids))
+
+	for _, pid := range pids {
+		fd, found := fds[pid]
+		if !found {
+			continue
+		}
+		if ok && fd >= 0 && !r.descendant(s, pid, parents, tracked) {
+			_ = unix.Close(fd)
+			delete(fds, pid)
+			continue
+		}
+		pinned = append(pinned, pid)
+	}
+
+	return pinned, fds
+}
+
+// descendant checks a pinned process is the process read from the
+// process table.
+func (r *Reap) descendant(s statter, pid int, parents, tracked map[int]struct{}) bool {
+	if _, ok := tracked[pid]; ok {
+		return true
+	}
+
+	p, err := s.Stat(pid)
+	if err != nil {
+		return false
+	}
+
+	if _, ok := parents[p.PPid]; !ok {
+		return false
+	}
+
+	return r.match(p)
+}
+
+// signalPidfd signals the process referred to by a pidfd or by pid if
+// the pidfd is -1.
+func signalPidfd(pid, fd int, sig syscall.Signal) error {
+	if fd < 0 {
+		return kill(pid, sig)
+	}
+	return pidfdSendSignal(fd, sig)
+}
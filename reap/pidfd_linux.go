@@ -0,0 +1,73 @@
+package reap
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// pidfdKill signals a process using a pidfd. The pidfd refers to the
+// process that held the pid when the pidfd was opened: if the process
+// exits, the signal fails with ESRCH instead of being delivered to an
+// unrelated process reusing the pid.
+//
+// Kernels without pidfd support (before Linux 5.3) fall back to kill(2).
+func pidfdKill(pid int, sig syscall.Signal) error {
+	if nopidfd.Load() {
+		return syscall.Kill(pid, sig)
+	}
+
+	fd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		if errors.Is(err, syscall.ENOSYS) {
+			nopidfd.Store(true)
+			return syscall.Kill(pid, sig)
+		}
+		return err
+	}
+	defer unix.Close(fd)
+
+	err = unix.PidfdSendSignal(fd, sig, nil, 0)
+	if errors.Is(err, syscall.ENOSYS) {
+		nopidfd.Store(true)
+		return syscall.Kill(pid, sig)
+	}
+	return err
+}
+
+// openPidfds opens a pidfd for each process. Processes that have exited
+// are omitted. If a pidfd cannot be opened, e.g., the process limit on
+// open files has been reached, the process is mapped to -1 and signaled
+// by pid.
+//
+// openPidfds returns nil if pidfds are not supported.
+func openPidfds(pids []int) map[int]int {
+	if nopidfd.Load() {
+		return nil
+	}
+
+	fds := make(map[int]int, len(pids))
+
+	for _, pid := range pids {
+		fd, err := unix.PidfdOpen(pid, 0)
+		switch {
+		case err == nil:
+			fds[pid] = fd
+		case errors.Is(err, syscall.ESRCH):
+		case errors.Is(err, syscall.ENOSYS):
+			nopidfd.Store(true)
+			closePidfds(fds)
+			return nil
+		default:
+			fds[pid] = -1
+		}
+	}
+
+	return fds
+}
+
+// pidfdSendSignal signals the process referred to by a pidfd.
+func pidfdSendSignal(fd int, sig syscall.Signal) error {
+	return unix.PidfdSendSignal(fd, sig, nil, 0)
+}
@@ -0,0 +1,25 @@
+//go:build !linux && !windows
+
+package reap
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// pidfdKill signals a process by pid: pidfds are not supported on this
+// platform.
+func pidfdKill(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+// openPidfds returns nil: pidfds are not supported on this platform.
+func openPidfds(pids []int) map[int]int {
+	return nil
+}
+
+// pidfdSendSignal is not supported on this platform.
+func pidfdSendSignal(fd int, sig syscall.Signal) error {
+	return unix.ENOSYS
+}
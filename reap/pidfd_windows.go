@@ -0,0 +1,59 @@
+package reap
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive is the exit code of a running process.
+const stillActive = 259
+
+// pidfdKill terminates a process by pid: pidfds and signals are not
+// supported on Windows. A signal terminates the process with an exit
+// status of 128 plus the signal number. Signals ignored by default on
+// Unix, e.g., SIGCHLD and SIGWINCH, are discarded. Signal 0 checks the
+// process is running.
+func pidfdKill(pid int, sig syscall.Signal) error {
+	switch sig {
+	case sigchld, sigcont, sigurg, sigwinch:
+		return nil
+	}
+
+	h, err := windows.OpenProcess(windows.PROCESS_TERMINATE|windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	switch {
+	case errors.Is(err, windows.ERROR_INVALID_PARAMETER):
+		return syscall.ESRCH
+	case errors.Is(err, windows.ERROR_ACCESS_DENIED):
+		return syscall.EPERM
+	case err != nil:
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	// the handle of an exited process remains valid
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err == nil && code != stillActive {
+		return syscall.ESRCH
+	}
+
+	if sig == 0 {
+		return nil
+	}
+
+	return windows.TerminateProcess(h, 128+uint32(sig))
+}
+
+func closePidfds(fds map[int]int) {}
+
+// pin returns the processes unchanged: pidfds are not supported on
+// Windows.
+func (r *Reap) pin(pids []int, tracked map[int]struct{}) ([]int, map[int]int) {
+	return pids, nil
+}
+
+// signalPidfd signals a process by pid.
+func signalPidfd(pid, fd int, sig syscall.Signal) error {
+	return kill(pid, sig)
+}
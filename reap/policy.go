@@ -0,0 +1,134 @@
+package reap
+
+import (
+	"errors"
+	"time"
+)
+
+// RestartMode sets when the foreground process is restarted.
+type RestartMode int
+
+const (
+	// RestartNever does not restart the foreground process (the
+	// default).
+	RestartNever RestartMode = iota
+
+	// RestartOnFailure restarts the foreground process if it exits
+	// with a non-zero status.
+	RestartOnFailure
+
+	// RestartAlways restarts the foreground process when it exits.
+	RestartAlways
+)
+
+// RestartPolicy configures restarting the foreground process.
+type RestartPolicy struct {
+	// Mode sets when the foreground process is restarted.
+	Mode RestartMode
+	// MaxRetries is the maximum number of restarts (0 for unlimited).
+	MaxRetries int
+	// Backoff is the delay before the first restart. The delay is
+	// doubled for each subsequent restart.
+	Backoff time.Duration
+	// MaxBackoff limits the delay between restarts (0 for no limit).
+	MaxBackoff time.Duration
+}
+
+// WithRestart sets the restart policy for the foreground process.
+//
+// Before restarting, subprocesses of the previous run are signaled and
+// reaped. The foreground process is not restarted if it could not be
+// started or if supervision was stopped by a shutdown signal (SIGINT,
+// SIGTERM or SIGQUIT), the forced shutdown signal or context
+// cancellation.
+func WithRestart(policy RestartPolicy) Option {
+	return func(r *Reap) {
+		r.restartPolicy = policy
+	}
+}
+
+// execRestart runs the foreground process, restarting it according to
+// the restart policy.
+func (r *supervision) execRestart(argv []string, env []string) (int, error) {
+	backoff := r.restartPolicy.Backoff
+
+	for n := 0; ; n++ {
+		r.stats.update(func(s *Stats) {
+			s.Pid = 0
+			s.Status = 0
+			s.Restarts = n
+		})
+		r.healthRestart = false
+
+		status, err := r.exec(argv, env)
+		if !r.retry(status, err, n) {
+			return status, err
+		}
+
+		if err := r.reap(false); err != nil {
+			if errors.Is(err, ErrReapTimeout) {
+				return r.timeoutStatus, err
+			}
+			return 111, err
+		}
+
+		r.notify("%d: restarting (%d): exit status %d: backoff %s", r.Pid(), n+1, status, backoff)
+
+		if !r.backoff(backoff) {
+			return status, nil
+		}
+
+		backoff *= 2
+		if r.restartPolicy.MaxBackoff > 0 && backoff > r.restartPolicy.MaxBackoff {
+			backoff = r.restartPolicy.MaxBackoff
+		}
+	}
+}
+
+// retry returns true if the foreground process should be restarted
+// after the nth restart.
+func (r *supervision) retry(status int, err error, n int) bool {
+	p := r.restartPolicy
+
+	switch {
+	case err != nil, r.stopping:
+		return false
+	case p.MaxRetries > 0 && n >= p.MaxRetries:
+		return false
+	}
+
+	if r.healthRestart {
+		return true
+	}
+
+	switch p.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return status != 0
+	default:
+		return false
+	}
+}
+
+// backoff waits before restarting the foreground process. backoff
+// returns false if a shutdown was requested while waiting.
+func (r *supervision) backoff(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			return true
+		case <-r.done:
+			r.notify("%d: supervision canceled: not restarting", r.Pid())
+			return false
+		case sig := <-r.sigch:
+			if sig == r.forceSig || shutdownSignal(sig) {
+				r.notify("%d: shutdown: %s: not restarting", r.Pid(), sig)
+				return false
+			}
+		}
+	}
+}
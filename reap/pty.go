@@ -0,0 +1,159 @@
+//go:build !windows
+
+package reap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ptyDrainTimeout is the maximum time to wait for the pseudo-terminal
+// output to be written after the foreground process exits. Output from
+// background processes continues to be written until the terminal is
+// closed.
+const ptyDrainTimeout = 100 * time.Millisecond
+
+// runPty runs a command with a pseudo-terminal as the controlling
+// terminal.
+func (r *supervision) runPty(cmd *exec.Cmd) (int, error) {
+	master, slave, err := openPty()
+	if err != nil {
+		return 111, err
+	}
+
+	stdin := cmd.Stdin
+
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+
+	// the session leader is the process group leader
+	cmd.SysProcAttr.Setpgid = false
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+
+	resize := func() {
+		ws, err := unix.IoctlGetWinsize(int(os.Stdin.Fd()), unix.TIOCGWINSZ)
+		if err != nil {
+			return
+		}
+		if err := control(master, func(fd int) error {
+			return unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, ws)
+		}); err != nil {
+			r.log(fmt.Errorf("%d: TIOCSWINSZ: %w", r.Pid(), err))
+		}
+	}
+
+	resize()
+
+	// copy the terminal modes, e.g., the erase character, before placing
+	// the terminal in raw mode
+	if termios, err := unix.IoctlGetTermios(int(os.Stdin.Fd()), ioctlGetTermios); err == nil {
+		if err := control(slave, func(fd int) error {
+			return unix.IoctlSetTermios(fd, ioctlSetTermios, termios)
+		}); err != nil {
+			r.log(fmt.Errorf("%d: TCSETS: %w", r.Pid(), err))
+		}
+	}
+
+	if restore, err := makeRaw(os.Stdin); err == nil {
+		defer restore()
+	}
+
+	err = r.start(cmd)
+	slave.Close()
+	if err != nil {
+		master.Close()
+		r.onStartErr(err)
+		return 127, err
+	}
+
+	r.cgroupEnter(cmd.Process.Pid)
+	r.writePid(cmd.Process.Pid)
+	r.startHandler(cmd.Process.Pid)
+
+	done := make(chan struct{})
+
+	stdout := r.stdout
+	if stdout == nil {
+		stdout = io.Discard
+	}
+
+	go func() {
+		defer close(done)
+		defer master.Close()
+		_, _ = io.Copy(stdout, master)
+	}()
+
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(master, stdin)
+		}()
+	}
+
+	status, err := r.waitCmd(cmd, resize)
+
+	t := time.NewTimer(ptyDrainTimeout)
+	defer t.Stop()
+
+	select {
+	case <-done:
+	case <-t.C:
+	}
+
+	return status, err
+}
+
+// control runs an ioctl on the file descriptor without changing the
+// file to blocking mode.
+func control(f *os.File, fn func(fd int) error) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var ferr error
+	if err := rc.Control(func(fd uintptr) {
+		ferr = fn(int(fd))
+	}); err != nil {
+		return err
+	}
+
+	return ferr
+}
+
+// makeRaw places a terminal into raw mode. The returned function
+// restores the terminal.
+func makeRaw(f *os.File) (func(), error) {
+	fd := int(f.Fd())
+
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	orig := *termios
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = unix.IoctlSetTermios(fd, ioctlSetTermios, &orig)
+	}, nil
+}
@@ -0,0 +1,51 @@
+package reap
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
+
+func openPty() (master *os.File, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var name string
+
+	if err := control(master, func(fd int) error {
+		if err := unix.IoctlSetInt(fd, unix.TIOCPTYGRANT, 0); err != nil {
+			return fmt.Errorf("TIOCPTYGRANT: %w", err)
+		}
+		if err := unix.IoctlSetInt(fd, unix.TIOCPTYUNLK, 0); err != nil {
+			return fmt.Errorf("TIOCPTYUNLK: %w", err)
+		}
+		buf := make([]byte, 128)
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd),
+			uintptr(unix.TIOCPTYGNAME), uintptr(unsafe.Pointer(&buf[0]))); errno != 0 {
+			return fmt.Errorf("TIOCPTYGNAME: %w", errno)
+		}
+		name = unix.ByteSliceToString(buf)
+		return nil
+	}); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	slave, err = os.OpenFile(name, os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	return master, slave, nil
+}
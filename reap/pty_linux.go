@@ -0,0 +1,44 @@
+package reap
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
+
+func openPty() (master *os.File, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var n int
+
+	if err := control(master, func(fd int) error {
+		if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+			return fmt.Errorf("TIOCSPTLCK: %w", err)
+		}
+		n, err = unix.IoctlGetInt(fd, unix.TIOCGPTN)
+		if err != nil {
+			return fmt.Errorf("TIOCGPTN: %w", err)
+		}
+		return nil
+	}); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	return master, slave, nil
+}
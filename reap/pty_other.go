@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !windows
+
+package reap
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
+
+// openPty is not supported on this platform.
+func openPty() (master *os.File, slave *os.File, err error) {
+	return nil, nil, syscall.ENOSYS
+}
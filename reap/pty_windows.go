@@ -0,0 +1,12 @@
+package reap
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// runPty is not supported on Windows.
+func (r *supervision) runPty(cmd *exec.Cmd) (int, error) {
+	return 111, fmt.Errorf("pty: %w", syscall.ENOSYS)
+}
@@ -1,43 +1,165 @@
 // Package reap configures the go process as a process supervisor. A
 // process supervisor is the init process for subprocesses and
 // terminates all subprocesses when the foreground process exits.
+//
+// On Unix platforms other than Linux, processes are started in a new
+// process group and the process groups are signaled to terminate
+// subprocesses which have escaped the process tree: on darwin, NetBSD
+// and OpenBSD, orphaned subprocesses are reparented to init (launchd)
+// instead of the supervisor. Parent death signals, cgroups, PID
+// namespaces, pidfds and WithDisableSetuid are not supported.
+// Pseudo-terminals are supported on darwin.
+//
+// On Windows, started processes are assigned to a job object created with
+// JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE: descendants are created in the job
+// and terminated if the supervisor exits. Signals terminate the process
+// with an exit status of 128 plus the signal number. Pseudo-terminals,
+// setting the user, root directory or umask, cgroups, PID namespaces and
+// WithDisableSetuid are not supported.
 package reap
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
 	"runtime"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/msantos/goreap/process"
 	"github.com/msantos/goreap/subreaper"
-
-	"golang.org/x/sys/unix"
 )
 
 const (
 	maxInt64 = 1<<63 - 1
 )
 
+// ErrReapTimeout is returned if subprocesses are running after the reap
+// timeout.
+var ErrReapTimeout = errors.New("reap timeout: subprocesses running")
+
+// ErrInvalidSignal is returned if the signal sent to subprocesses is
+// rejected as invalid by the operating system.
+var ErrInvalidSignal = errors.New("invalid signal")
+
+// ErrStdinConflict is returned if the configuration requires both the
+// foreground process and the supervisor to read from standard input.
+var ErrStdinConflict = errors.New("stdin: owned by supervisor: cannot be forwarded to the foreground process")
+
+// sigchSize is the number of signals queued before signals are
+// dropped, e.g., signals received while the foreground process is
+// starting.
+const sigchSize = 16
+
+var (
+	kill         = pidfdKill
+	subreaperGet = SubReaper
+)
+
 type Reap struct {
 	sig           syscall.Signal
+	forceSig      syscall.Signal
 	disableSetuid bool
+	ignoreSigpipe bool
+	signalSelf    bool
+	marker        string
+	pty           bool
+	reexec        bool
+	pidns         bool
+	namespaces    Namespace
+	hostname      string
+	seccomp       *SeccompProfile
+	capDrop       []string
+	capAdd        []string
+	thread        *startThread
+	threadUsers   int
 	wait          bool
 	deadline      time.Duration
+	reapTimeout   time.Duration
+	timeoutStatus int
+	killedStatus  int
+	signalStatus  int
+	successStatus map[int]struct{}
 	delay         time.Duration
-	log           func(error)
+	maxPasses     int
+	maxDepth      int
+	minGrace      time.Duration
+	doubleSignal  bool
+	noEscalate    bool
+	contStopped   bool
+	trigger       Trigger
+	stateFile     string
+	restartSig    syscall.Signal
+	cgroup        string
+	cgroupCreated bool
+	restartPolicy RestartPolicy
+	escalation    []Step
+	neverKill     map[int]struct{}
+	exclude       map[int]struct{}
+	excludeFilter func(process.PID) bool
+	resend        map[syscall.Signal]bool
+	signalMap     map[os.Signal]syscall.Signal
+	forwardTo     Forward
+	predicate     func(process.PID) bool
+	ladders       []Ladder
+	matchExe      bool
+	progress      bool
+	exitPolicy    ExitPolicy
+	descEvents    bool
+	checkpoint    time.Duration
+	logger        func(error)
+	structLog     Logger
+	stdinFile     string
+	stdinOwner    StdinOwner
+	pidFd         int
+	inheritFds    []*os.File
+	stdin         io.Reader
+	stdout        io.Writer
+	stderr        io.Writer
+	waitErr       func(error) bool
+	onStartErr    func(error)
+	exitHandler   func(int, syscall.WaitStatus)
+	rusageHandler func(int, syscall.WaitStatus, Rusage)
+	startHandler  func(int)
+	hooks         map[Hook]func(Event)
+	credential    *credential
+	setenv        []string
+	unsetenv      []string
+	clearenv      bool
+	adopted       int
+	dir           string
+	umask         int
+	rlimits       []rlimit
+	oom           *oomScoreAdj
+	chroot        string
+	health        healthCheck
 
 	sigch chan os.Signal
+	abort chan error
+
+	orphans     orphans
+	pgroups     pgroups
+	descendants int
+	stats       stats
+
+	mu          sync.Mutex
+	logDisabled bool
+	logPanic    interface{}
 
 	process.Process
+
+	// tracker reads the process table polled while supervising
+	tracker *process.Tracker
 }
 
 func init() {
-	_ = subreaper.Set()
+	shimExec()
+	subreaperErr = subreaper.Set()
 }
 
 // SubReaper indicates whether the current process is the init process
@@ -48,6 +170,44 @@ func SubReaper() bool {
 
 type Option func(*Reap)
 
+// WithCombinedOutput sets the standard output and standard error of the
+// foreground process to the same writer. Output is written to w in the
+// order it is written by the process.
+func WithCombinedOutput(w io.Writer) Option {
+	return func(r *Reap) {
+		if w == nil {
+			w = io.Discard
+		}
+		r.stdout = w
+		r.stderr = w
+	}
+}
+
+// WithStdin sets the standard input of the foreground process. A nil
+// reader connects standard input to /dev/null. If a file is set using
+// WithStdinFile, the file is used instead.
+func WithStdin(stdin io.Reader) Option {
+	return func(r *Reap) {
+		r.stdin = stdin
+	}
+}
+
+// WithStdout sets the standard output of the foreground process. A nil
+// writer connects standard output to /dev/null.
+func WithStdout(w io.Writer) Option {
+	return func(r *Reap) {
+		r.stdout = w
+	}
+}
+
+// WithStderr sets the standard error of the foreground process. A nil
+// writer connects standard error to /dev/null.
+func WithStderr(w io.Writer) Option {
+	return func(r *Reap) {
+		r.stderr = w
+	}
+}
+
 // WithDeadline sets a timeout for subprocesses to exit after the
 // foreground process exits. When the deadline is reached, subprocesses
 // are signaled with SIGKILL.
@@ -61,6 +221,94 @@ func WithDeadline(t time.Duration) Option {
 	}
 }
 
+// WithPidFd writes the process ID of the foreground process to an open
+// file descriptor after the process is started, e.g., for supervisors
+// using a pipe to receive the pid. The file descriptor is closed after
+// writing. A negative file descriptor (the default) disables writing the
+// pid.
+func WithPidFd(fd int) Option {
+	return func(r *Reap) {
+		r.pidFd = fd
+	}
+}
+
+// WithInheritFds passes open files to the foreground process. The files
+// are numbered in order starting at file descriptor 3, e.g., for socket
+// activation. Files are not closed and are passed to the process when
+// restarted. Passing files is not supported on Windows.
+func WithInheritFds(files []*os.File) Option {
+	return func(r *Reap) {
+		r.inheritFds = files
+	}
+}
+
+// writePid writes the foreground process ID to the pid file descriptor.
+func (r *Reap) writePid(pid int) {
+	if r.pidFd < 0 {
+		return
+	}
+
+	f := os.NewFile(uintptr(r.pidFd), "pidfd")
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%d\n", pid); err != nil {
+		r.log(fmt.Errorf("%d: pid fd: %w", r.Pid(), err))
+	}
+}
+
+// StdinOwner is the process reading from standard input.
+type StdinOwner int
+
+const (
+	// StdinChild connects standard input to the foreground process
+	// (the default).
+	StdinChild StdinOwner = iota
+
+	// StdinSupervisor reserves standard input for the supervisor, e.g.,
+	// for a control channel. The foreground process standard input is
+	// set to /dev/null unless a file or reader is specified using
+	// WithStdinFile or WithStdin.
+	StdinSupervisor
+)
+
+// WithStdinOwner sets the process reading from standard input. Options
+// which forward standard input to the foreground process conflict with
+// StdinSupervisor and cause Supervise to return ErrStdinConflict: for
+// example, WithPTY without WithStdinFile.
+func WithStdinOwner(owner StdinOwner) Option {
+	return func(r *Reap) {
+		r.stdinOwner = owner
+	}
+}
+
+// WithStdinFile sets the standard input of the foreground process to a
+// file, e.g., /dev/null. The file is opened when the process is started
+// and closed after the process exits.
+func WithStdinFile(path string) Option {
+	return func(r *Reap) {
+		r.stdinFile = path
+	}
+}
+
+// WithShutdownBudget sets the deadline as a fraction of a total shutdown
+// budget, e.g., a platform grace period before the supervisor is
+// killed. Subprocesses are signaled for killAfterFraction of the budget
+// and then sent SIGKILL for the remainder.
+//
+// The fraction is clamped to the range 0 to 1. A budget of 0 disables
+// the deadline.
+func WithShutdownBudget(total time.Duration, killAfterFraction float64) Option {
+	return func(r *Reap) {
+		switch {
+		case killAfterFraction < 0:
+			killAfterFraction = 0
+		case killAfterFraction > 1:
+			killAfterFraction = 1
+		}
+		WithDeadline(time.Duration(float64(total) * killAfterFraction))(r)
+	}
+}
+
 // WithDelay waits the specified duration before resending signals
 // after the foreground process exits.
 func WithDelay(t time.Duration) Option {
@@ -73,6 +321,46 @@ func WithDelay(t time.Duration) Option {
 	}
 }
 
+// WithCheckpointLog logs the subprocesses remaining at intervals while
+// reaping, e.g., to find processes which do not exit during shutdown.
+// An interval of 0 (the default) disables logging.
+func WithCheckpointLog(interval time.Duration) Option {
+	return func(r *Reap) {
+		r.checkpoint = interval
+	}
+}
+
+// WithDescendantEvents logs the number of descendants of the process
+// when the number changes while the foreground process is running.
+// Descendants are counted at intervals (100ms): processes running for
+// less than the interval may not be reported.
+func WithDescendantEvents(b bool) Option {
+	return func(r *Reap) {
+		r.descEvents = b
+	}
+}
+
+// WithUser runs the foreground process as a user, group and list of
+// supplementary groups. The supervisor continues to run as the current
+// user, retaining the ability to signal descendants. Setting the user
+// requires privileges (CAP_SETUID and CAP_SETGID).
+//
+// An empty list of supplementary groups clears the groups of the
+// process.
+func WithUser(uid, gid int, groups []int) Option {
+	return func(r *Reap) {
+		cred := &credential{
+			Uid:    uint32(uid),
+			Gid:    uint32(gid),
+			Groups: make([]uint32, 0, len(groups)),
+		}
+		for _, g := range groups {
+			cred.Groups = append(cred.Groups, uint32(g))
+		}
+		r.credential = cred
+	}
+}
+
 // WithDisableSetuid disallows unkillable setuid subprocesses.
 func WithDisableSetuid(b bool) Option {
 	return func(r *Reap) {
@@ -80,205 +368,1627 @@ func WithDisableSetuid(b bool) Option {
 	}
 }
 
+// WithIgnoreSigpipe sets SIGPIPE to be ignored by the foreground
+// process: writes to a closed pipe fail with EPIPE instead of terminating
+// the process. By default, SIGPIPE terminates the foreground process.
+//
+// The signal disposition is inherited from this process: SIGPIPE is
+// ignored by this process while the foreground process is running and
+// restored when the foreground process exits.
+func WithIgnoreSigpipe(b bool) Option {
+	return func(r *Reap) {
+		r.ignoreSigpipe = b
+	}
+}
+
 // WithLog specifies a function for logging.
 func WithLog(f func(error)) Option {
 	return func(r *Reap) {
 		if f == nil {
-			r.log = func(error) {}
+			r.logger = func(error) {}
 			return
 		}
-		r.log = f
+		r.logger = f
 	}
 }
 
-// WithSignal sets the signal sent to subprocesses after the foreground
-// process exits.
-func WithSignal(sig int) Option {
+// WithWaitErrorHandler sets a function called when waiting for
+// subprocesses fails with an unexpected error. Reaping continues if the
+// function returns true and aborts with the error if false.
+//
+// Transient errors (EINTR, EAGAIN) are always retried.
+func WithWaitErrorHandler(f func(error) bool) Option {
 	return func(r *Reap) {
-		r.sig = syscall.Signal(sig)
+		if f == nil {
+			r.waitErr = func(error) bool { return false }
+			return
+		}
+		r.waitErr = f
 	}
 }
 
-// WithWait disables signalling subprocesses.
-func WithWait(b bool) Option {
+// WithExitHandler sets a function called with the pid and wait status
+// of each subprocess reaped by this process. The handler is not called
+// for the foreground process: the foreground process exit status is
+// returned by Supervise.
+func WithExitHandler(f func(pid int, status syscall.WaitStatus)) Option {
 	return func(r *Reap) {
-		r.wait = b
+		if f == nil {
+			r.exitHandler = func(int, syscall.WaitStatus) {}
+			return
+		}
+		r.exitHandler = f
 	}
 }
 
-// New sets the current process to act as a process supervisor.
-func New(opts ...Option) *Reap {
-	r := &Reap{
-		Process:  process.New(),
-		delay:    time.Duration(1) * time.Second,
-		deadline: time.Duration(60) * time.Second,
-		log:      func(error) {},
-		sig:      syscall.Signal(15),
-		sigch:    make(chan os.Signal, 1),
+// WithStartHandler sets a function called with the pid of the
+// foreground process after the process is started, including when the
+// process is restarted (see WithRestart).
+func WithStartHandler(f func(pid int)) Option {
+	return func(r *Reap) {
+		if f == nil {
+			r.startHandler = func(int) {}
+			return
+		}
+		r.startHandler = f
 	}
+}
 
-	signal.Notify(r.sigch)
-
-	for _, opt := range opts {
-		opt(r)
+// WithOnStartError sets a function called when the foreground process
+// cannot be started, e.g., the executable is not found. The function is
+// not called if the process starts and exits with a non-zero status.
+func WithOnStartError(f func(error)) Option {
+	return func(r *Reap) {
+		if f == nil {
+			r.onStartErr = func(error) {}
+			return
+		}
+		r.onStartErr = f
 	}
+}
 
-	return r
+// WithMaxSignalPasses sets the maximum number of times subprocesses are
+// sent the signal after the foreground process exits. Subsequent passes
+// send SIGKILL. The signal is escalated when either the deadline or the
+// maximum number of passes is reached.
+//
+// A value of 0 (the default) disables the limit.
+func WithMaxSignalPasses(n int) Option {
+	return func(r *Reap) {
+		r.maxPasses = n
+	}
 }
 
-// Supervise creates a subprocess, terminating all subprocesses when
-// the foreground process exits.
-func (r *Reap) Supervise(argv []string, env []string) (int, error) {
-	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
+// WithProcess sets the implementation used to enumerate subprocesses.
+func WithProcess(p process.Process) Option {
+	return func(r *Reap) {
+		if p == nil {
+			return
+		}
+		r.Process = p
+	}
+}
 
-	status, err := r.Exec(argv, env)
+// WithProgressDeadline restarts the deadline whenever the number of
+// subprocesses decreases between signals. Subprocesses are signaled with
+// SIGKILL only if no subprocess exits for the duration of the deadline.
+func WithProgressDeadline(b bool) Option {
+	return func(r *Reap) {
+		r.progress = b
+	}
+}
 
-	if err := r.Reap(); err != nil {
-		return 111, err
+// WithDoubleSignal escalates to SIGKILL when a shutdown signal (SIGINT,
+// SIGTERM or SIGQUIT) is received while a shutdown is in progress, like
+// pressing Ctrl-C twice.
+//
+// The first shutdown signal received while the foreground process is
+// running is forwarded to subprocesses. A subsequent shutdown signal, or
+// a shutdown signal received while reaping, sends SIGKILL to all
+// subprocesses instead of being forwarded (see also WithNoEscalate and
+// WithNeverKill).
+func WithDoubleSignal(b bool) Option {
+	return func(r *Reap) {
+		r.doubleSignal = b
 	}
+}
 
-	return status, err
+// kill9 sends SIGKILL to subprocesses after a repeated shutdown signal.
+func (r *supervision) kill9(sig os.Signal) {
+	if r.noEscalate {
+		r.notify("%d: shutdown in progress: %s: ignored", r.Pid(), sig)
+		return
+	}
+	r.notify("%d: shutdown in progress: %s: escalating", r.Pid(), sig)
+	r.stats.update(func(s *Stats) { s.Killed = true })
+	r.cgroupKill()
+	r.reaperKill()
+	r.signalWith(syscall.SIGKILL)
 }
 
-// Exec forks and executes a subprocess.
-func (r *Reap) Exec(argv []string, env []string) (int, error) {
-	if r.disableSetuid {
-		runtime.LockOSThread()
-		defer runtime.UnlockOSThread()
+// killAll returns true if all subprocesses can be sent SIGKILL without
+// signaling each process by pid: subprocesses are not excluded from
+// SIGKILL or from being signaled.
+func (r *Reap) killAll() bool {
+	return len(r.neverKill) == 0 && len(r.exclude) == 0 &&
+		r.predicate == nil && r.excludeFilter == nil && r.maxDepth <= 0
+}
 
-		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
-			return 111, fmt.Errorf("prctl(PR_SET_NO_NEW_PRIVS): %w", err)
-		}
+// reaperKill sends SIGKILL to all descendants using the kernel reaper
+// facility (FreeBSD procctl(2) PROC_REAP_KILL). On other platforms,
+// subprocesses are signaled by pid.
+func (r *Reap) reaperKill() {
+	if r.Pid() != os.Getpid() || r.nested() || !r.killAll() {
+		return
 	}
 
-	return r.execv(argv[0], argv[1:], env)
+	n, err := subreaper.Kill(syscall.SIGKILL)
+	switch {
+	case err == nil:
+		r.notify("%d: reaper kill: %d processes", r.Pid(), n)
+	case errors.Is(err, syscall.ENOSYS), errors.Is(err, syscall.ESRCH):
+	default:
+		r.log(fmt.Errorf("%d: reaper kill: %w", r.Pid(), err))
+	}
 }
 
-func (r *Reap) kill(pid int, sig syscall.Signal) {
-	err := syscall.Kill(pid, sig)
-	if err == nil || errors.Is(err, syscall.ESRCH) {
-		return
+// shutdownSignal returns true if the signal requests termination.
+func shutdownSignal(sig os.Signal) bool {
+	switch sig {
+	case syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT:
+		return true
 	}
-	r.log(err)
+	return false
 }
 
-func (r *Reap) signalWith(sig syscall.Signal) {
-	pids, err := r.Children()
-	if err != nil {
-		r.log(err)
-		return
+// WithMaxDepth limits the depth of the process tree signaled: depth 1
+// signals the children of the process, depth 2 the children and
+// grandchildren and so on. Orphaned subprocesses are reparented to the
+// supervisor and signaled in a later pass. A depth of 0 (the default)
+// signals all descendants.
+func WithMaxDepth(n int) Option {
+	return func(r *Reap) {
+		r.maxDepth = n
 	}
+}
 
-	for _, pid := range pids {
-		r.log(fmt.Errorf("%d: kill %d %d", r.Pid(), sig, pid))
-		r.kill(pid, sig)
+// WithMinGrace sets the minimum time subprocesses are given to exit
+// after the first signal before SIGKILL is sent. The minimum grace
+// period takes precedence over the deadline, the maximum number of
+// signal passes and signal ladders: SIGKILL is not sent until both the
+// deadline and the minimum grace period have passed.
+func WithMinGrace(d time.Duration) Option {
+	return func(r *Reap) {
+		r.minGrace = d
 	}
 }
 
-func (r *Reap) reaper(exitch <-chan struct{}) {
-	t := time.NewTimer(r.deadline)
-	tick := time.NewTicker(r.delay)
+// WithNeverKill sets processes which are not sent SIGKILL when the
+// deadline is reached. The configured signal continues to be sent to the
+// processes.
+func WithNeverKill(pids []int) Option {
+	return func(r *Reap) {
+		r.neverKill = make(map[int]struct{}, len(pids))
+		for _, pid := range pids {
+			r.neverKill[pid] = struct{}{}
+		}
+	}
+}
 
-	signal := func() {
-		if r.wait {
-			return
+// WithExcludePids sets processes which are never signaled. Excluded
+// subprocesses continue to be waited for: see WithReapTimeout.
+func WithExcludePids(pids []int) Option {
+	return func(r *Reap) {
+		r.exclude = make(map[int]struct{}, len(pids))
+		for _, pid := range pids {
+			r.exclude[pid] = struct{}{}
 		}
-		r.signalWith(r.sig)
 	}
+}
 
-	signal()
+// WithExcludeFilter sets a function excluding subprocesses from being
+// signaled: subprocesses are never signaled if the function returns
+// true. Excluded subprocesses continue to be waited for: see
+// WithReapTimeout.
+func WithExcludeFilter(f func(process.PID) bool) Option {
+	return func(r *Reap) {
+		r.excludeFilter = f
+	}
+}
 
-	for {
-		select {
-		case <-exitch:
-			return
-		case <-t.C:
-			r.sig = syscall.SIGKILL
-		case sig := <-r.sigch:
-			switch sig {
-			case syscall.SIGCHLD, syscall.SIGIO, syscall.SIGPIPE, syscall.SIGURG:
-			default:
-				r.signalWith(sig.(syscall.Signal))
-			}
-		case <-tick.C:
-			signal()
-		}
+// WithNoEscalate disables sending SIGKILL when the deadline or the
+// maximum number of signal passes is reached. The configured signal
+// continues to be sent to subprocesses.
+func WithNoEscalate(b bool) Option {
+	return func(r *Reap) {
+		r.noEscalate = b
 	}
 }
 
-// Reap delivers a signal to all descendants of this process.
-func (r *Reap) Reap() error {
-	exitch := make(chan struct{})
-	defer close(exitch)
+// WithContinueStopped sends SIGCONT to stopped subprocesses (process
+// state T) before signaling: a stopped process does not handle signals
+// other than SIGKILL until the process is continued, e.g., a shell job
+// suspended by SIGTSTP.
+func WithContinueStopped(b bool) Option {
+	return func(r *Reap) {
+		r.contStopped = b
+	}
+}
 
-	go r.reaper(exitch)
+// WithPTY runs the foreground process in a pseudo-terminal. Standard
+// input is copied to the terminal and terminal output is written to
+// standard output. If standard input is a terminal, the terminal modes
+// are copied to the pseudo-terminal, the terminal is placed in raw mode
+// and window size changes are propagated.
+func WithPTY(b bool) Option {
+	return func(r *Reap) {
+		r.pty = b
+	}
+}
 
-	for {
-		_, err := syscall.Wait4(-1, nil, 0, nil)
-		switch {
-		case err == nil, errors.Is(err, syscall.EINTR):
-		case errors.Is(err, syscall.ECHILD):
-			return nil
-		default:
-			return err
-		}
+// WithReapTimeout sets the maximum duration for subprocesses to exit
+// after the foreground process exits. If subprocesses are running after
+// the timeout, reaping is abandoned: Reap returns ErrReapTimeout and
+// Supervise returns the reap timeout status.
+//
+// A timeout of 0 (the default) waits for all subprocesses to exit.
+func WithReapTimeout(t time.Duration) Option {
+	return func(r *Reap) {
+		r.reapTimeout = t
 	}
 }
 
-func (r *Reap) execv(command string, args []string, env []string) (int, error) {
-	cmd := exec.Command(command, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Env = env
+// WithForcedKillExitCode sets the exit status returned by Supervise if
+// subprocesses were sent SIGKILL after the deadline, the maximum number
+// of signal passes or a repeated shutdown signal. The exit status of
+// the foreground process is logged and replaced: callers cannot
+// distinguish a failure of the foreground process from a forced
+// shutdown. A negative value (the default) returns the foreground
+// process exit status.
+func WithForcedKillExitCode(status int) Option {
+	return func(r *Reap) {
+		r.killedStatus = status
+	}
+}
 
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Pdeathsig: syscall.SIGKILL,
+// WithReapTimeoutStatus sets the exit status returned by Supervise when
+// the reap timeout is reached (default 112).
+func WithReapTimeoutStatus(status int) Option {
+	return func(r *Reap) {
+		r.timeoutStatus = status
 	}
+}
 
-	if err := cmd.Start(); err != nil {
-		return 127, err
+// WithReexec re-executes the current process as the init process of a
+// new PID namespace if the process could not be configured as a
+// subreaper. See ReexecEnv for details.
+func WithReexec(b bool) Option {
+	return func(r *Reap) {
+		r.reexec = b
 	}
+}
 
-	waitch := make(chan error, 1)
-	go func() {
-		waitch <- cmd.Wait()
-	}()
+// WithNewPidNamespace re-executes the current process as the init
+// process of a new PID namespace: the foreground process and
+// subprocesses run in the namespace. If the init process exits, the
+// remaining processes in the namespace are killed by the kernel.
+//
+// An unprivileged process also creates a user namespace: the process
+// and subprocesses run as root in the namespace, mapped to the current
+// uid and gid. See ReexecEnv for details.
+func WithNewPidNamespace(b bool) Option {
+	return func(r *Reap) {
+		r.pidns = b
+	}
+}
 
-	return r.waitpid(waitch)
+// WithSignal sets the signal sent to subprocesses after the foreground
+// process exits.
+//
+// Signal 0 checks subprocesses are running without sending a signal:
+// running subprocesses are logged and are not terminated when the
+// deadline is reached.
+func WithSignal(sig int) Option {
+	return func(r *Reap) {
+		r.sig = syscall.Signal(sig)
+	}
 }
 
-func (r *Reap) waitpid(waitch <-chan error) (int, error) {
-	var exitError *exec.ExitError
+// WithReapPredicate sets a function deciding whether a subprocess is
+// signaled. Subprocesses are signaled if the function returns true (the
+// default). Subprocesses which are not signaled continue to be waited
+// for: see WithReapTimeout.
+//
+// The process includes the effective user ID if the process table
+// supports reading a single process (see process.Ps.Stat) or is
+// created using process.WithDetails.
+func WithReapPredicate(f func(process.PID) bool) Option {
+	return func(r *Reap) {
+		r.predicate = f
+	}
+}
 
-	for {
-		select {
-		case sig := <-r.sigch:
-			switch sig {
-			case syscall.SIGCHLD, syscall.SIGIO, syscall.SIGPIPE, syscall.SIGURG:
-			default:
-				r.signalWith(sig.(syscall.Signal))
-			}
-		case err := <-waitch:
-			if err == nil {
-				return 0, nil
-			}
+// WithResendSignals sets which signals are re-sent to subprocesses at
+// each interval (see WithDelay) while reaping and which are sent once.
+//
+// By default, the signal set by WithSignal is re-sent and signals
+// received while reaping are forwarded once. A signal mapped to true is
+// re-sent until subprocesses exit; false sends the signal once. SIGKILL
+// is always re-sent.
+func WithResendSignals(m map[syscall.Signal]bool) Option {
+	return func(r *Reap) {
+		r.resend = make(map[syscall.Signal]bool, len(m))
+		for sig, b := range m {
+			r.resend[sig] = b
+		}
+	}
+}
 
-			if !errors.As(err, &exitError) {
-				return 128, err
-			}
+// resends returns true if the signal is re-sent at each interval.
+func (r *Reap) resends(sig syscall.Signal) bool {
+	if sig == syscall.SIGKILL {
+		return true
+	}
+	if b, ok := r.resend[sig]; ok {
+		return b
+	}
+	return sig == r.sig || r.escalates(sig)
+}
 
-			waitStatus, ok := exitError.Sys().(syscall.WaitStatus)
-			if !ok {
-				return 128, err
+// WithSignalMap translates signals received by the supervisor before
+// forwarding the signals to subprocesses, e.g., mapping SIGINT to SIGTERM
+// for daemons expecting SIGTERM for a graceful shutdown.
+//
+// Signals are mapped when forwarded: shutdown signals and the forced
+// shutdown signal are handled using the received signal.
+func WithSignalMap(m map[os.Signal]os.Signal) Option {
+	return func(r *Reap) {
+		r.signalMap = make(map[os.Signal]syscall.Signal, len(m))
+		for from, to := range m {
+			if sig, ok := to.(syscall.Signal); ok {
+				r.signalMap[from] = sig
 			}
+		}
+	}
+}
 
-			if waitStatus.Signaled() {
-				return 128 + int(waitStatus.Signal()), nil
-			}
+// Forward is the set of processes receiving signals forwarded while the
+// foreground process is running.
+type Forward int
+
+const (
+	// ForwardDescendants forwards signals to the subprocesses signaled
+	// when reaping (the default).
+	ForwardDescendants Forward = iota
 
-			return waitStatus.ExitStatus(), nil
+	// ForwardChild forwards signals to the foreground process only.
+	ForwardChild
+
+	// ForwardGroup forwards signals to subprocesses in the process
+	// group of the foreground process.
+	ForwardGroup
+)
+
+// WithForward sets the processes receiving signals forwarded while the
+// foreground process is running. After the foreground process exits,
+// signals are forwarded to all subprocesses.
+func WithForward(f Forward) Option {
+	return func(r *Reap) {
+		r.forwardTo = f
+	}
+}
+
+// forward sends a signal received while the foreground process is
+// running.
+func (r *Reap) forward(fg int, sig syscall.Signal) {
+	var pids []int
+
+	switch r.forwardTo {
+	case ForwardChild:
+		pids = []int{fg}
+	case ForwardGroup:
+		// the process group may be shared with this process: signal
+		// subprocesses in the group by pid
+		pgid, err := getpgid(fg)
+		if err != nil {
+			return
+		}
+		snapshot, err := r.Snapshot()
+		if err != nil {
+			r.log(err)
+			return
+		}
+		for _, pid := range process.Descendants(snapshot, r.Pid()) {
+			if r.excluded(pid) {
+				continue
+			}
+			if p, err := getpgid(pid); err == nil && p == pgid {
+				pids = append(pids, pid)
+			}
 		}
+	default:
+		r.signalWith(sig)
+		return
 	}
+
+	for _, pid := range pids {
+		r.event("signal sent", []Field{{"pid", pid}, {"signal", sysSignalName(sig)}},
+			"%d: kill %d %d", r.Pid(), sig, pid)
+		if err := r.kill(pid, -1, sig); err != nil {
+			r.fatal(err)
+			return
+		}
+	}
+}
+
+// translate returns the signal forwarded to subprocesses.
+func (r *Reap) translate(sig os.Signal) syscall.Signal {
+	s, ok := r.signalMap[sig]
+	if !ok {
+		return sig.(syscall.Signal)
+	}
+	r.notify("%d: forwarding %s as %s", r.Pid(), sig, s)
+	return s
+}
+
+// Trigger is the event causing subprocesses to be terminated.
+type Trigger int
+
+const (
+	// TriggerExit terminates subprocesses when the foreground process
+	// exits (the default).
+	TriggerExit Trigger = iota
+
+	// TriggerFailure terminates subprocesses when the foreground process
+	// exits with a non-zero status. If the foreground process exits
+	// successfully, subprocesses are waited for without being signaled.
+	TriggerFailure
+
+	// TriggerChildExit terminates subprocesses, including the foreground
+	// process, when any child process exits.
+	TriggerChildExit
+
+	// TriggerSignal terminates subprocesses only when the forced shutdown
+	// signal is received (see WithForceShutdownSignal). When the
+	// foreground process exits, subprocesses are waited for without being
+	// signaled.
+	TriggerSignal
+)
+
+// WithShutdownTrigger sets the event causing subprocesses to be
+// terminated.
+func WithShutdownTrigger(t Trigger) Option {
+	return func(r *Reap) {
+		r.trigger = t
+	}
+}
+
+// teardown returns true if subprocesses are signaled after the
+// foreground process exits with status.
+func (r *Reap) teardown(status int) bool {
+	switch r.trigger {
+	case TriggerFailure:
+		return status != 0
+	case TriggerSignal:
+		return false
+	default:
+		return true
+	}
+}
+
+// WithSignalSelf allows signaling this process if it is included in the
+// list of subprocesses, for example, by a custom Process implementation.
+// By default, this process is never signaled.
+func WithSignalSelf(b bool) Option {
+	return func(r *Reap) {
+		r.signalSelf = b
+	}
+}
+
+// WithForceShutdownSignal sets a signal that, when received while the
+// foreground process is running, immediately begins terminating the
+// process tree, including the foreground process. Subprocesses are
+// signaled with the configured signal until the deadline is reached,
+// then with SIGKILL.
+//
+// A signal of 0 disables forced shutdown.
+func WithForceShutdownSignal(sig int) Option {
+	return func(r *Reap) {
+		r.forceSig = syscall.Signal(sig)
+	}
+}
+
+// WithWait disables signalling subprocesses.
+func WithWait(b bool) Option {
+	return func(r *Reap) {
+		r.wait = b
+	}
+}
+
+// New sets the current process to act as a process supervisor.
+// Options are not validated: see NewWithError.
+func New(opts ...Option) *Reap {
+	r := &Reap{
+		Process:       process.New(),
+		delay:         time.Duration(1) * time.Second,
+		deadline:      time.Duration(60) * time.Second,
+		timeoutStatus: 112,
+		killedStatus:  -1,
+		signalStatus:  -1,
+		logger:        func(error) {},
+		stdin:         os.Stdin,
+		stdout:        os.Stdout,
+		stderr:        os.Stderr,
+		waitErr:       func(error) bool { return false },
+		onStartErr:    func(error) {},
+		exitHandler:   func(int, syscall.WaitStatus) {},
+		rusageHandler: func(int, syscall.WaitStatus, Rusage) {},
+		startHandler:  func(int) {},
+		pidFd:         -1,
+		umask:         -1,
+		health:        healthCheck{retries: 3},
+		sig:           syscall.Signal(15),
+		sigch:         make(chan os.Signal, sigchSize),
+		abort:         make(chan error, 1),
+	}
+
+	signal.Notify(r.sigch)
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.tracker = process.NewTracker(r.Process)
+
+	return r
+}
+
+// Supervise creates a subprocess, terminating all subprocesses when
+// the foreground process exits.
+//
+// If Supervise panics, subprocesses are reaped before the panic is
+// re-raised. A panic in the log function disables logging: subprocesses
+// are reaped and the panic is re-raised by Supervise.
+func (r *Reap) Supervise(argv []string, env []string) (int, error) {
+	return r.superviseArgv(nil, argv, env)
+}
+
+// superviseArgv supervises a command, stopping supervision when done
+// is closed.
+func (r *Reap) superviseArgv(done <-chan struct{}, argv []string, env []string) (int, error) {
+	return r.supervise(done, func(r *supervision) (int, error) {
+		switch st := r.restore(); {
+		case st != nil:
+			return r.resume(st)
+		case r.needsReexec():
+			if !r.pidns && r.hostname == "" {
+				r.log(fmt.Errorf("%d: not a subreaper: re-executing in a PID namespace", r.Pid()))
+			}
+			return r.reexecv(env)
+		case os.Getenv(ReexecEnv) != "":
+			return r.reexecInit(argv, env)
+		default:
+			return r.execRestart(argv, env)
+		}
+	})
+}
+
+// supervision is the state of a single call to Supervise. The options
+// are shared with the Reap: calls may run concurrently or after an
+// earlier call left goroutines running.
+type supervision struct {
+	*Reap
+
+	// done stops supervision when closed
+	done <-chan struct{}
+
+	// stopping is set when a shutdown was requested: the foreground
+	// process is not restarted
+	stopping bool
+
+	// healthRestart is set when the foreground process was signaled
+	// by a failed health check
+	healthRestart bool
+
+	stats stats
+}
+
+// supervise runs the foreground processes and reaps subprocesses.
+func (r *Reap) supervise(done <-chan struct{}, run func(*supervision) (int, error)) (int, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	defer r.startSession()()
+
+	sv := &supervision{Reap: r, done: done}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = sv.reap(r.wait)
+			panic(p)
+		}
+		if p := r.takeLogPanic(); p != nil {
+			panic(p)
+		}
+	}()
+
+	if r.nested() {
+		r.log(fmt.Errorf("%d: not a subreaper: tracking descendants by pid", r.Pid()))
+	}
+
+	status, err := run(sv)
+
+	start := time.Now()
+	rerr := sv.reap(r.wait || !r.teardown(status))
+	r.cgroupRemove()
+
+	if err == nil {
+		status = r.remapStatus(status)
+	}
+
+	switch {
+	case errors.Is(rerr, ErrReapTimeout):
+		status, err = r.timeoutStatus, rerr
+	case rerr != nil:
+		status, err = 111, rerr
+	case r.killedStatus >= 0 && sv.stats.load().Killed:
+		r.notify("%d: subprocesses killed: exit status %d: replaced with %d", r.Pid(), status, r.killedStatus)
+		status = r.killedStatus
+	}
+
+	sv.stats.update(func(s *Stats) {
+		s.Status = status
+		s.Duration = time.Since(start)
+	})
+
+	st := sv.stats.load()
+	r.stats.update(func(s *Stats) { *s = st })
+
+	return status, err
+}
+
+// SuperviseContext is like Supervise but stops supervision when the
+// context is done: the foreground process and all subprocesses are
+// signaled and reaped.
+//
+// If the context is done, the context error is returned with the exit
+// status.
+func (r *Reap) SuperviseContext(ctx context.Context, argv []string, env []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 111, err
+	}
+
+	status, err := r.superviseArgv(ctx.Done(), argv, env)
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	return status, err
+}
+
+// Exec forks and executes a subprocess.
+//
+// The foreground process is terminated with SIGKILL if the supervisor
+// exits (see PR_SET_PDEATHSIG in prctl(2)):
+//
+//   - signals are captured by New: signals received before the foreground
+//     process is started are queued and forwarded after the process starts
+//
+//   - the parent death signal is set in the child after fork and before
+//     exec: if the supervisor exits before the signal is set, the child
+//     signals itself
+//
+//   - the parent death signal is sent when the thread which forked the
+//     child exits: the goroutine is locked to the thread until the
+//     foreground process is waited for
+//
+// Subprocesses of the foreground process are not signaled if the
+// supervisor is killed: orphaned subprocesses are reparented to the next
+// subreaper or init (see WithReexec).
+func (r *Reap) Exec(argv []string, env []string) (int, error) {
+	sv := &supervision{Reap: r}
+	return sv.exec(argv, env)
+}
+
+func (r *supervision) exec(argv []string, env []string) (int, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	defer r.startSession()()
+
+	restore, err := r.prepare()
+	if err != nil {
+		return 111, err
+	}
+	defer restore()
+
+	return r.execv(argv[0], argv[1:], env)
+}
+
+// prepare sets the process attributes inherited by the foreground
+// process. The returned function restores the signal disposition of
+// this process after the foreground process exits.
+func (r *Reap) prepare() (func(), error) {
+	restore := func() {}
+
+	if r.disableSetuid {
+		if err := noNewPrivs(); err != nil {
+			return restore, err
+		}
+	}
+
+	if r.oom != nil {
+		if err := setOOMScoreAdj(r.oom.self); err != nil {
+			return restore, err
+		}
+	}
+
+	switch ignored := signal.Ignored(syscall.SIGPIPE); {
+	case r.ignoreSigpipe && !ignored:
+		signal.Ignore(syscall.SIGPIPE)
+		restore = func() {
+			signal.Reset(syscall.SIGPIPE)
+			signal.Notify(r.sigch, syscall.SIGPIPE)
+		}
+	case !r.ignoreSigpipe && ignored:
+		signal.Notify(r.sigch, syscall.SIGPIPE)
+	}
+
+	return restore, nil
+}
+
+// log calls the log function. If the log function panics, logging is
+// disabled and the panic is saved.
+func (r *Reap) log(err error) {
+	r.mu.Lock()
+	disabled := r.logDisabled
+	r.mu.Unlock()
+
+	if disabled {
+		return
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.logDisabled = true
+			if r.logPanic == nil {
+				r.logPanic = p
+			}
+		}
+	}()
+
+	r.logger(err)
+
+	if r.structLog != nil {
+		r.structLog.Log(logEvent(err))
+	}
+}
+
+// takeLogPanic returns and clears the saved log function panic.
+func (r *Reap) takeLogPanic() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.logPanic
+	r.logPanic = nil
+	return p
+}
+
+// notice is an informational message.
+type notice struct {
+	error
+}
+
+func (r *Reap) notify(format string, a ...interface{}) {
+	r.log(notice{fmt.Errorf(format, a...)})
+}
+
+// kill signals a process using the pidfd or by pid if the pidfd is -1.
+func (r *Reap) kill(pid, fd int, sig syscall.Signal) error {
+	err := signalPidfd(pid, fd, sig)
+	switch {
+	case err == nil, errors.Is(err, syscall.ESRCH):
+		return nil
+	case errors.Is(err, syscall.EINVAL):
+		return fmt.Errorf("%d: kill %d: %w: %v", r.Pid(), sig, ErrInvalidSignal, err)
+	}
+	r.log(err)
+	return nil
+}
+
+// fatal aborts reaping with an error.
+func (r *Reap) fatal(err error) {
+	r.log(err)
+	select {
+	case r.abort <- err:
+	default:
+	}
+}
+
+// Targets returns the pids of the processes signaled when reaping:
+// descendants of the process, orphans which cannot be found by walking
+// the process tree and, if enabled, processes with the environment
+// marker. Processes not matching the reap predicate or matching the
+// exclude filter are excluded.
+func (r *Reap) Targets() ([]int, error) {
+	pids, _, err := r.targets()
+	return pids, err
+}
+
+// targets returns the processes to signal and the processes tracked by
+// pid (see unreachable).
+func (r *Reap) targets() ([]int, map[int]struct{}, error) {
+	pids, err := r.children()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	orphans := r.unreachable()
+	tracked := make(map[int]struct{}, len(orphans))
+	for _, pid := range orphans {
+		tracked[pid] = struct{}{}
+	}
+
+	targets := make([]int, 0, len(pids))
+	for _, pid := range union(pids, orphans) {
+		if r.excluded(pid) {
+			continue
+		}
+		targets = append(targets, pid)
+	}
+
+	if r.predicate == nil && r.excludeFilter == nil {
+		return targets, tracked, nil
+	}
+
+	targets, err = r.filter(targets)
+	return targets, tracked, err
+}
+
+// filter returns the processes matching the predicate and not matching
+// the exclude filter. Processes not found in the process table have
+// exited and are removed.
+func (r *Reap) filter(pids []int) ([]int, error) {
+	if s, ok := r.Process.(statter); ok {
+		// the owner is read for each target only
+		targets := pids[:0]
+		for _, pid := range pids {
+			p, err := s.Stat(pid)
+			if err != nil || !r.match(p) {
+				continue
+			}
+			targets = append(targets, pid)
+		}
+		return targets, nil
+	}
+
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	table := make(map[int]process.PID, len(snapshot))
+	for _, p := range snapshot {
+		table[p.Pid] = p
+	}
+
+	targets := pids[:0]
+	for _, pid := range pids {
+		p, ok := table[pid]
+		if !ok || !r.match(p) {
+			continue
+		}
+		targets = append(targets, pid)
+	}
+
+	return targets, nil
+}
+
+// match returns true if a process is signaled by the reap predicate and
+// the exclude filter.
+func (r *Reap) match(p process.PID) bool {
+	if r.predicate != nil && !r.predicate(p) {
+		return false
+	}
+	return r.excludeFilter == nil || !r.excludeFilter(p)
+}
+
+// children returns the descendants of the process limited to the
+// maximum depth.
+func (r *Reap) children() ([]int, error) {
+	if r.maxDepth <= 0 {
+		return r.Children()
+	}
+
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return process.DescendantsDepth(snapshot, r.Pid(), r.maxDepth), nil
+}
+
+// signalWith signals all descendants and returns the number of
+// processes signaled or -1 if the descendants could not be enumerated or
+// the signal is invalid.
+func (r *Reap) signalWith(sig syscall.Signal) int {
+	return r.signalEach(func(int) syscall.Signal { return sig })
+}
+
+// signalEach signals all descendants with the signal returned by the
+// function.
+func (r *Reap) signalEach(signalFor func(pid int) syscall.Signal) int {
+	pids, tracked, err := r.targets()
+	if err != nil {
+		r.log(err)
+		return -1
+	}
+
+	pids, fds := r.pin(pids, tracked)
+	defer closePidfds(fds)
+
+	stopped := r.stopped(pids)
+
+	for _, pid := range pids {
+		s := signalFor(pid)
+		if s == 0 {
+			r.probe(pid)
+			continue
+		}
+		if s == syscall.SIGKILL && r.protected(pid) {
+			r.notify("%d: not responding %d", r.Pid(), pid)
+			s = r.sig
+		}
+		fd, ok := fds[pid]
+		if !ok {
+			fd = -1
+		}
+		if _, ok := stopped[pid]; ok && s != syscall.SIGKILL && s != sigcont {
+			r.event("signal sent", []Field{{"pid", pid}, {"signal", sysSignalName(sigcont)}},
+				"%d: kill %d %d", r.Pid(), sigcont, pid)
+			if err := r.kill(pid, fd, sigcont); err != nil {
+				r.fatal(err)
+				return -1
+			}
+		}
+		r.event("signal sent", []Field{{"pid", pid}, {"signal", sysSignalName(s)}},
+			"%d: kill %d %d", r.Pid(), s, pid)
+		if err := r.kill(pid, fd, s); err != nil {
+			// the signal will be rejected for all processes
+			r.fatal(err)
+			return -1
+		}
+	}
+
+	r.signalGroups(signalFor)
+
+	return len(pids)
+}
+
+// statter reads the process table entry of a single process.
+type statter interface {
+	Stat(pid int) (process.PID, error)
+}
+
+// stopped returns the stopped subprocesses if enabled by
+// WithContinueStopped. The state of each process is read individually
+// if supported by the process table.
+func (r *Reap) stopped(pids []int) map[int]struct{} {
+	if !r.contStopped {
+		return nil
+	}
+
+	stopped := make(map[int]struct{})
+
+	if s, ok := r.Process.(statter); ok {
+		for _, pid := range pids {
+			if p, err := s.Stat(pid); err == nil && p.State == 'T' {
+				stopped[pid] = struct{}{}
+			}
+		}
+		return stopped
+	}
+
+	snapshot, err := r.tracker.Snapshot()
+	if err != nil {
+		r.log(err)
+		return nil
+	}
+
+	for _, p := range snapshot {
+		if p.State == 'T' {
+			stopped[p.Pid] = struct{}{}
+		}
+	}
+
+	return stopped
+}
+
+// excluded returns true if a pid should not be signaled: this process
+// (unless enabled by WithSignalSelf), pids referring to process groups
+// (0 or negative pids) and pids excluded by WithExcludePids.
+func (r *Reap) excluded(pid int) bool {
+	if pid <= 0 {
+		return true
+	}
+	if _, ok := r.exclude[pid]; ok {
+		return true
+	}
+	return pid == os.Getpid() && !r.signalSelf
+}
+
+// protected returns true if the pid should never be sent SIGKILL.
+func (r *Reap) protected(pid int) bool {
+	_, ok := r.neverKill[pid]
+	return ok
+}
+
+func (r *Reap) probe(pid int) {
+	err := kill(pid, 0)
+	if err == nil || errors.Is(err, syscall.EPERM) {
+		r.notify("%d: alive %d", r.Pid(), pid)
+	}
+}
+
+func (r *supervision) reaper(exitch <-chan struct{}, wait bool) {
+	deadline := r.deadline
+	if deadline < r.minGrace {
+		deadline = r.minGrace
+	}
+
+	t := time.NewTimer(deadline)
+	defer t.Stop()
+	tick := time.NewTicker(r.delay)
+	defer tick.Stop()
+
+	var checkpoint <-chan time.Time
+	if r.checkpoint > 0 {
+		c := time.NewTicker(r.checkpoint)
+		defer c.Stop()
+		checkpoint = c.C
+	}
+
+	sig := r.sig
+	if s, ok := r.escalationStep(0); ok {
+		sig = s
+	}
+	passes := 0
+	running := -1
+	done := r.done
+	started := time.Now()
+	first := started
+
+	// forwarded signals re-sent at each interval
+	forwarded := make(map[syscall.Signal]struct{})
+
+	unresponsive := false
+
+	escalate := func() {
+		switch {
+		case sig == 0, sig == syscall.SIGKILL:
+		case r.noEscalate:
+			if !unresponsive {
+				r.notify("%d: not escalating to SIGKILL: subprocesses not responding", r.Pid())
+				unresponsive = true
+			}
+		default:
+			r.stats.update(func(s *Stats) { s.Killed = true })
+			sig = syscall.SIGKILL
+		}
+	}
+
+	signal := func() {
+		if wait {
+			return
+		}
+		if r.maxPasses > 0 && passes >= r.maxPasses && time.Since(first) >= r.minGrace {
+			escalate()
+		}
+		stepped := false
+		if s, ok := r.escalationStep(time.Since(first)); ok && s != sig && sig != syscall.SIGKILL &&
+			(s != syscall.SIGKILL || time.Since(first) >= r.minGrace) {
+			r.event("escalating", []Field{{"signal", sysSignalName(s)}},
+				"%d: escalating: %s", r.Pid(), signalName(s))
+			if s == syscall.SIGKILL {
+				r.stats.update(func(s *Stats) { s.Killed = true })
+			}
+			sig = s
+			stepped = true
+		}
+		for s := range forwarded {
+			r.signalWith(s)
+		}
+		if passes > 0 && !stepped && !r.resends(sig) {
+			passes++
+			return
+		}
+		if sig == syscall.SIGKILL {
+			r.cgroupKill()
+			r.reaperKill()
+		}
+		n := r.signalLadder(sig, time.Since(started), time.Since(first))
+		passes++
+		if r.progress && sig != syscall.SIGKILL && n >= 0 && n < running {
+			if !t.Stop() {
+				select {
+				case <-t.C:
+				default:
+				}
+			}
+			t.Reset(deadline)
+			started = time.Now()
+		}
+		if n >= 0 {
+			running = n
+		}
+	}
+
+	signal()
+
+	// the deadline and the minimum grace period start after the first
+	// signal is sent to subprocesses
+	if !t.Stop() {
+		<-t.C
+	}
+	t.Reset(deadline)
+	tick.Reset(r.delay)
+	started = time.Now()
+	first = started
+
+	for {
+		select {
+		case <-exitch:
+			return
+		case <-t.C:
+			r.event("deadline reached", []Field{{"deadline", deadline}},
+				"%d: deadline reached: %s", r.Pid(), deadline)
+			r.stats.update(func(s *Stats) { s.DeadlineExceeded = true })
+			escalate()
+		case <-done:
+			done = nil
+			if wait {
+				r.notify("%d: supervision canceled: shutdown", r.Pid())
+				wait = false
+				signal()
+			}
+		case s := <-r.sigch:
+			switch s {
+			case sigchld, sigio, syscall.SIGPIPE, sigurg:
+			case r.forceSig:
+				if wait {
+					r.notify("%d: forced shutdown: %s", r.Pid(), s)
+					wait = false
+					signal()
+					continue
+				}
+				r.signalWith(s.(syscall.Signal))
+			default:
+				if r.doubleSignal && shutdownSignal(s) && !wait {
+					r.notify("%d: shutdown in progress: %s: escalating", r.Pid(), s)
+					escalate()
+					signal()
+					continue
+				}
+				fwd := r.translate(s)
+				r.signalWith(fwd)
+				if fwd != sig && r.resends(fwd) {
+					forwarded[fwd] = struct{}{}
+				}
+			}
+		case <-tick.C:
+			signal()
+		case <-checkpoint:
+			r.logRemaining()
+		}
+	}
+}
+
+// Orphans partitions the descendants of this process into the
+// descendants of the foreground process (including the foreground
+// process) and orphaned subprocesses adopted by this process.
+func (r *Reap) Orphans(fg int) (tree []int, orphans []int, err error) {
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, orphans = process.Partition(snapshot, r.Pid(), fg)
+	return tree, orphans, nil
+}
+
+// startReaper signals subprocesses until the returned function is
+// called.
+func (r *supervision) startReaper(wait bool) func() {
+	exitch := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		r.reaper(exitch, wait)
+	}()
+
+	return func() {
+		close(exitch)
+		<-done
+	}
+}
+
+// Reap delivers a signal to all descendants of this process.
+//
+// If the process is not a subreaper, orphaned descendants recorded while
+// the foreground process was running are signaled by pid and Reap
+// returns when they have exited.
+//
+// If a reap timeout is set and subprocesses are running after the
+// timeout, Reap returns ErrReapTimeout. Subprocesses exiting after the
+// timeout are not waited for (see ReapAvailable).
+func (r *Reap) Reap() error {
+	sv := &supervision{Reap: r}
+	return sv.reap(r.wait)
+}
+
+// reap waits for subprocesses to exit. If wait is false, subprocesses
+// are signaled.
+func (r *supervision) reap(wait bool) error {
+	select {
+	case <-r.abort:
+	default:
+	}
+
+	defer r.startReaper(wait)()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	errch := make(chan error, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if r.cgroup != "" {
+			if err := waitCgroup(r.cgroup, done); err != nil {
+				r.log(fmt.Errorf("%d: cgroup: %w", r.Pid(), err))
+			}
+		}
+		if err := r.waitGroups(done); err != nil {
+			r.log(fmt.Errorf("%d: %w", r.Pid(), err))
+		}
+		errch <- r.waitAll(done)
+	}()
+
+	var timeout <-chan time.Time
+	if r.reapTimeout > 0 {
+		t := time.NewTimer(r.reapTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case err := <-errch:
+		return err
+	case err := <-r.abort:
+		return err
+	case <-timeout:
+		return ErrReapTimeout
+	}
+}
+
+// ReapAvailable waits for exited subprocesses without blocking and
+// returns the pids of the reaped subprocesses.
+func (r *Reap) ReapAvailable() ([]int, error) {
+	reaped := make([]int, 0)
+
+	for {
+		var ws syscall.WaitStatus
+		var ru syscall.Rusage
+		pid, err := wait4(-1, &ws, wnohang, &ru)
+		switch {
+		case err == nil && pid > 0:
+			r.event("child reaped", statusFields(pid, ws),
+				"%d: reaped %d: %s", r.Pid(), pid, DescribeStatus(ws))
+			r.exitHandler(pid, ws)
+			r.rusageHandler(pid, ws, rusageOf(&ru))
+			r.hook(Event{Hook: HookChildExit, Pid: pid, Status: ws})
+			reaped = append(reaped, pid)
+		case err == nil, errors.Is(err, syscall.ECHILD):
+			return reaped, nil
+		case errors.Is(err, syscall.EINTR), errors.Is(err, syscall.EAGAIN):
+		default:
+			return reaped, err
+		}
+	}
+}
+
+// waitAll waits for all subprocesses to exit or until done is closed.
+func (r *supervision) waitAll(done <-chan struct{}) error {
+	exited := make(chan os.Signal, 1)
+	signal.Notify(exited, sigchld)
+	defer signal.Stop(exited)
+
+	// pause returns false if done is closed while waiting for a
+	// subprocess to exit
+	pause := func() bool {
+		t := time.NewTimer(trackInterval)
+		defer t.Stop()
+		select {
+		case <-done:
+			return false
+		case <-exited:
+		case <-t.C:
+		}
+		return true
+	}
+
+	for {
+		var ws syscall.WaitStatus
+		var ru syscall.Rusage
+		pid, err := wait4(-1, &ws, wnohang, &ru)
+		switch {
+		case err == nil && pid > 0:
+			usage := rusageOf(&ru)
+			r.stats.reaped(ws, usage)
+			r.event("child reaped", statusFields(pid, ws),
+				"%d: reaped %d: %s", r.Pid(), pid, DescribeStatus(ws))
+			r.exitHandler(pid, ws)
+			r.rusageHandler(pid, ws, usage)
+			r.hook(Event{Hook: HookChildExit, Pid: pid, Status: ws})
+		case err == nil:
+			if !pause() {
+				return nil
+			}
+		case errors.Is(err, syscall.EINTR), errors.Is(err, syscall.EAGAIN):
+		case errors.Is(err, syscall.ECHILD):
+			if len(r.unreachable()) == 0 {
+				return nil
+			}
+			if !pause() {
+				return nil
+			}
+		case r.waitErr(err):
+			r.log(fmt.Errorf("%d: wait4: %w", r.Pid(), err))
+		default:
+			return err
+		}
+	}
+}
+
+func (r *supervision) execv(command string, args []string, env []string) (int, error) {
+	r.hook(Event{Hook: HookPreStart})
+
+	cmd, closer, err := r.command(command, args, env, true)
+	if err != nil {
+		r.onStartErr(err)
+		return 127, err
+	}
+	defer closer()
+
+	if r.pty {
+		return r.runPty(cmd)
+	}
+
+	return r.run(cmd)
+}
+
+// command returns the command for a foreground process. If stdin is
+// false, standard input of the process is connected to /dev/null. The
+// returned function closes files opened for the process.
+func (r *Reap) command(command string, args []string, env []string, stdin bool) (*exec.Cmd, func(), error) {
+	closer := func() {}
+
+	cmd := exec.Command(command, args...)
+	if r.chroot != "" {
+		// the executable is in the new root
+		cmd = &exec.Cmd{Path: command, Args: append([]string{command}, args...)}
+	}
+	cmd.Stdin = r.stdin
+	if r.stdinOwner == StdinSupervisor && r.stdin == os.Stdin {
+		if r.pty && r.stdinFile == "" {
+			return nil, closer, ErrStdinConflict
+		}
+		// exec connects a nil stdin to /dev/null
+		cmd.Stdin = nil
+	}
+	if r.stdinFile != "" && stdin {
+		f, err := os.Open(r.stdinFile)
+		if err != nil {
+			return nil, closer, fmt.Errorf("stdin: %w", err)
+		}
+		closer = func() { f.Close() }
+		cmd.Stdin = f
+	}
+	if !stdin {
+		cmd.Stdin = nil
+	}
+	if err := r.cgroupCreate(); err != nil {
+		closer()
+		return nil, func() {}, fmt.Errorf("cgroup: %w", err)
+	}
+	cmd.Stdout = r.stdout
+	cmd.Stderr = r.stderr
+	cmd.Dir = r.dir
+	cmd.ExtraFiles = r.inheritFds
+	env = r.environ(env)
+	cmd.Env = env
+
+	if r.marker != "" {
+		cmd.Env = append(withoutEnv(env, EnvMarker), EnvMarker+"="+r.marker)
+	}
+
+	attr, err := r.commandAttr()
+	if err != nil {
+		closer()
+		return nil, func() {}, err
+	}
+	cmd.SysProcAttr = attr
+
+	return cmd, closer, nil
+}
+
+func (r *supervision) run(cmd *exec.Cmd) (int, error) {
+	if err := r.start(cmd); err != nil {
+		r.onStartErr(err)
+		return 127, err
+	}
+
+	r.cgroupEnter(cmd.Process.Pid)
+	r.writePid(cmd.Process.Pid)
+	r.startHandler(cmd.Process.Pid)
+
+	return r.waitCmd(cmd, nil)
+}
+
+// waitCmd waits for a started process to exit. winch is called when the
+// window size changes.
+func (r *supervision) waitCmd(cmd *exec.Cmd, winch func()) (int, error) {
+	waitch := make(chan error, 1)
+	go func() {
+		waitch <- cmd.Wait()
+	}()
+
+	status, err := r.waitpid(cmd.Process.Pid, waitch, winch)
+
+	if cmd.ProcessState == nil {
+		return status, err
+	}
+
+	if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok {
+		r.event("foreground exited", statusFields(cmd.Process.Pid, ws),
+			"%d: foreground %d: %s", r.Pid(), cmd.Process.Pid, DescribeStatus(ws))
+		// hooks run in the re-executed process
+		if !r.needsReexec() {
+			r.hook(Event{Hook: HookPostStop, Pid: cmd.Process.Pid, Status: ws})
+		}
+	}
+
+	return status, err
+}
+
+// waitpid waits for the foreground process to exit. The error returned
+// by the waitch channel is nil if the process exited with status 0 or
+// contains the process status (see exec.ExitError).
+func (r *supervision) waitpid(fg int, waitch <-chan error, winch func()) (int, error) {
+	var stop func()
+	var track <-chan time.Time
+
+	shutdowns := 0
+	done := r.done
+
+	r.stats.update(func(s *Stats) { s.Pid = fg })
+
+	shutdown := func(format string, a ...interface{}) {
+		if stop != nil {
+			return
+		}
+		r.notify(format, a...)
+		stop = r.startReaper(false)
+	}
+
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
+
+	if r.tracking() {
+		t := time.NewTicker(trackInterval)
+		defer t.Stop()
+		track = t.C
+	}
+
+	health, stopHealth := r.startHealthCheck()
+	defer stopHealth()
+
+	failures := 0
+
+	for {
+		select {
+		case <-track:
+			r.track()
+		case err := <-health:
+			if err == nil {
+				failures = 0
+				continue
+			}
+			failures++
+			r.log(event{
+				error:  fmt.Errorf("%d: health check failed (%d/%d): %w", r.Pid(), failures, r.health.retries, err),
+				msg:    "health check failed",
+				fields: []Field{{"pid", fg}, {"failures", failures}},
+			})
+			if failures < r.health.retries || stop != nil {
+				continue
+			}
+			failures = 0
+			if r.unhealthy(fg) {
+				shutdown("%d: health check failed: shutdown", r.Pid())
+			}
+		case <-done:
+			done = nil
+			r.stopping = true
+			shutdown("%d: supervision canceled: shutdown", r.Pid())
+		case sig := <-r.sigch:
+			switch sig {
+			case sigchld:
+				if r.trigger == TriggerChildExit {
+					shutdown("%d: subprocess exited: shutdown", r.Pid())
+				}
+			case sigio, syscall.SIGPIPE, sigurg:
+			case r.forceSig:
+				r.stopping = true
+				shutdown("%d: forced shutdown: %s", r.Pid(), sig)
+			case r.restartSig:
+				r.restart(fg)
+			case sigwinch:
+				if winch == nil {
+					r.forward(fg, sigwinch)
+					continue
+				}
+				winch()
+			default:
+				if shutdownSignal(sig) {
+					r.stopping = true
+				}
+				if r.doubleSignal && shutdownSignal(sig) {
+					shutdowns++
+					if shutdowns > 1 {
+						r.kill9(sig)
+						continue
+					}
+				}
+				r.forward(fg, r.translate(sig))
+			}
+		case err := <-waitch:
+			return waitStatus(err)
+		}
+	}
+}
+
+// waitStatus converts the error returned by waiting for a process to an
+// exit status. The error is nil if the process exited with status 0 or
+// contains the process status (see exec.ExitError).
+func waitStatus(err error) (int, error) {
+	var exitError interface {
+		Sys() interface{}
+	}
+
+	if err == nil {
+		return 0, nil
+	}
+
+	if !errors.As(err, &exitError) {
+		return 128, err
+	}
+
+	ws, ok := exitError.Sys().(syscall.WaitStatus)
+	if !ok {
+		return 128, err
+	}
+
+	if ws.Signaled() {
+		return 128 + int(ws.Signal()), nil
+	}
+
+	return ws.ExitStatus(), nil
 }
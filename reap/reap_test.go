@@ -1,10 +1,27 @@
+//go:build linux
+
 package reap_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/syslog"
+	"net"
 	"os"
+	osexec "os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
@@ -156,3 +173,3328 @@ func TestSubReaper(t *testing.T) {
 		t.Errorf("not a subreaper")
 	}
 }
+
+func TestSuperviseForceShutdown(t *testing.T) {
+	r := reap.New(
+		reap.WithForceShutdownSignal(int(syscall.SIGUSR1)),
+		reap.WithDeadline(time.Duration(1)*time.Second),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		"trap '' TERM USR1; (exec -a goreaptest-force sleep 120) & (exec -a goreaptest-force sleep 120) & exec -a goreaptest-force sleep 120",
+	}
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	}()
+
+	start := time.Now()
+
+	if err := exec(r, cmd, 1); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("forced shutdown exceeded deadline: %s", d)
+	}
+}
+
+func TestReapWaitErrorHandler(t *testing.T) {
+	errs := []error{syscall.EAGAIN, syscall.EIO}
+
+	wait4 := func(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+		if len(errs) > 0 {
+			err := errs[0]
+			errs = errs[1:]
+			return 0, err
+		}
+		return syscall.Wait4(pid, ws, options, rusage)
+	}
+
+	defer reap.SetWait4(wait4)()
+
+	r := reap.New(
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	if err := r.Reap(); !errors.Is(err, syscall.EIO) {
+		t.Errorf("error = %v, want %v", err, syscall.EIO)
+	}
+
+	errs = []error{syscall.EAGAIN, syscall.EIO}
+	var handled []error
+
+	r = reap.New(
+		reap.WithWaitErrorHandler(func(err error) bool {
+			handled = append(handled, err)
+			return true
+		}),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	if err := r.Reap(); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if len(handled) != 1 || !errors.Is(handled[0], syscall.EIO) {
+		t.Errorf("handled = %v, want [%v]", handled, syscall.EIO)
+	}
+}
+
+func TestReexecGuard(t *testing.T) {
+	r := reap.New(reap.WithReexec(true))
+
+	if r.NeedsReexec() {
+		t.Errorf("subreaper: re-exec enabled")
+	}
+
+	defer reap.SetSubReaper(func() bool { return false })()
+
+	if !r.NeedsReexec() {
+		t.Errorf("not subreaper: re-exec disabled")
+	}
+
+	t.Setenv(reap.ReexecEnv, "1")
+
+	if r.NeedsReexec() {
+		t.Errorf("%s: re-exec loop", reap.ReexecEnv)
+	}
+}
+
+func TestNewPidNamespace(t *testing.T) {
+	r := reap.New(reap.WithNewPidNamespace(true))
+
+	if !r.NeedsReexec() {
+		t.Errorf("subreaper: re-exec disabled")
+	}
+
+	t.Setenv(reap.ReexecEnv, "1")
+
+	if r.NeedsReexec() {
+		t.Errorf("%s: re-exec loop", reap.ReexecEnv)
+	}
+}
+
+func TestHostname(t *testing.T) {
+	r := reap.New(reap.WithHostname("goreaptest"))
+
+	if !r.NeedsReexec() {
+		t.Errorf("hostname: re-exec disabled")
+	}
+}
+
+func TestSuperviseSignalZero(t *testing.T) {
+	var mu sync.Mutex
+	alive := 0
+
+	r := reap.New(
+		reap.WithSignal(0),
+		reap.WithDelay(100*time.Millisecond),
+		reap.WithDeadline(500*time.Millisecond),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+			if strings.Contains(err.Error(), "alive") {
+				mu.Lock()
+				alive++
+				mu.Unlock()
+			}
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		"(exec -a goreaptest-probe sleep 2) &",
+	}
+
+	start := time.Now()
+
+	if err := exec(r, cmd, 1); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if d := time.Since(start); d < 2*time.Second {
+		t.Errorf("subprocess terminated: %s", d)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if alive == 0 {
+		t.Errorf("subprocess not reported as alive")
+	}
+}
+
+func TestSuperviseNested(t *testing.T) {
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 0, 0, 0, 0); err != nil {
+		t.Fatalf("prctl: %v", err)
+	}
+	defer func() {
+		if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+			t.Fatalf("prctl: %v", err)
+		}
+	}()
+
+	r := reap.New(
+		reap.WithDelay(100*time.Millisecond),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		"(exec -a goreaptest-nested sleep 120) & sleep 1",
+	}
+
+	if _, err := r.Supervise(cmd, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	ps := process.New()
+	for i := 0; i < 20; i++ {
+		if !running(t, ps, "goreaptest-nested") {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	t.Errorf("orphaned subprocess running")
+}
+
+// mkfifo creates a named pipe for synchronizing with a shell: a
+// subprocess signals it is ready, e.g., after installing a signal
+// handler, by writing to the pipe (echo > "$1") and the shell waits by
+// reading from the pipe (read < "$1").
+func mkfifo(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Fatalf("%v", err)
+	}
+	return path
+}
+
+func running(t *testing.T, ps process.Process, name string) bool {
+	pids, err := ps.Snapshot()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, p := range pids {
+		if p.State == 'Z' {
+			continue
+		}
+		b, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", p.Pid))
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(string(b), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDescribeStatus(t *testing.T) {
+	tests := []struct {
+		ws   syscall.WaitStatus
+		want string
+	}{
+		{0, "exited: 0"},
+		{3 << 8, "exited: 3"},
+		{syscall.WaitStatus(syscall.SIGKILL), "killed by SIGKILL (9)"},
+		{syscall.WaitStatus(syscall.SIGSEGV) | 0x80, "killed by SIGSEGV (11), dumped core"},
+		{syscall.WaitStatus(syscall.SIGTSTP)<<8 | 0x7f, "stopped by SIGTSTP (20)"},
+		{0xffff, "continued"},
+	}
+
+	for _, tt := range tests {
+		if got := reap.DescribeStatus(tt.ws); got != tt.want {
+			t.Errorf("%#x: got %q, want %q", uint32(tt.ws), got, tt.want)
+		}
+	}
+}
+
+func TestSuperviseCombinedOutput(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := reap.New(
+		reap.WithCombinedOutput(&buf),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	cmd := []string{
+		"sh", "-c",
+		"echo stdout1; echo stderr1 >&2; echo stdout2; echo stderr2 >&2",
+	}
+
+	if err := exec(r, cmd, 1); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if want := "stdout1\nstderr1\nstdout2\nstderr2\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSuperviseReapTimeout(t *testing.T) {
+	r := reap.New(
+		reap.WithWait(true),
+		reap.WithReapTimeout(500*time.Millisecond),
+		reap.WithReapTimeoutStatus(99),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		"(exec -a goreaptest-timeout sleep 120) &",
+	}
+
+	status, err := r.Supervise(cmd, os.Environ())
+	if !errors.Is(err, reap.ErrReapTimeout) {
+		t.Errorf("error = %v, want %v", err, reap.ErrReapTimeout)
+	}
+	if status != 99 {
+		t.Errorf("status = %d, want 99", status)
+	}
+
+	if err := reap.New().Reap(); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+func TestReapTimeout(t *testing.T) {
+	var returned atomic.Bool
+
+	// subprocesses never exit
+	defer reap.SetWait4(func(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+		if returned.Load() {
+			t.Errorf("wait4 called after reap timeout")
+		}
+		return 0, nil
+	})()
+
+	r := reap.New(
+		reap.WithProcess(&fakeProcess{pid: os.Getpid(), children: []int{1001}}),
+		reap.WithWait(true),
+		reap.WithReapTimeout(100*time.Millisecond),
+	)
+
+	err := r.Reap()
+	returned.Store(true)
+
+	if !errors.Is(err, reap.ErrReapTimeout) {
+		t.Errorf("error = %v, want %v", err, reap.ErrReapTimeout)
+	}
+}
+
+func TestSupervisePTY(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := reap.New(
+		reap.WithPTY(true),
+		reap.WithCombinedOutput(&buf),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	cmd := []string{
+		"sh", "-c",
+		"if [ -t 0 ] && [ -t 1 ] && [ -t 2 ]; then echo tty; else echo notty; fi",
+	}
+
+	if err := exec(r, cmd, 1); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if got := strings.TrimSpace(buf.String()); got != "tty" {
+		t.Errorf("output = %q, want %q", got, "tty")
+	}
+}
+
+func TestSuperviseMaxSignalPasses(t *testing.T) {
+	var mu sync.Mutex
+	signals := make(map[string]int)
+
+	r := reap.New(
+		reap.WithMaxSignalPasses(3),
+		reap.WithDelay(100*time.Millisecond),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+			f := strings.Fields(err.Error())
+			if len(f) == 4 && f[1] == "kill" {
+				mu.Lock()
+				signals[f[2]]++
+				mu.Unlock()
+			}
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		"trap '' TERM; (exec -a goreaptest-passes sleep 120) &",
+	}
+
+	if err := exec(r, cmd, 1); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if signals["15"] != 3 {
+		t.Errorf("SIGTERM sent %d times, want 3", signals["15"])
+	}
+	if signals["9"] == 0 {
+		t.Errorf("SIGKILL not sent")
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "log")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer conn.Close()
+
+	defer reap.SetSyslogAddr("unixgram", addr)()
+
+	errs := []error{syscall.EIO}
+	defer reap.SetWait4(func(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+		if len(errs) > 0 {
+			err := errs[0]
+			errs = errs[1:]
+			return 0, err
+		}
+		return syscall.Wait4(pid, ws, options, rusage)
+	})()
+
+	r := reap.New(
+		reap.WithSyslog("goreaptest", syslog.LOG_DAEMON),
+		reap.WithWaitErrorHandler(func(error) bool { return true }),
+	)
+
+	if _, err := r.Supervise([]string{"true"}, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	priority := make(map[string]bool)
+	buf := make([]byte, 1024)
+
+	for !priority["<28>"] || !priority["<30>"] {
+		if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("%v", err)
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("syslog: received %v: %v", priority, err)
+		}
+		msg := string(buf[:n])
+		t.Log(msg)
+		if !strings.Contains(msg, "goreaptest") {
+			t.Errorf("syslog: missing tag: %s", msg)
+		}
+		priority[msg[:4]] = true
+	}
+}
+
+func TestSuperviseProgressDeadline(t *testing.T) {
+	var mu sync.Mutex
+	killed := false
+
+	r := reap.New(
+		reap.WithProgressDeadline(true),
+		reap.WithDelay(100*time.Millisecond),
+		reap.WithDeadline(600*time.Millisecond),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+			if strings.Contains(err.Error(), "kill 9 ") {
+				mu.Lock()
+				killed = true
+				mu.Unlock()
+			}
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		`trap '' TERM
+for n in 0.4 0.8 1.2 1.6; do
+  (exec -a goreaptest-progress sleep $n) &
+done`,
+	}
+
+	start := time.Now()
+
+	if err := exec(r, cmd, 1); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if d := time.Since(start); d < 1500*time.Millisecond {
+		t.Errorf("subprocesses terminated early: %s", d)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if killed {
+		t.Errorf("SIGKILL sent while subprocesses were exiting")
+	}
+}
+
+type fakeProcess struct {
+	pid      int
+	children []int
+	comm     map[int]string
+	ppid     map[int]int
+}
+
+func (p *fakeProcess) Pid() int {
+	return p.pid
+}
+
+func (p *fakeProcess) Children() ([]int, error) {
+	return p.children, nil
+}
+
+func (p *fakeProcess) Snapshot() ([]process.PID, error) {
+	pids := make([]process.PID, 0, len(p.children))
+	for _, pid := range p.children {
+		ppid, ok := p.ppid[pid]
+		if !ok {
+			ppid = p.pid
+		}
+		pids = append(pids, process.PID{Pid: pid, PPid: ppid, Comm: p.comm[pid]})
+	}
+	return pids, nil
+}
+
+func TestWithProcess(t *testing.T) {
+	var mu sync.Mutex
+	signaled := make(map[int]syscall.Signal)
+
+	defer reap.SetKill(func(pid int, sig syscall.Signal) error {
+		mu.Lock()
+		defer mu.Unlock()
+		signaled[pid] = sig
+		return nil
+	})()
+
+	r := reap.New(
+		reap.WithProcess(&fakeProcess{pid: os.Getpid(), children: []int{1001, 1002, 1003}}),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	if err := r.Reap(); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := map[int]syscall.Signal{
+		1001: syscall.SIGTERM,
+		1002: syscall.SIGTERM,
+		1003: syscall.SIGTERM,
+	}
+
+	if !reflect.DeepEqual(signaled, want) {
+		t.Errorf("signaled = %v, want %v", signaled, want)
+	}
+}
+
+func TestSuperviseEnvMarker(t *testing.T) {
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 0, 0, 0, 0); err != nil {
+		t.Fatalf("prctl: %v", err)
+	}
+	defer func() {
+		if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+			t.Fatalf("prctl: %v", err)
+		}
+	}()
+
+	r := reap.New(
+		reap.WithEnvMarker(true),
+		reap.WithDelay(100*time.Millisecond),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	if r.Marker() == "" {
+		t.Fatalf("marker not set")
+	}
+
+	cmd := []string{
+		"bash", "-c",
+		"setsid bash -c '(exec -a goreaptest-marker sleep 120) &'",
+	}
+
+	if _, err := r.Supervise(cmd, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	ps := process.New()
+	for i := 0; i < 20; i++ {
+		if !running(t, ps, "goreaptest-marker") {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	t.Errorf("marked subprocess running")
+}
+
+func TestReapAvailable(t *testing.T) {
+	r := reap.New(
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	reaped, err := r.ReapAvailable()
+	if err != nil || len(reaped) != 0 {
+		t.Errorf("no subprocesses: reaped = %v, error = %v", reaped, err)
+	}
+
+	pids := make([]int, 0)
+	for i := 0; i < 3; i++ {
+		cmd := osexec.Command("true")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("%v", err)
+		}
+		pids = append(pids, cmd.Process.Pid)
+	}
+
+	sort.Ints(pids)
+	reaped = make([]int, 0)
+
+	for i := 0; i < 20 && len(reaped) < len(pids); i++ {
+		time.Sleep(50 * time.Millisecond)
+		p, err := r.ReapAvailable()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		reaped = append(reaped, p...)
+	}
+
+	sort.Ints(reaped)
+
+	if !reflect.DeepEqual(reaped, pids) {
+		t.Errorf("reaped = %v, want %v", reaped, pids)
+	}
+}
+
+func TestSignalSelf(t *testing.T) {
+	var mu sync.Mutex
+	signaled := make(map[int]bool)
+
+	defer reap.SetKill(func(pid int, sig syscall.Signal) error {
+		mu.Lock()
+		defer mu.Unlock()
+		signaled[pid] = true
+		return nil
+	})()
+
+	p := &fakeProcess{pid: os.Getpid(), children: []int{0, os.Getpid(), 1001}}
+
+	if err := reap.New(reap.WithProcess(p)).Reap(); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	if want := map[int]bool{1001: true}; !reflect.DeepEqual(signaled, want) {
+		t.Errorf("signaled = %v, want %v", signaled, want)
+	}
+	signaled = make(map[int]bool)
+	mu.Unlock()
+
+	if err := reap.New(reap.WithProcess(p), reap.WithSignalSelf(true)).Reap(); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := map[int]bool{os.Getpid(): true, 1001: true}; !reflect.DeepEqual(signaled, want) {
+		t.Errorf("signal self: signaled = %v, want %v", signaled, want)
+	}
+}
+
+func TestSupervisePanic(t *testing.T) {
+	r := reap.New(
+		reap.WithLog(func(err error) {
+			if strings.Contains(err.Error(), "kill") {
+				panic("log handler")
+			}
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		"(exec -a goreaptest-panic sleep 120) & (exec -a goreaptest-panic sleep 120) &",
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p != "log handler" {
+				t.Errorf("panic = %v, want %q", p, "log handler")
+			}
+		}()
+		_, _ = r.Supervise(cmd, os.Environ())
+	}()
+
+	children, err := process.New().Children()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(children) != 0 {
+		t.Errorf("subprocesses running: %v", children)
+	}
+}
+
+func TestSuperviseSigpipe(t *testing.T) {
+	for _, tt := range []struct {
+		ignore bool
+		status int
+	}{
+		{false, 128 + int(syscall.SIGPIPE)},
+		{true, 1},
+		// the disposition is restored after the run
+		{false, 128 + int(syscall.SIGPIPE)},
+	} {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		go func() {
+			buf := make([]byte, 4096)
+			_, _ = pr.Read(buf)
+			pr.Close()
+		}()
+
+		r := reap.New(
+			reap.WithIgnoreSigpipe(tt.ignore),
+			reap.WithCombinedOutput(pw),
+			reap.WithLog(func(err error) {
+				t.Log(err)
+			}),
+		)
+
+		status, err := r.Supervise([]string{"yes"}, os.Environ())
+		pw.Close()
+
+		if err != nil {
+			t.Errorf("ignore=%v: %v", tt.ignore, err)
+		}
+		if status != tt.status {
+			t.Errorf("ignore=%v: status = %d, want %d", tt.ignore, status, tt.status)
+		}
+
+		if signal.Ignored(syscall.SIGPIPE) {
+			t.Errorf("ignore=%v: SIGPIPE ignored after run", tt.ignore)
+		}
+	}
+}
+
+func TestNeverKill(t *testing.T) {
+	for _, tt := range []struct {
+		opt  reap.Option
+		want map[int]bool
+	}{
+		{reap.WithNeverKill([]int{1001}), map[int]bool{1002: true}},
+		{reap.WithNoEscalate(true), map[int]bool{}},
+	} {
+		var mu sync.Mutex
+		killed := make(map[int]bool)
+
+		restoreKill := reap.SetKill(func(pid int, sig syscall.Signal) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if sig == syscall.SIGKILL {
+				killed[pid] = true
+			}
+			return nil
+		})
+
+		// wait past the deadline
+		waited := false
+		restoreWait4 := reap.SetWait4(func(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+			if !waited {
+				waited = true
+				time.Sleep(500 * time.Millisecond)
+				return 0, syscall.EINTR
+			}
+			return syscall.Wait4(pid, ws, options, rusage)
+		})
+
+		r := reap.New(
+			tt.opt,
+			reap.WithProcess(&fakeProcess{pid: os.Getpid(), children: []int{1001, 1002}}),
+			reap.WithDeadline(100*time.Millisecond),
+			reap.WithDelay(50*time.Millisecond),
+			reap.WithLog(func(err error) {
+				t.Log(err)
+			}),
+		)
+
+		if err := r.Reap(); err != nil {
+			t.Errorf("%v", err)
+		}
+
+		restoreWait4()
+		restoreKill()
+
+		mu.Lock()
+		if !reflect.DeepEqual(killed, tt.want) {
+			t.Errorf("SIGKILL = %v, want %v", killed, tt.want)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestExclude(t *testing.T) {
+	fake := &fakeProcess{
+		pid:      os.Getpid(),
+		children: []int{1001, 1002, 1003},
+		comm:     map[int]string{1001: "sh", 1002: "logger", 1003: "sleep"},
+	}
+
+	for _, tt := range []struct {
+		opt  reap.Option
+		want []int
+	}{
+		{reap.WithExcludePids([]int{1001}), []int{1002, 1003}},
+		{reap.WithExcludeFilter(func(p process.PID) bool {
+			return p.Comm == "logger"
+		}), []int{1001, 1003}},
+	} {
+		r := reap.New(tt.opt, reap.WithProcess(fake))
+
+		targets, err := r.Targets()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		sort.Ints(targets)
+		if !reflect.DeepEqual(targets, tt.want) {
+			t.Errorf("targets = %v, want %v", targets, tt.want)
+		}
+	}
+}
+
+func TestShutdownTriggerFailure(t *testing.T) {
+	for _, tt := range []struct {
+		exit   int
+		killed bool
+	}{
+		{0, false},
+		{3, true},
+	} {
+		var mu sync.Mutex
+		killed := false
+
+		r := reap.New(
+			reap.WithShutdownTrigger(reap.TriggerFailure),
+			reap.WithLog(func(err error) {
+				t.Log(err)
+				if strings.Contains(err.Error(), "kill 15 ") {
+					mu.Lock()
+					killed = true
+					mu.Unlock()
+				}
+			}),
+		)
+
+		cmd := []string{
+			"bash", "-c",
+			fmt.Sprintf("(exec -a goreaptest-trigger sleep 1) & exit %d", tt.exit),
+		}
+
+		status, err := r.Supervise(cmd, os.Environ())
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+		if status != tt.exit {
+			t.Errorf("status = %d, want %d", status, tt.exit)
+		}
+
+		mu.Lock()
+		if killed != tt.killed {
+			t.Errorf("exit %d: subprocesses signaled = %v, want %v", tt.exit, killed, tt.killed)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	want := &reap.State{
+		Foreground:  reap.Proc{Pid: 100, StartTime: 12345},
+		Descendants: []reap.Proc{{Pid: 101, StartTime: 12346}, {Pid: 102, StartTime: 12347}},
+	}
+
+	if err := reap.WriteState(path, want); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	got, err := reap.ReadState(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSuperviseStaleState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	// A saved process which is no longer running: the start time does
+	// not match the current process.
+	st := &reap.State{
+		Foreground: reap.Proc{Pid: os.Getpid(), StartTime: 1},
+	}
+
+	if err := reap.WriteState(path, st); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	r := reap.New(
+		reap.WithStateFile(path),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	status, err := r.Supervise([]string{"sh", "-c", "exit 3"}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if status != 3 {
+		t.Errorf("status = %d, want 3", status)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("state file not removed: %v", err)
+	}
+}
+
+func TestSuperviseStats(t *testing.T) {
+	r := reap.New(
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		`(exec -a goreaptest-stats sleep 120) & (exec -a goreaptest-stats sleep 120) & (trap 'exit 4' TERM; echo > "$1"; read <> "$2") & read < "$1"; exit 3`,
+		"bash", mkfifo(t, "ready"), mkfifo(t, "wait"),
+	}
+
+	status, err := r.Supervise(cmd, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if status != 3 {
+		t.Errorf("status = %d, want 3", status)
+	}
+
+	st := r.Stats()
+	if st.Reaped != 3 {
+		t.Errorf("reaped = %d, want 3", st.Reaped)
+	}
+
+	re := regexp.MustCompile(`^child pid \d+ exited 3; reaped 3 orphans in [0-9.]+m?s \(deadline not hit\)$`)
+	if !re.MatchString(st.String()) {
+		t.Errorf("unexpected summary: %q", st.String())
+	}
+
+	if want := "exited 4: 1, killed by SIGTERM: 2"; st.Statuses() != want {
+		t.Errorf("statuses = %q, want %q", st.Statuses(), want)
+	}
+}
+
+func TestSignalDuplicatePids(t *testing.T) {
+	var mu sync.Mutex
+	signaled := make(map[int]int)
+
+	defer reap.SetKill(func(pid int, sig syscall.Signal) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if sig == syscall.SIGTERM {
+			signaled[pid]++
+		}
+		return nil
+	})()
+
+	fake := &fakeProcess{pid: os.Getpid(), children: []int{1001, 1002, 1001, 1003, 1002}}
+
+	r := reap.New(
+		reap.WithProcess(fake),
+		reap.WithDelay(time.Hour),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	if err := r.Reap(); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := map[int]int{1001: 1, 1002: 1, 1003: 1}
+	if !reflect.DeepEqual(signaled, want) {
+		t.Errorf("signaled = %v, want %v", signaled, want)
+	}
+
+	if want := []int{1001, 1002, 1001, 1003, 1002}; !reflect.DeepEqual(fake.children, want) {
+		t.Errorf("children modified: %v", fake.children)
+	}
+}
+
+func TestSuperviseOnStartError(t *testing.T) {
+	var startErr error
+
+	r := reap.New(
+		reap.WithOnStartError(func(err error) {
+			startErr = err
+		}),
+	)
+
+	status, err := r.Supervise([]string{"goreaptest-nonexistent-command"}, os.Environ())
+	if err == nil {
+		t.Errorf("expected error")
+	}
+	if status != 127 {
+		t.Errorf("status = %d, want 127", status)
+	}
+
+	if !errors.Is(startErr, osexec.ErrNotFound) {
+		t.Errorf("start error = %v, want %v", startErr, osexec.ErrNotFound)
+	}
+
+	startErr = nil
+
+	if _, err := r.Supervise([]string{"sh", "-c", "exit 3"}, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if startErr != nil {
+		t.Errorf("start error called for a non-zero exit: %v", startErr)
+	}
+}
+
+func TestSuperviseShutdownBudget(t *testing.T) {
+	var mu sync.Mutex
+	var escalated time.Time
+
+	r := reap.New(
+		reap.WithShutdownBudget(2*time.Second, 0.25),
+		reap.WithDelay(50*time.Millisecond),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+			if strings.Contains(err.Error(), "kill 9 ") {
+				mu.Lock()
+				if escalated.IsZero() {
+					escalated = time.Now()
+				}
+				mu.Unlock()
+			}
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		`(trap '' TERM; echo > "$1"; exec -a goreaptest-budget sleep 120) & read < "$1"`,
+		"bash", mkfifo(t, "ready"),
+	}
+
+	start := time.Now()
+
+	if _, err := r.Supervise(cmd, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if escalated.IsZero() {
+		t.Fatalf("SIGKILL not sent")
+	}
+
+	// 25% of 2s after the foreground process exits
+	if d := escalated.Sub(start); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+		t.Errorf("escalated after %s, want 500ms", d)
+	}
+}
+
+// testCgroup returns the path of a cgroup v2 cgroup that can be created
+// by the test. The test is skipped if cgroup v2 is not mounted or
+// cgroup.kill is not supported.
+func testCgroup(t *testing.T) string {
+	t.Helper()
+
+	b, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		t.Skipf("%v", err)
+	}
+
+	mnt := ""
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && fields[i+1] == "cgroup2" {
+				mnt = fields[4]
+			}
+		}
+	}
+	if mnt == "" {
+		t.Skip("cgroup v2 not mounted")
+	}
+
+	b, err = os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		t.Skipf("%v", err)
+	}
+
+	parent := ""
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			parent = filepath.Join(mnt, strings.TrimPrefix(line, "0::"))
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(parent, "cgroup.procs")); err != nil {
+		t.Skipf("cgroup v2: %v", err)
+	}
+
+	path := filepath.Join(parent, fmt.Sprintf("goreaptest-%d", os.Getpid()))
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Skipf("cgroup v2: %v", err)
+	}
+	defer os.Remove(path)
+
+	if _, err := os.Stat(filepath.Join(path, "cgroup.kill")); err != nil {
+		t.Skipf("cgroup v2: %v", err)
+	}
+
+	return path
+}
+
+func TestSuperviseCgroup(t *testing.T) {
+	path := testCgroup(t)
+
+	r := reap.New(
+		reap.WithCgroup(path),
+		reap.WithDeadline(200*time.Millisecond),
+		reap.WithDelay(50*time.Millisecond),
+	)
+
+	start := time.Now()
+
+	// the subprocess ignores SIGTERM and is killed using cgroup.kill
+	status, err := r.Supervise([]string{
+		"bash", "-c",
+		`(trap '' TERM; echo > "$1"; exec -a goreaptest-cgroup sleep 120) & read < "$1"; exit 3`,
+		"bash", mkfifo(t, "ready"),
+	}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if status != 3 {
+		t.Errorf("status = %d, want 3", status)
+	}
+
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("cgroup kill exceeded deadline: %s", d)
+	}
+
+	if !r.Stats().Killed {
+		t.Errorf("subprocesses not killed")
+	}
+
+	if running(t, process.New(), "goreaptest-cgroup") {
+		t.Errorf("subprocesses running after cgroup was killed")
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("cgroup not removed: %v", err)
+	}
+}
+
+func TestWaitCgroup(t *testing.T) {
+	path := testCgroup(t)
+
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.Remove(path)
+
+	cmd := osexec.Command("sleep", "0.3")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(path, "cgroup.procs"),
+		[]byte(strconv.Itoa(cmd.Process.Pid)), 0o644); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	start := time.Now()
+
+	// the process has exited but has not been waited for
+	if err := reap.WaitCgroup(path); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if d := time.Since(start); d < 200*time.Millisecond || d > 5*time.Second {
+		t.Errorf("cgroup drained after %s", d)
+	}
+
+	_ = cmd.Wait()
+}
+
+func TestReapResendSignals(t *testing.T) {
+	var mu sync.Mutex
+	signaled := make(map[syscall.Signal]int)
+
+	defer reap.SetKill(func(pid int, sig syscall.Signal) error {
+		mu.Lock()
+		defer mu.Unlock()
+		signaled[sig]++
+		return nil
+	})()
+
+	done := make(chan struct{})
+	defer reap.SetWait4(func(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+		<-done
+		return 0, syscall.ECHILD
+	})()
+
+	r := reap.New(
+		reap.WithProcess(&fakeProcess{pid: os.Getpid(), children: []int{1001}}),
+		reap.WithDelay(20*time.Millisecond),
+		reap.WithResendSignals(map[syscall.Signal]bool{
+			syscall.SIGTERM: false,
+			syscall.SIGHUP:  false,
+			syscall.SIGUSR1: true,
+		}),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		time.Sleep(50 * time.Millisecond)
+		_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+		time.Sleep(200 * time.Millisecond)
+		close(done)
+	}()
+
+	if err := r.Reap(); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if n := signaled[syscall.SIGTERM]; n != 1 {
+		t.Errorf("SIGTERM sent %d times, want 1", n)
+	}
+	if n := signaled[syscall.SIGHUP]; n != 1 {
+		t.Errorf("SIGHUP sent %d times, want 1", n)
+	}
+	if n := signaled[syscall.SIGUSR1]; n < 2 {
+		t.Errorf("SIGUSR1 sent %d times, want re-sent", n)
+	}
+}
+
+func TestSuperviseDescendantEvents(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+
+	re := regexp.MustCompile(`descendants: (\d+) -> (\d+)$`)
+	ready := mkfifo(t, "ready")
+
+	// the shell waits for the subprocess to be counted before killing
+	// it and for the decrease to be counted before exiting
+	r := reap.New(
+		reap.WithDescendantEvents(true),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+			if !strings.Contains(err.Error(), ": descendants: ") {
+				return
+			}
+			mu.Lock()
+			events = append(events, err.Error())
+			mu.Unlock()
+			m := re.FindStringSubmatch(err.Error())
+			if m == nil {
+				return
+			}
+			from, _ := strconv.Atoi(m[1])
+			to, _ := strconv.Atoi(m[2])
+			if (from < 2 && to == 2) || (from == 2 && to == 1) {
+				go func() { _ = os.WriteFile(ready, []byte("\n"), 0) }()
+			}
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		`(exec -a goreaptest-events sleep 120) & p=$!; read < "$1"; kill $p; read < "$1"`,
+		"bash", ready,
+	}
+
+	if _, err := r.Supervise(cmd, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var grew, shrank bool
+	for _, event := range events {
+		m := re.FindStringSubmatch(event)
+		if m == nil {
+			t.Errorf("unexpected event: %s", event)
+			continue
+		}
+		from, _ := strconv.Atoi(m[1])
+		to, _ := strconv.Atoi(m[2])
+		switch {
+		case to > from:
+			grew = true
+		case to < from && grew:
+			shrank = true
+		}
+	}
+
+	if !grew || !shrank {
+		t.Errorf("events = %v, want increase and decrease", events)
+	}
+}
+
+func TestReapInvalidSignal(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	logged := 0
+
+	defer reap.SetKill(func(pid int, sig syscall.Signal) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return syscall.EINVAL
+	})()
+
+	// subprocesses never exit: reaping must be aborted
+	defer reap.SetWait4(func(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+		return 0, nil
+	})()
+
+	r := reap.New(
+		reap.WithProcess(&fakeProcess{pid: os.Getpid(), children: []int{1001, 1002, 1003}}),
+		reap.WithSignal(99),
+		reap.WithDelay(10*time.Millisecond),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+			if errors.Is(err, reap.ErrInvalidSignal) {
+				mu.Lock()
+				logged++
+				mu.Unlock()
+			}
+		}),
+	)
+
+	err := r.Reap()
+	if !errors.Is(err, reap.ErrInvalidSignal) {
+		t.Errorf("error = %v, want %v", err, reap.ErrInvalidSignal)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if calls != 1 {
+		t.Errorf("kill called %d times, want 1", calls)
+	}
+	if logged != 1 {
+		t.Errorf("error logged %d times, want 1", logged)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	cmd := osexec.Command("bash", "-c",
+		"(exec -a goreaptest-targets sleep 120) & (exec -a goreaptest-targets bash -c 'sleep 120 & wait') & wait")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	defer func() {
+		_ = reap.New(reap.WithSignal(int(syscall.SIGKILL)), reap.WithDelay(10*time.Millisecond)).Reap()
+	}()
+
+	pid := cmd.Process.Pid
+
+	ps := process.New(
+		process.WithPid(pid),
+		process.WithSnapshot(process.SnapshotPs),
+	)
+
+	r := reap.New(reap.WithProcess(ps))
+
+	var got []int
+	for i := 0; i < 50; i++ {
+		var err error
+		got, err = r.Targets()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if len(got) == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	snapshot, err := ps.Snapshot()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	want := process.Descendants(snapshot, pid)
+
+	sort.Ints(got)
+	sort.Ints(want)
+
+	if len(got) != 3 || !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %v, want %v", got, want)
+	}
+}
+
+func TestSuperviseStdinFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte("goreap stdin\n"), 0o600); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var buf bytes.Buffer
+
+	r := reap.New(
+		reap.WithStdinFile(path),
+		reap.WithCombinedOutput(&buf),
+	)
+
+	status, err := r.Supervise([]string{"cat"}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+	if want := "goreap stdin\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	r = reap.New(
+		reap.WithStdinFile(filepath.Join(t.TempDir(), "nonexistent")),
+	)
+
+	status, err = r.Supervise([]string{"cat"}, os.Environ())
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("error = %v, want %v", err, os.ErrNotExist)
+	}
+	if status != 127 {
+		t.Errorf("status = %d, want 127", status)
+	}
+}
+
+func TestSuperviseReapPredicate(t *testing.T) {
+	r := reap.New(
+		reap.WithReapTimeout(500*time.Millisecond),
+		reap.WithReapPredicate(func(p process.PID) bool {
+			return p.Comm == "sleep"
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		`(exec -a goreaptest-predicate sleep 120) & p=$!; (exec -a goreaptest-predicate tail -f /dev/null) & q=$!
+		until grep -qx sleep /proc/$p/comm && grep -qx tail /proc/$q/comm; do :; done`,
+	}
+
+	if _, err := r.Supervise(cmd, os.Environ()); !errors.Is(err, reap.ErrReapTimeout) {
+		t.Errorf("error = %v, want %v", err, reap.ErrReapTimeout)
+	}
+
+	ps := process.New(process.WithSnapshot(process.SnapshotPs))
+	snapshot, err := ps.Snapshot()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	comm := make(map[string]int)
+	for _, pid := range process.Descendants(snapshot, os.Getpid()) {
+		for _, p := range snapshot {
+			if p.Pid == pid && p.State != 'Z' {
+				comm[p.Comm]++
+			}
+		}
+	}
+
+	if comm["sleep"] != 0 {
+		t.Errorf("process matching predicate is running")
+	}
+	if comm["tail"] != 1 {
+		t.Errorf("process not matching predicate was signaled")
+	}
+
+	if err := reap.New(reap.WithSignal(int(syscall.SIGKILL))).Reap(); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+func TestReapLadders(t *testing.T) {
+	var mu sync.Mutex
+	signaled := make(map[int][]syscall.Signal)
+
+	defer reap.SetKill(func(pid int, sig syscall.Signal) error {
+		mu.Lock()
+		defer mu.Unlock()
+		s := signaled[pid]
+		if len(s) == 0 || s[len(s)-1] != sig {
+			signaled[pid] = append(s, sig)
+		}
+		return nil
+	})()
+
+	done := make(chan struct{})
+	defer reap.SetWait4(func(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+		<-done
+		return 0, syscall.ECHILD
+	})()
+
+	r := reap.New(
+		reap.WithProcess(&fakeProcess{
+			pid:      os.Getpid(),
+			children: []int{1001, 1002},
+			comm:     map[int]string{1001: "sleep", 1002: "nginx"},
+		}),
+		reap.WithDeadline(400*time.Millisecond),
+		reap.WithDelay(20*time.Millisecond),
+		reap.WithLadders(reap.Ladder{
+			Pattern: "ngin*",
+			Signals: []syscall.Signal{syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGKILL},
+		}),
+	)
+
+	go func() {
+		time.Sleep(600 * time.Millisecond)
+		close(done)
+	}()
+
+	if err := r.Reap(); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := map[int][]syscall.Signal{
+		1001: {syscall.SIGTERM, syscall.SIGKILL},
+		1002: {syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGKILL},
+	}
+
+	if !reflect.DeepEqual(signaled, want) {
+		t.Errorf("signaled = %v, want %v", signaled, want)
+	}
+}
+
+func TestSupervisePidFd(t *testing.T) {
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_CLOEXEC); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	rd := os.NewFile(uintptr(p[0]), "pidfd")
+	defer rd.Close()
+
+	var buf bytes.Buffer
+
+	cmd := []string{"sh", "-c", "echo $$ >&2"}
+
+	r := reap.New(reap.WithPidFd(p[1]), reap.WithCombinedOutput(&buf))
+
+	if _, err := r.Supervise(cmd, os.Environ()); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	b, err := io.ReadAll(rd)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if string(b) != buf.String() {
+		t.Errorf("pid = %q, want %q", b, buf.String())
+	}
+}
+
+func TestSuperviseStdinOwner(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := reap.New(
+		reap.WithStdinOwner(reap.StdinSupervisor),
+		reap.WithCombinedOutput(&buf),
+	)
+
+	// stdin is /dev/null: cat exits without blocking
+	status, err := r.Supervise([]string{"sh", "-c", "cat; echo done"}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+	if want := "done\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	r = reap.New(
+		reap.WithStdinOwner(reap.StdinSupervisor),
+		reap.WithPTY(true),
+	)
+
+	status, err = r.Supervise([]string{"cat"}, os.Environ())
+	if !errors.Is(err, reap.ErrStdinConflict) {
+		t.Errorf("error = %v, want %v", err, reap.ErrStdinConflict)
+	}
+	if status != 127 {
+		t.Errorf("status = %d, want 127", status)
+	}
+}
+
+func TestSuperviseCheckpointLog(t *testing.T) {
+	var mu sync.Mutex
+	var checkpoints []string
+
+	r := reap.New(
+		reap.WithDeadline(time.Second),
+		reap.WithCheckpointLog(100*time.Millisecond),
+		reap.WithLog(func(err error) {
+			if strings.Contains(err.Error(), ": remaining: ") {
+				mu.Lock()
+				checkpoints = append(checkpoints, err.Error())
+				mu.Unlock()
+			}
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		`(trap '' TERM; echo > "$1"; exec -a goreaptest-checkpoint sleep 120) & read < "$1"`,
+		"bash", mkfifo(t, "ready"),
+	}
+
+	if _, err := r.Supervise(cmd, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(checkpoints) < 2 {
+		t.Fatalf("checkpoints = %d, want periodic checkpoints", len(checkpoints))
+	}
+
+	re := regexp.MustCompile(`: remaining: \d+ \(sleep\)$`)
+	for _, c := range checkpoints {
+		if !re.MatchString(c) {
+			t.Errorf("unexpected checkpoint: %s", c)
+		}
+	}
+}
+
+func TestSuperviseCheckpointLogDefunct(t *testing.T) {
+	var mu sync.Mutex
+	var checkpoints []string
+
+	r := reap.New(
+		reap.WithDeadline(500*time.Millisecond),
+		reap.WithCheckpointLog(100*time.Millisecond),
+		reap.WithLog(func(err error) {
+			if strings.Contains(err.Error(), ": remaining: ") {
+				mu.Lock()
+				checkpoints = append(checkpoints, err.Error())
+				mu.Unlock()
+			}
+		}),
+	)
+
+	// the parent of the zombie does not wait for subprocesses: the
+	// zombie exits after the parent has executed sleep
+	cmd := []string{
+		"bash", "-c",
+		`(trap '' TERM; sh -c 'until grep -q "(sleep)" /proc/$PPID/stat; do :; done; echo > "$1"' sh "$1" & exec -a goreaptest-defunct sleep 120) & read < "$1"`,
+		"bash", mkfifo(t, "ready"),
+	}
+
+	if _, err := r.Supervise(cmd, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	re := regexp.MustCompile(`: remaining: .*\d+ \(sh, defunct: parent \d+\)`)
+	for _, c := range checkpoints {
+		if re.MatchString(c) {
+			return
+		}
+	}
+
+	t.Errorf("defunct process not logged: %v", checkpoints)
+}
+
+func TestSuperviseMinGrace(t *testing.T) {
+	var mu sync.Mutex
+	var terminated, killed time.Time
+
+	r := reap.New(
+		reap.WithDeadline(10*time.Millisecond),
+		reap.WithMinGrace(500*time.Millisecond),
+		reap.WithDelay(20*time.Millisecond),
+		reap.WithLog(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case strings.Contains(err.Error(), "kill 15 ") && terminated.IsZero():
+				terminated = time.Now()
+			case strings.Contains(err.Error(), "kill 9 ") && killed.IsZero():
+				killed = time.Now()
+			}
+		}),
+	)
+
+	cmd := []string{
+		"bash", "-c",
+		`(trap '' TERM; echo > "$1"; exec -a goreaptest-grace sleep 120) & read < "$1"`,
+		"bash", mkfifo(t, "ready"),
+	}
+
+	if _, err := r.Supervise(cmd, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if terminated.IsZero() || killed.IsZero() {
+		t.Fatalf("signals not sent: SIGTERM=%v, SIGKILL=%v", terminated, killed)
+	}
+
+	if d := killed.Sub(terminated); d < 500*time.Millisecond {
+		t.Errorf("SIGKILL sent %s after SIGTERM, want at least 500ms", d)
+	}
+}
+
+func TestReapLaddersRenamedProcess(t *testing.T) {
+	// goreaptest-cmdline: command name changed, matched by the command
+	// line
+	// goreaptest-exe: command name and command line changed, matched by
+	// the executable
+	cmd := osexec.Command("bash", "-c", `
+(printf goreaptest-cmdline > /proc/self/comm; while :; do sleep 0.1; done) &
+(exec -a goreaptest-exe bash -c 'printf goreaptest-exe > /proc/self/comm; while :; do sleep 0.1; done') &
+wait`)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	defer func() {
+		_ = reap.New(reap.WithSignal(int(syscall.SIGKILL)), reap.WithDelay(10*time.Millisecond)).Reap()
+	}()
+
+	ps := process.New(
+		process.WithPid(cmd.Process.Pid),
+		process.WithSnapshot(process.SnapshotPs),
+	)
+
+	// wait for the subshells to change the command name
+	renamed := make(map[string]int)
+	for i := 0; i < 100 && len(renamed) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		snapshot, err := ps.Snapshot()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		for _, p := range snapshot {
+			if strings.HasPrefix(p.Comm, "goreaptest-") {
+				renamed[p.Comm] = p.Pid
+			}
+		}
+	}
+
+	byCmdline, byExe := renamed["goreaptest-cmdl"], renamed["goreaptest-exe"]
+	if byCmdline == 0 || byExe == 0 {
+		t.Fatalf("command name not changed: %v", renamed)
+	}
+
+	for _, tt := range []struct {
+		matchExe bool
+		want     map[int]syscall.Signal
+	}{
+		{false, map[int]syscall.Signal{byCmdline: syscall.SIGUSR2, byExe: syscall.SIGTERM}},
+		{true, map[int]syscall.Signal{byCmdline: syscall.SIGUSR2, byExe: syscall.SIGUSR2}},
+	} {
+		var mu sync.Mutex
+		signaled := make(map[int]syscall.Signal)
+
+		restoreKill := reap.SetKill(func(pid int, sig syscall.Signal) error {
+			mu.Lock()
+			defer mu.Unlock()
+			signaled[pid] = sig
+			return nil
+		})
+		restoreWait4 := reap.SetWait4(func(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+			time.Sleep(50 * time.Millisecond)
+			return 0, syscall.ECHILD
+		})
+
+		r := reap.New(
+			reap.WithProcess(ps),
+			reap.WithMatchExe(tt.matchExe),
+			reap.WithLadders(reap.Ladder{
+				Pattern: "bash",
+				Signals: []syscall.Signal{syscall.SIGUSR2},
+			}),
+		)
+
+		err := r.Reap()
+
+		restoreWait4()
+		restoreKill()
+
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		mu.Lock()
+		for pid, want := range tt.want {
+			if sig := signaled[pid]; sig != want {
+				t.Errorf("match exe=%t: %d signaled with %v, want %v", tt.matchExe, pid, sig, want)
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+func TestSuperviseDoubleSignal(t *testing.T) {
+	r := reap.New(
+		reap.WithDoubleSignal(true),
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			time.Sleep(200 * time.Millisecond)
+			_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+		}
+	}()
+
+	start := time.Now()
+
+	// the foreground process ignores SIGINT
+	status, err := r.Supervise([]string{"bash", "-c", "trap '' INT; sleep 5"}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if want := 128 + int(syscall.SIGKILL); status != want {
+		t.Errorf("status = %d, want %d", status, want)
+	}
+
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("second signal did not accelerate termination: %s", d)
+	}
+}
+
+func TestSuperviseForcedKillExitCode(t *testing.T) {
+	for _, tt := range []struct {
+		cmd    string
+		status int
+	}{
+		{`(trap '' TERM; echo > "$1"; exec -a goreaptest-killed sleep 120) & read < "$1"; exit 3`, 99},
+		{"(exec -a goreaptest-killed sleep 120) & exit 3", 3},
+	} {
+		r := reap.New(
+			reap.WithDeadline(200*time.Millisecond),
+			reap.WithDelay(50*time.Millisecond),
+			reap.WithForcedKillExitCode(99),
+		)
+
+		status, err := r.Supervise([]string{"bash", "-c", tt.cmd, "bash", mkfifo(t, "ready")}, os.Environ())
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+		if status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.cmd, status, tt.status)
+		}
+	}
+}
+
+func TestTargetsMaxDepth(t *testing.T) {
+	fake := &fakeProcess{
+		pid:      os.Getpid(),
+		children: []int{1001, 1002, 1003, 1004},
+		ppid:     map[int]int{1002: 1001, 1003: 1002, 1004: 1003},
+	}
+
+	for _, tt := range []struct {
+		depth int
+		want  []int
+	}{
+		{1, []int{1001}},
+		{2, []int{1001, 1002}},
+		{0, []int{1001, 1002, 1003, 1004}},
+	} {
+		r := reap.New(
+			reap.WithProcess(fake),
+			reap.WithMaxDepth(tt.depth),
+		)
+
+		got, err := r.Targets()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("depth %d: targets = %v, want %v", tt.depth, got, tt.want)
+		}
+	}
+}
+
+// TestHelperSupervise runs Supervise in a subprocess for tests killing
+// the supervisor.
+func TestHelperSupervise(t *testing.T) {
+	if os.Getenv("GOREAP_TEST_HELPER") == "" {
+		t.Skip("helper process")
+	}
+
+	status, _ := reap.New().Supervise(
+		[]string{"bash", "-c", "exec -a goreaptest-pdeathsig sleep 120"},
+		os.Environ(),
+	)
+	os.Exit(status)
+}
+
+func TestSuperviseKilledAtStartup(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		cmd := osexec.Command(os.Args[0], "-test.run=^TestHelperSupervise$")
+		cmd.Env = append(os.Environ(), "GOREAP_TEST_HELPER=1")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		// kill the supervisor at varying points during startup
+		time.Sleep(time.Duration(i) * 5 * time.Millisecond)
+
+		if err := cmd.Process.Kill(); err != nil {
+			t.Errorf("%v", err)
+		}
+		_ = cmd.Wait()
+	}
+
+	ps := process.New()
+
+	for i := 0; i < 50; i++ {
+		if !running(t, ps, "goreaptest-pdeathsig") {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Errorf("foreground process running after supervisor was killed")
+
+	_ = reap.New(reap.WithSignal(int(syscall.SIGKILL))).Reap()
+}
+
+func TestPidfdKill(t *testing.T) {
+	fd, err := unix.PidfdOpen(os.Getpid(), 0)
+	if err != nil {
+		t.Skipf("pidfd not supported: %v", err)
+	}
+	_ = unix.Close(fd)
+
+	cmd := osexec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	pid := cmd.Process.Pid
+
+	if err := reap.PidfdKill(pid, syscall.SIGTERM); err != nil {
+		t.Fatalf("PidfdKill: %v", err)
+	}
+
+	_ = cmd.Wait()
+
+	ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() || ws.Signal() != syscall.SIGTERM {
+		t.Errorf("unexpected exit: %v", cmd.ProcessState)
+	}
+
+	// the process has exited and been reaped
+	if err := reap.PidfdKill(pid, syscall.SIGTERM); !errors.Is(err, syscall.ESRCH) {
+		t.Errorf("expected ESRCH: %v", err)
+	}
+}
+
+func TestSuperviseContext(t *testing.T) {
+	r := reap.New(
+		reap.WithLog(func(err error) {
+			t.Log(err)
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	status, err := r.SuperviseContext(ctx, []string{
+		"bash", "-c",
+		"(exec -a goreaptest-context sleep 120) & exec -a goreaptest-context sleep 120",
+	}, os.Environ())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded: %v", err)
+	}
+
+	if want := 128 + int(syscall.SIGTERM); status != want {
+		t.Errorf("status = %d, want %d", status, want)
+	}
+
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("canceled supervision exceeded deadline: %s", d)
+	}
+
+	if running(t, process.New(), "goreaptest-context") {
+		t.Errorf("subprocesses running after supervision was canceled")
+	}
+
+	status, err = r.SuperviseContext(ctx, []string{"true"}, os.Environ())
+	if !errors.Is(err, context.DeadlineExceeded) || status != 111 {
+		t.Errorf("done context: status = %d: %v", status, err)
+	}
+}
+
+func TestSuperviseExitHandler(t *testing.T) {
+	var mu sync.Mutex
+	statuses := make([]string, 0)
+
+	r := reap.New(
+		reap.WithExitHandler(func(pid int, status syscall.WaitStatus) {
+			mu.Lock()
+			defer mu.Unlock()
+			statuses = append(statuses, reap.DescribeStatus(status))
+		}),
+	)
+
+	_, err := r.Supervise([]string{
+		"bash", "-c",
+		`(trap 'exit 3' TERM; echo > "$1"; read <> "$2") & (exec -a goreaptest-exithandler sleep 120) & read < "$1"`,
+		"bash", mkfifo(t, "ready"), mkfifo(t, "wait"),
+	}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	sort.Strings(statuses)
+
+	want := []string{"exited: 3", "killed by SIGTERM (15)"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %q, want %q", statuses, want)
+	}
+}
+
+func TestSuperviseRestart(t *testing.T) {
+	for _, tt := range []struct {
+		policy   reap.RestartPolicy
+		cmd      string
+		status   int
+		restarts int
+	}{
+		{reap.RestartPolicy{Mode: reap.RestartNever}, "exit 1", 1, 0},
+		{reap.RestartPolicy{Mode: reap.RestartOnFailure, MaxRetries: 2}, "exit 1", 1, 2},
+		{reap.RestartPolicy{Mode: reap.RestartOnFailure, MaxRetries: 2}, "exit 0", 0, 0},
+		{reap.RestartPolicy{Mode: reap.RestartAlways, MaxRetries: 1, Backoff: 10 * time.Millisecond}, "exit 0", 0, 1},
+	} {
+		var mu sync.Mutex
+		killed := 0
+
+		r := reap.New(
+			reap.WithRestart(tt.policy),
+			reap.WithExitHandler(func(pid int, status syscall.WaitStatus) {
+				mu.Lock()
+				defer mu.Unlock()
+				if status.Signaled() {
+					killed++
+				}
+			}),
+		)
+
+		// each run leaves a subprocess reaped before the next run
+		status, err := r.Supervise([]string{
+			"bash", "-c",
+			"(exec -a goreaptest-restart sleep 120) & " + tt.cmd,
+		}, os.Environ())
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		if status != tt.status {
+			t.Errorf("%+v: %s: status = %d, want %d", tt.policy, tt.cmd, status, tt.status)
+		}
+
+		st := r.Stats()
+
+		if st.Restarts != tt.restarts {
+			t.Errorf("%+v: %s: restarts = %d, want %d", tt.policy, tt.cmd, st.Restarts, tt.restarts)
+		}
+
+		// stats accumulate across restarts
+		if n := st.Signaled[syscall.SIGTERM]; st.Reaped != tt.restarts+1 || n != tt.restarts+1 {
+			t.Errorf("%+v: %s: stats: reaped = %d, killed by SIGTERM = %d, want %d",
+				tt.policy, tt.cmd, st.Reaped, n, tt.restarts+1)
+		}
+
+		mu.Lock()
+		if killed != tt.restarts+1 {
+			t.Errorf("%+v: %s: subprocesses reaped = %d, want %d", tt.policy, tt.cmd, killed, tt.restarts+1)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestSuperviseRestartShutdown(t *testing.T) {
+	r := reap.New(
+		reap.WithRestart(reap.RestartPolicy{Mode: reap.RestartAlways}),
+	)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	}()
+
+	status, err := r.Supervise([]string{"sleep", "5"}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if want := 128 + int(syscall.SIGTERM); status != want {
+		t.Errorf("status = %d, want %d", status, want)
+	}
+
+	if n := r.Stats().Restarts; n != 0 {
+		t.Errorf("restarted after shutdown signal: %d", n)
+	}
+}
+
+// reusedProcess simulates a subprocess exiting and the pid being reused
+// by an unrelated process after the process table is read: the parent
+// of the process is init when the process is re-read.
+type reusedProcess struct {
+	fakeProcess
+}
+
+func (p *reusedProcess) Stat(pid int) (process.PID, error) {
+	return process.PID{Pid: pid, PPid: 1}, nil
+}
+
+func TestSignalPidfdReused(t *testing.T) {
+	fd, err := unix.PidfdOpen(os.Getpid(), 0)
+	if err != nil {
+		t.Skipf("pidfd not supported: %v", err)
+	}
+	_ = unix.Close(fd)
+
+	for _, tt := range []struct {
+		reused bool
+		n      int
+		sig    syscall.Signal
+	}{
+		{false, 1, syscall.SIGTERM},
+		{true, 0, syscall.SIGKILL},
+	} {
+		cmd := osexec.Command("sleep", "60")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		fake := fakeProcess{pid: os.Getpid(), children: []int{cmd.Process.Pid}}
+
+		var ps process.Process = &fake
+		if tt.reused {
+			ps = &reusedProcess{fakeProcess: fake}
+		}
+
+		r := reap.New(reap.WithProcess(ps))
+
+		if n := r.SignalWith(syscall.SIGTERM); n != tt.n {
+			t.Errorf("reused=%t: signaled %d, want %d", tt.reused, n, tt.n)
+		}
+
+		if tt.reused {
+			_ = cmd.Process.Kill()
+		}
+		_ = cmd.Wait()
+
+		ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+		if !ok || !ws.Signaled() || ws.Signal() != tt.sig {
+			t.Errorf("reused=%t: unexpected exit: %v", tt.reused, cmd.ProcessState)
+		}
+	}
+}
+
+func TestSuperviseSignalMap(t *testing.T) {
+	r := reap.New(
+		reap.WithSignalMap(map[os.Signal]os.Signal{
+			syscall.SIGINT: syscall.SIGTERM,
+		}),
+	)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}()
+
+	status, err := r.Supervise([]string{
+		"bash", "-c",
+		"trap 'exit 7' TERM; trap 'exit 2' INT; sleep 5 & wait",
+	}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if status != 7 {
+		t.Errorf("status = %d, want 7", status)
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, tt := range []struct {
+		s   string
+		sig syscall.Signal
+		err error
+	}{
+		{"TERM", syscall.SIGTERM, nil},
+		{"SIGTERM", syscall.SIGTERM, nil},
+		{"int", syscall.SIGINT, nil},
+		{"9", syscall.SIGKILL, nil},
+		{"0", 0, nil},
+		{"FOO", 0, reap.ErrInvalidSignal},
+		{"65", 0, reap.ErrInvalidSignal},
+	} {
+		sig, err := reap.ParseSignal(tt.s)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.s, err, tt.err)
+		}
+		if sig != tt.sig {
+			t.Errorf("%s: signal = %d, want %d", tt.s, sig, tt.sig)
+		}
+	}
+}
+
+func TestSuperviseForward(t *testing.T) {
+	for _, tt := range []struct {
+		forward reap.Forward
+		want    []string
+	}{
+		{reap.ForwardDescendants, []string{"killed by SIGUSR1 (10)", "killed by SIGUSR1 (10)"}},
+		{reap.ForwardChild, []string{"killed by SIGTERM (15)", "killed by SIGTERM (15)"}},
+		{reap.ForwardGroup, []string{"killed by SIGTERM (15)", "killed by SIGUSR1 (10)"}},
+	} {
+		var mu sync.Mutex
+		statuses := make([]string, 0)
+
+		r := reap.New(
+			reap.WithForward(tt.forward),
+			reap.WithProcess(process.New(process.WithSnapshot(process.SnapshotPs))),
+			reap.WithExitHandler(func(pid int, status syscall.WaitStatus) {
+				mu.Lock()
+				defer mu.Unlock()
+				statuses = append(statuses, reap.DescribeStatus(status))
+			}),
+		)
+
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+		}()
+
+		// the subprocesses are orphaned: the second subprocess runs in
+		// a new process group
+		status, err := r.Supervise([]string{
+			"bash", "-c",
+			"trap 'exit 5' USR1; (sleep 120 &); set -m; (sleep 120 &); while :; do :; done",
+		}, os.Environ())
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		if status != 5 {
+			t.Errorf("%d: status = %d, want 5", tt.forward, status)
+		}
+
+		mu.Lock()
+		sort.Strings(statuses)
+		if !reflect.DeepEqual(statuses, tt.want) {
+			t.Errorf("%d: statuses = %q, want %q", tt.forward, statuses, tt.want)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestSuperviseEscalation(t *testing.T) {
+	var mu sync.Mutex
+	statuses := make([]string, 0)
+
+	r := reap.New(
+		reap.WithEscalation([]reap.Step{
+			{Signal: syscall.SIGTERM, Timeout: 300 * time.Millisecond},
+			{Signal: syscall.SIGUSR1, Timeout: 300 * time.Millisecond},
+			{Signal: syscall.SIGKILL},
+		}),
+		reap.WithDelay(50*time.Millisecond),
+		reap.WithExitHandler(func(pid int, status syscall.WaitStatus) {
+			mu.Lock()
+			defer mu.Unlock()
+			statuses = append(statuses, reap.DescribeStatus(status))
+		}),
+	)
+
+	status, err := r.Supervise([]string{
+		"bash", "-c",
+		`(trap '' TERM; echo > "$1"; exec -a goreaptest-escalation sleep 120) & read < "$1"; (trap '' TERM USR1; echo > "$1"; exec -a goreaptest-escalation sleep 120) & read < "$1"`,
+		"bash", mkfifo(t, "ready"),
+	}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+
+	stats := r.Stats()
+
+	if !stats.Killed {
+		t.Errorf("subprocesses not killed")
+	}
+
+	if stats.Duration < 600*time.Millisecond || stats.Duration > 5*time.Second {
+		t.Errorf("escalation completed in %s", stats.Duration)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	sort.Strings(statuses)
+
+	want := []string{"killed by SIGKILL (9)", "killed by SIGUSR1 (10)"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %q, want %q", statuses, want)
+	}
+}
+
+func TestSuperviseStdio(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	r := reap.New(
+		reap.WithStdin(strings.NewReader("input")),
+		reap.WithStdout(&stdout),
+		reap.WithStderr(&stderr),
+	)
+
+	status, err := r.Supervise([]string{"sh", "-c", "cat; echo error >&2"}, os.Environ())
+	if err != nil || status != 0 {
+		t.Errorf("status = %d: %v", status, err)
+	}
+
+	if stdout.String() != "input" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "input")
+	}
+
+	if stderr.String() != "error\n" {
+		t.Errorf("stderr = %q, want %q", stderr.String(), "error\n")
+	}
+
+	// standard input, output and error connected to /dev/null
+	r = reap.New(
+		reap.WithStdin(nil),
+		reap.WithStdout(nil),
+		reap.WithStderr(nil),
+	)
+
+	status, err = r.Supervise([]string{"sh", "-c", "test -z \"$(cat)\" && echo output && echo error >&2"}, os.Environ())
+	if err != nil || status != 0 {
+		t.Errorf("null: status = %d: %v", status, err)
+	}
+}
+
+func TestSupervisePTYTerminalModes(t *testing.T) {
+	master, slave, err := reap.OpenPty()
+	if err != nil {
+		t.Skipf("pty: %v", err)
+	}
+	defer master.Close()
+	defer slave.Close()
+
+	termios, err := unix.IoctlGetTermios(int(slave.Fd()), unix.TCGETS)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	termios.Cc[unix.VERASE] = 'x'
+	if err := unix.IoctlSetTermios(int(slave.Fd()), unix.TCSETS, termios); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// standard input of the supervisor is a terminal
+	stdin := os.Stdin
+	os.Stdin = slave
+	defer func() {
+		os.Stdin = stdin
+	}()
+
+	var buf bytes.Buffer
+
+	r := reap.New(
+		reap.WithPTY(true),
+		reap.WithStdin(nil),
+		reap.WithStdout(&buf),
+	)
+
+	if _, err := r.Supervise([]string{"stty", "-a"}, os.Environ()); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if !strings.Contains(buf.String(), "erase = x;") {
+		t.Errorf("terminal modes not copied: %q", buf.String())
+	}
+}
+
+func TestSuperviseAll(t *testing.T) {
+	for _, tt := range []struct {
+		policy   reap.ExitPolicy
+		argvs    [][]string
+		status   int
+		restarts int
+		elapsed  time.Duration
+	}{
+		{reap.ExitStopAll, [][]string{{"sh", "-c", "exit 3"}, {"sleep", "120"}}, 3, 0, 0},
+		{reap.ExitContinue, [][]string{{"sh", "-c", "exit 3"}, {"sh", "-c", "sleep 0.3; exit 4"}}, 3, 0, 300 * time.Millisecond},
+		{reap.ExitRestart, [][]string{{"sh", "-c", "exit 5"}, {"sleep", "120"}}, 5, 2, 0},
+	} {
+		r := reap.New(
+			reap.WithExitPolicy(tt.policy),
+			reap.WithRestart(reap.RestartPolicy{MaxRetries: 2, Backoff: 10 * time.Millisecond}),
+			reap.WithLog(func(err error) {
+				t.Log(err)
+			}),
+		)
+
+		start := time.Now()
+
+		status, err := r.SuperviseAll(tt.argvs, os.Environ())
+		if err != nil {
+			t.Errorf("policy %d: %v", tt.policy, err)
+		}
+
+		if elapsed := time.Since(start); elapsed < tt.elapsed || elapsed > 10*time.Second {
+			t.Errorf("policy %d: elapsed = %s", tt.policy, elapsed)
+		}
+
+		if status != tt.status {
+			t.Errorf("policy %d: status = %d, want %d", tt.policy, status, tt.status)
+		}
+
+		if n := r.Stats().Restarts; n != tt.restarts {
+			t.Errorf("policy %d: restarts = %d, want %d", tt.policy, n, tt.restarts)
+		}
+	}
+
+	// supervision state is not shared between runs
+	r := reap.New(
+		reap.WithExitPolicy(reap.ExitRestart),
+		reap.WithRestart(reap.RestartPolicy{MaxRetries: 1, Backoff: 10 * time.Millisecond}),
+	)
+
+	for i := 0; i < 2; i++ {
+		status, err := r.SuperviseAll([][]string{{"sh", "-c", "exit 5"}}, os.Environ())
+		if err != nil || status != 5 {
+			t.Errorf("run %d: status = %d, error = %v", i, status, err)
+		}
+
+		if n := r.Stats().Restarts; n != 1 {
+			t.Errorf("run %d: restarts = %d, want 1", i, n)
+		}
+	}
+
+	if _, err := reap.New().SuperviseAll(nil, os.Environ()); !errors.Is(err, reap.ErrNoCommand) {
+		t.Errorf("error = %v, want %v", err, reap.ErrNoCommand)
+	}
+}
+
+func TestSuperviseLogger(t *testing.T) {
+	var mu sync.Mutex
+	events := make(map[string]reap.LogEvent)
+
+	r := reap.New(
+		reap.WithLogger(reap.LoggerFunc(func(ev reap.LogEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events[ev.Msg] = ev
+		})),
+	)
+
+	status, err := r.Supervise([]string{
+		"bash", "-c",
+		"(exec -a goreaptest-logger sleep 120) & exit 3",
+	}, os.Environ())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if status != 3 {
+		t.Errorf("status = %d, want 3", status)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	field := func(ev reap.LogEvent, key string) interface{} {
+		for _, f := range ev.Fields {
+			if f.Key == key {
+				return f.Value
+			}
+		}
+		return nil
+	}
+
+	for _, tt := range []struct {
+		msg   string
+		key   string
+		value interface{}
+	}{
+		{"foreground exited", "status", 3},
+		{"signal sent", "signal", "SIGTERM"},
+		{"child reaped", "signal", "SIGTERM"},
+	} {
+		ev, ok := events[tt.msg]
+		if !ok {
+			t.Errorf("%s: event not logged", tt.msg)
+			continue
+		}
+		if ev.Level != reap.LevelInfo {
+			t.Errorf("%s: level = %s, want %s", tt.msg, ev.Level, reap.LevelInfo)
+		}
+		if v := field(ev, tt.key); v != tt.value {
+			t.Errorf("%s: %s = %v, want %v", tt.msg, tt.key, v, tt.value)
+		}
+		if _, ok := field(ev, "pid").(int); !ok {
+			t.Errorf("%s: pid not set: %+v", tt.msg, ev)
+		}
+	}
+}
+
+func TestNewWithError(t *testing.T) {
+	for _, tt := range []struct {
+		opt reap.Option
+		err error
+	}{
+		{reap.WithDelay(10 * time.Millisecond), nil},
+		{reap.WithDelay(-1), reap.ErrInvalidOption},
+		{reap.WithReapTimeout(-time.Second), reap.ErrInvalidOption},
+		{reap.WithSignal(65), reap.ErrInvalidOption},
+		{reap.WithForceShutdownSignal(-1), reap.ErrInvalidOption},
+		{reap.WithReapTimeoutStatus(256), reap.ErrInvalidOption},
+		{reap.WithRestart(reap.RestartPolicy{MaxRetries: -1}), reap.ErrInvalidOption},
+		{reap.WithEscalation([]reap.Step{{Signal: 99}}), reap.ErrInvalidOption},
+		{reap.WithSignalMap(map[os.Signal]os.Signal{syscall.SIGINT: syscall.Signal(100)}), reap.ErrInvalidOption},
+		{reap.WithHealthCheck([]string{"true"}, 0, reap.ActionExit), reap.ErrInvalidOption},
+		{reap.WithHealthRetries(0), reap.ErrInvalidOption},
+		{reap.WithExitStatusOnSignal(256), reap.ErrInvalidOption},
+		{reap.WithSuccessStatuses([]int{0, -1}), reap.ErrInvalidOption},
+		{reap.WithRlimit(syscall.RLIMIT_NOFILE, 2048, 1024), reap.ErrInvalidOption},
+		{reap.WithOOMScoreAdj(-1001, 0), reap.ErrInvalidOption},
+		{reap.WithNamespaces(reap.NamespaceIPC << 1), reap.ErrInvalidOption},
+		{reap.WithDropCapabilities([]string{"goreaptest"}), reap.ErrInvalidOption},
+	} {
+		r, err := reap.NewWithError(tt.opt)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("error = %v, want %v", err, tt.err)
+		}
+		if (r == nil) != (tt.err != nil) {
+			t.Errorf("%v: reap = %v", err, r)
+		}
+	}
+
+	_, err := reap.NewWithError(
+		reap.WithStdinOwner(reap.StdinSupervisor),
+		reap.WithPTY(true),
+	)
+	if !errors.Is(err, reap.ErrStdinConflict) {
+		t.Errorf("error = %v, want %v", err, reap.ErrStdinConflict)
+	}
+}
+
+func TestSuperviseStartHandler(t *testing.T) {
+	var pids []int
+
+	r := reap.New(
+		reap.WithRestart(reap.RestartPolicy{Mode: reap.RestartAlways, MaxRetries: 1}),
+		reap.WithStartHandler(func(pid int) {
+			pids = append(pids, pid)
+		}),
+	)
+
+	if _, err := r.Supervise([]string{"true"}, os.Environ()); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(pids) != 2 || pids[0] == pids[1] {
+		t.Errorf("started = %v", pids)
+	}
+}
+
+func TestSuperviseHook(t *testing.T) {
+	var mu sync.Mutex
+	events := make([]string, 0)
+
+	hook := func(ev reap.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		desc := ev.Hook.String()
+		if ev.Pid > 0 {
+			desc += ": " + reap.DescribeStatus(ev.Status)
+		}
+		events = append(events, desc)
+	}
+
+	r := reap.New(
+		reap.WithHook(reap.HookPreStart, hook),
+		reap.WithHook(reap.HookPostStop, hook),
+		reap.WithHook(reap.HookChildExit, hook),
+	)
+
+	status, err := r.Supervise([]string{"sh", "-c", "sleep 120 & exit 3"}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if status != 3 {
+		t.Errorf("status = %d, want 3", status)
+	}
+
+	want := []string{
+		"pre-start",
+		"post-stop: exited: 3",
+		"child-exit: killed by SIGTERM (15)",
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
+
+func TestSuperviseRusage(t *testing.T) {
+	var mu sync.Mutex
+	usage := make(map[int]reap.Rusage)
+
+	r := reap.New(
+		reap.WithRusageHandler(func(pid int, status syscall.WaitStatus, ru reap.Rusage) {
+			mu.Lock()
+			defer mu.Unlock()
+			usage[pid] = ru
+		}),
+	)
+
+	// the orphaned subprocess ignores SIGTERM and uses CPU time for up
+	// to a second
+	status, err := r.Supervise([]string{
+		"bash", "-c",
+		"(trap '' TERM; while [ $SECONDS -lt 1 ]; do :; done) & exit 0",
+	}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(usage) != 1 {
+		t.Fatalf("rusage = %v", usage)
+	}
+
+	for pid, ru := range usage {
+		if ru.User+ru.System == 0 || ru.MaxRSS == 0 {
+			t.Errorf("%d: rusage = %+v", pid, ru)
+		}
+	}
+
+	st := r.Stats()
+	if st.UserTime+st.SystemTime == 0 || st.MaxRSS == 0 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestSuperviseInheritFds(t *testing.T) {
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer rd.Close()
+
+	r := reap.New(reap.WithInheritFds([]*os.File{wr}))
+
+	status, err := r.Supervise([]string{"sh", "-c", "echo fd3 >&3"}, os.Environ())
+	wr.Close()
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+
+	b, err := io.ReadAll(rd)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if string(b) != "fd3\n" {
+		t.Errorf("output = %q, want %q", b, "fd3\n")
+	}
+}
+
+func TestSuperviseUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	r := reap.New(
+		reap.WithUser(65534, 65534, []int{65534}),
+	)
+
+	status, err := r.Supervise([]string{
+		"sh", "-c",
+		`test "$(id -u):$(id -g):$(id -G)" = "65534:65534:65534"`,
+	}, os.Environ())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+}
+
+func TestSuperviseEnv(t *testing.T) {
+	for _, tt := range []struct {
+		opts []reap.Option
+		want string
+	}{
+		{nil, "A=1 B=2"},
+		{[]reap.Option{reap.WithEnv([]string{"B=3", "C=4"})}, "A=1 B=3 C=4"},
+		{[]reap.Option{reap.WithUnsetenv([]string{"A"})}, "B=2"},
+		{[]reap.Option{reap.WithClearenv(true), reap.WithEnv([]string{"C=4"})}, "C=4"},
+		{[]reap.Option{reap.WithClearenv(true)}, ""},
+	} {
+		var stdout bytes.Buffer
+
+		r := reap.New(append(tt.opts, reap.WithStdout(&stdout))...)
+
+		status, err := r.Supervise([]string{"/usr/bin/env"}, []string{"A=1", "B=2"})
+		if err != nil || status != 0 {
+			t.Fatalf("status = %d: %v", status, err)
+		}
+
+		if got := strings.Join(strings.Fields(stdout.String()), " "); got != tt.want {
+			t.Errorf("env = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestSuperviseDirUmask(t *testing.T) {
+	dir := t.TempDir()
+
+	var stdout bytes.Buffer
+
+	r := reap.New(
+		reap.WithDir(dir),
+		reap.WithUmask(0o027),
+		reap.WithStdout(&stdout),
+	)
+
+	status, err := r.Supervise([]string{"sh", "-c", "pwd; umask"}, os.Environ())
+	if err != nil || status != 0 {
+		t.Fatalf("status = %d: %v", status, err)
+	}
+
+	if want := dir + "\n0027\n"; stdout.String() != want {
+		t.Errorf("output = %q, want %q", stdout.String(), want)
+	}
+
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	if mask == 0o027 {
+		t.Errorf("umask not restored")
+	}
+}
+
+func TestSuperviseRlimit(t *testing.T) {
+	var old syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &old); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if old.Max < 128 {
+		t.Skipf("nofile hard limit: %d", old.Max)
+	}
+
+	var stdout bytes.Buffer
+
+	// lowering the hard limit of this process could not be reverted by
+	// an unprivileged process
+	r := reap.New(
+		reap.WithRlimit(syscall.RLIMIT_NOFILE, 64, 128),
+		reap.WithRlimit(syscall.RLIMIT_CORE, 0, 0),
+		reap.WithStdout(&stdout),
+	)
+
+	status, err := r.Supervise([]string{"sh", "-c", "ulimit -Sn; ulimit -Hn; ulimit -Hc"}, os.Environ())
+	if err != nil || status != 0 {
+		t.Fatalf("status = %d: %v", status, err)
+	}
+
+	if want := "64\n128\n0\n"; stdout.String() != want {
+		t.Errorf("output = %q, want %q", stdout.String(), want)
+	}
+
+	var lim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &lim); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if lim != old {
+		t.Errorf("rlimit changed: %+v, want %+v", lim, old)
+	}
+}
+
+func TestSuperviseOOMScoreAdj(t *testing.T) {
+	// raising the score adjustment does not require privileges
+	old, err := os.ReadFile("/proc/self/oom_score_adj")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.WriteFile("/proc/self/oom_score_adj", old, 0)
+
+	var stdout bytes.Buffer
+
+	r := reap.New(
+		reap.WithOOMScoreAdj(100, 300),
+		reap.WithStdout(&stdout),
+	)
+
+	status, err := r.Supervise([]string{"cat", "/proc/self/oom_score_adj"}, os.Environ())
+	if err != nil || status != 0 {
+		t.Fatalf("status = %d: %v", status, err)
+	}
+
+	if want := "300\n"; stdout.String() != want {
+		t.Errorf("child: oom_score_adj = %q, want %q", stdout.String(), want)
+	}
+
+	b, err := os.ReadFile("/proc/self/oom_score_adj")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if want := "100\n"; string(b) != want {
+		t.Errorf("self: oom_score_adj = %q, want %q", b, want)
+	}
+}
+
+func TestSuperviseNamespaces(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	var stdout bytes.Buffer
+
+	r := reap.New(
+		reap.WithNamespaces(reap.NamespaceUTS|reap.NamespaceIPC),
+		reap.WithStdout(&stdout),
+	)
+
+	cmd := []string{"readlink", "/proc/self/ns/uts", "/proc/self/ns/ipc", "/proc/self/ns/mnt"}
+
+	status, err := r.Supervise(cmd, os.Environ())
+	if err != nil || status != 0 {
+		t.Fatalf("status = %d: %v", status, err)
+	}
+
+	ns := strings.Fields(stdout.String())
+	if len(ns) != 3 {
+		t.Fatalf("namespaces: %q", stdout.String())
+	}
+
+	for i, name := range []string{"uts", "ipc", "mnt"} {
+		self, err := os.Readlink("/proc/self/ns/" + name)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if unshared := name != "mnt"; (ns[i] != self) != unshared {
+			t.Errorf("%s: namespace = %s, self = %s", name, ns[i], self)
+		}
+	}
+}
+
+func TestSuperviseSeccomp(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skip("seccomp not supported")
+	}
+
+	dir := t.TempDir()
+
+	r, err := reap.NewWithError(reap.WithSeccompProfile(&reap.SeccompProfile{
+		DefaultAction: reap.SeccompActAllow,
+		Syscalls: []reap.SeccompSyscall{
+			{Names: []string{"mkdir", "mkdirat"}, Action: reap.SeccompActErrno, ErrnoRet: uint(syscall.EACCES)},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	cmd := []string{"sh", "-c", "grep '^Seccomp:' /proc/self/status && mkdir " + dir + "/x"}
+
+	status, err := r.Supervise(cmd, os.Environ())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if status != 1 {
+		t.Errorf("status = %d, want 1", status)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "x")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("mkdir: directory created: %v", err)
+	}
+
+	if _, err := reap.NewWithError(reap.WithSeccompProfile(&reap.SeccompProfile{
+		DefaultAction: reap.SeccompActAllow,
+		Syscalls: []reap.SeccompSyscall{
+			{Names: []string{"goreaptest"}, Action: reap.SeccompActErrno},
+		},
+	})); !errors.Is(err, reap.ErrInvalidOption) {
+		t.Errorf("unknown system call: %v", err)
+	}
+
+	if _, err := reap.NewWithError(
+		reap.WithSeccompProfile(&reap.SeccompProfile{DefaultAction: reap.SeccompActAllow}),
+		reap.WithChroot(dir),
+	); !errors.Is(err, reap.ErrInvalidOption) {
+		t.Errorf("chroot: %v", err)
+	}
+
+	// the filter is installed in the foreground process only
+	r = reap.New(
+		reap.WithSeccompProfile(&reap.SeccompProfile{DefaultAction: reap.SeccompActAllow}),
+		reap.WithIgnoreSigpipe(true),
+	)
+
+	cmd = []string{"bash", "-c", "yes 2>/dev/null | true; test ${PIPESTATUS[0]} -eq 1 && grep -q '^Seccomp:[[:space:]]2' /proc/self/status"}
+
+	status, err = r.Supervise(cmd, os.Environ())
+	if err != nil || status != 0 {
+		t.Errorf("ignore sigpipe: status = %d: %v", status, err)
+	}
+
+	b, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if !bytes.Contains(b, []byte("\nSeccomp:\t0\n")) {
+		t.Errorf("seccomp filter installed in the supervisor")
+	}
+}
+
+func TestSuperviseCapabilities(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	var stdout bytes.Buffer
+
+	r := reap.New(
+		reap.WithDropCapabilities([]string{"ALL"}),
+		reap.WithAddCapabilities([]string{"NET_BIND_SERVICE", "CAP_KILL"}),
+		reap.WithStdout(&stdout),
+	)
+
+	cmd := []string{"sh", "-c", "grep -E '^Cap(Bnd|Amb):' /proc/self/status"}
+
+	status, err := r.Supervise(cmd, os.Environ())
+	if err != nil || status != 0 {
+		t.Fatalf("status = %d: %v", status, err)
+	}
+
+	if _, err := reap.NewWithError(
+		reap.WithDropCapabilities([]string{"ALL"}),
+		reap.WithRestartSignal(int(syscall.SIGHUP)),
+	); !errors.Is(err, reap.ErrInvalidOption) {
+		t.Errorf("restart signal: %v", err)
+	}
+
+	caps := uint64(1<<unix.CAP_NET_BIND_SERVICE | 1<<unix.CAP_KILL)
+	want := fmt.Sprintf("CapBnd:\t%016x\nCapAmb:\t%016x\n", caps, caps)
+
+	if stdout.String() != want {
+		t.Errorf("capabilities = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestLoadSeccompProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seccomp.json")
+
+	for _, tt := range []struct {
+		profile string
+		want    *reap.SeccompProfile
+	}{
+		{
+			`{"defaultAction": "SCMP_ACT_ERRNO", "defaultErrnoRet": 1,
+			  "syscalls": [{"names": ["read", "write"], "action": "SCMP_ACT_ALLOW"}]}`,
+			&reap.SeccompProfile{
+				DefaultAction:   reap.SeccompActErrno,
+				DefaultErrnoRet: 1,
+				Syscalls: []reap.SeccompSyscall{
+					{Names: []string{"read", "write"}, Action: reap.SeccompActAllow},
+				},
+			},
+		},
+		{
+			`{"defaultAction": "SCMP_ACT_ALLOW",
+			  "syscalls": [{"names": ["clone"], "action": "SCMP_ACT_ERRNO", "args": [{"index": 0}]}]}`,
+			nil,
+		},
+		{`{"defaultAction": `, nil},
+	} {
+		if err := os.WriteFile(path, []byte(tt.profile), 0o600); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		p, err := reap.LoadSeccompProfile(path)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s: invalid profile accepted", tt.profile)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tt.profile, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(p, tt.want) {
+			t.Errorf("%s: profile = %+v, want %+v", tt.profile, p, tt.want)
+		}
+	}
+}
+
+func TestHelperChroot(t *testing.T) {
+	if os.Getenv("GOREAP_TEST_HELPER") == "" {
+		t.Skip("helper process")
+	}
+
+	if _, err := os.Stat("/goreaptest-chroot"); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestSuperviseChroot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	root := t.TempDir()
+
+	exe, err := os.ReadFile(os.Args[0])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for name, b := range map[string][]byte{"test": exe, "goreaptest-chroot": nil} {
+		if err := os.WriteFile(filepath.Join(root, name), b, 0o755); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	r := reap.New(
+		reap.WithChroot(root),
+		reap.WithDir("/"),
+	)
+
+	status, err := r.Supervise(
+		[]string{"/test", "-test.run=^TestHelperChroot$"},
+		append(os.Environ(), "GOREAP_TEST_HELPER=1"),
+	)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Skipf("test binary is not statically linked: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	ready := mkfifo(t, "ready")
+
+	// the foreground process exits after it is adopted
+	cmd := osexec.Command("bash", "-c",
+		`(exec -a goreaptest-adopt sleep 120) & read < "$1"; exit 3`,
+		"bash", ready)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	r := reap.New(
+		reap.WithLog(func(err error) {
+			t.Log(err)
+			if strings.Contains(err.Error(), ": adopt: foreground ") {
+				go func() { _ = os.WriteFile(ready, []byte("\n"), 0) }()
+			}
+		}),
+	)
+
+	status, err := r.Adopt(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if status != 3 {
+		t.Errorf("status = %d, want 3", status)
+	}
+
+	if n := r.Stats().Reaped; n != 1 {
+		t.Errorf("reaped = %d, want 1", n)
+	}
+
+	if _, err := r.Adopt(cmd.Process.Pid); !errors.Is(err, syscall.ESRCH) {
+		t.Errorf("error = %v, want %v", err, syscall.ESRCH)
+	}
+}
+
+func TestWaitExit(t *testing.T) {
+	cmd := osexec.Command("sleep", "0.2")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer func() { _ = cmd.Wait() }()
+
+	select {
+	case <-reap.WaitExit(cmd.Process.Pid):
+	case <-time.After(5 * time.Second):
+		t.Errorf("process exit not detected")
+	}
+}
+
+func TestAdoptNotChild(t *testing.T) {
+	ready := mkfifo(t, "ready")
+
+	// the foreground process exits after it is adopted
+	cmd := osexec.Command("bash", "-c",
+		`(exec -a goreaptest-adopt sleep 120) & read < "$1"`,
+		"bash", ready)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	fg := cmd.Process.Pid
+
+	// the foreground process is handled as if it was started by
+	// another process
+	defer reap.SetWait4(func(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+		if pid == fg {
+			return 0, syscall.ECHILD
+		}
+		return syscall.Wait4(pid, ws, options, rusage)
+	})()
+
+	r := reap.New(
+		reap.WithLog(func(err error) {
+			t.Log(err)
+			if strings.Contains(err.Error(), ": adopt: foreground ") {
+				go func() { _ = os.WriteFile(ready, []byte("\n"), 0) }()
+			}
+		}),
+	)
+
+	status, err := r.Adopt(fg)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+
+	ps := process.New(process.WithSnapshot(process.SnapshotPs))
+	if running(t, ps, "goreaptest-adopt") {
+		t.Errorf("descendant of adopted process running")
+	}
+}
+
+func TestSuperviseHealthCheck(t *testing.T) {
+	for _, tt := range []struct {
+		health   string
+		onFail   reap.Action
+		policy   reap.RestartPolicy
+		cmd      string
+		status   int
+		restarts int
+	}{
+		{"true", reap.ActionExit, reap.RestartPolicy{}, "sleep 0.3", 0, 0},
+		{"false", reap.ActionSignal, reap.RestartPolicy{}, "sleep 5", 128 + int(syscall.SIGTERM), 0},
+		{"false", reap.ActionRestart, reap.RestartPolicy{MaxRetries: 1}, "sleep 5", 128 + int(syscall.SIGTERM), 1},
+		{"false", reap.ActionExit, reap.RestartPolicy{Mode: reap.RestartAlways}, "sleep 5", 128 + int(syscall.SIGTERM), 0},
+	} {
+		var mu sync.Mutex
+		failures := 0
+
+		r := reap.New(
+			reap.WithRestart(tt.policy),
+			reap.WithHealthCheck([]string{tt.health}, 20*time.Millisecond, tt.onFail),
+			reap.WithHealthRetries(2),
+			reap.WithLogger(reap.LoggerFunc(func(ev reap.LogEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				if ev.Msg == "health check failed" {
+					failures++
+				}
+			})),
+		)
+
+		status, err := r.Supervise([]string{"sh", "-c", tt.cmd}, os.Environ())
+		if err != nil {
+			t.Errorf("%s: %s: %v", tt.health, tt.onFail, err)
+		}
+
+		if status != tt.status {
+			t.Errorf("%s: %s: status = %d, want %d", tt.health, tt.onFail, status, tt.status)
+		}
+
+		if n := r.Stats().Restarts; n != tt.restarts {
+			t.Errorf("%s: %s: restarts = %d, want %d", tt.health, tt.onFail, n, tt.restarts)
+		}
+
+		mu.Lock()
+		if want := 2 * (tt.restarts + 1); tt.health == "false" && failures < want {
+			t.Errorf("%s: %s: failures = %d, want %d", tt.health, tt.onFail, failures, want)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestSuperviseExitStatus(t *testing.T) {
+	for _, tt := range []struct {
+		opts   []reap.Option
+		cmd    string
+		status int
+	}{
+		{[]reap.Option{reap.WithExitStatusFromChild()}, "kill -TERM $$", 143},
+		{[]reap.Option{reap.WithExitStatusOnSignal(0)}, "kill -TERM $$", 0},
+		{[]reap.Option{reap.WithExitStatusOnSignal(0)}, "exit 3", 3},
+		{[]reap.Option{reap.WithSuccessStatuses([]int{3, 143})}, "exit 3", 0},
+		{[]reap.Option{reap.WithSuccessStatuses([]int{3, 143})}, "kill -TERM $$", 0},
+		{[]reap.Option{reap.WithSuccessStatuses([]int{3}), reap.WithExitStatusOnSignal(1)}, "kill -TERM $$", 1},
+		{[]reap.Option{reap.WithSuccessStatuses([]int{3}), reap.WithExitStatusFromChild()}, "exit 3", 3},
+	} {
+		r := reap.New(tt.opts...)
+
+		status, err := r.Supervise([]string{"sh", "-c", tt.cmd}, os.Environ())
+		if err != nil {
+			t.Errorf("%s: %v", tt.cmd, err)
+		}
+
+		if status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.cmd, status, tt.status)
+		}
+	}
+}
+
+func TestSuperviseContinueStopped(t *testing.T) {
+	r := reap.New(
+		reap.WithContinueStopped(true),
+		reap.WithDeadline(5*time.Second),
+		reap.WithDelay(100*time.Millisecond),
+	)
+
+	status, err := r.Supervise([]string{
+		"bash", "-c",
+		"(exec -a goreaptest-stopped sleep 120) & p=$!; kill -STOP $p; until grep -q ') T ' /proc/$p/stat; do :; done",
+	}, os.Environ())
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if status != 0 {
+		t.Errorf("status = %d, want 0", status)
+	}
+
+	st := r.Stats()
+
+	if st.Killed || st.Signaled[syscall.SIGTERM] != 1 {
+		t.Errorf("stopped subprocess not terminated: %s", st.Statuses())
+	}
+
+	if st.Duration > 2*time.Second {
+		t.Errorf("duration = %s", st.Duration)
+	}
+}
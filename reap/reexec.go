@@ -0,0 +1,63 @@
+package reap
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReexecEnv is set in the environment of a re-executed process. The
+// re-executed process will not re-execute itself again, preventing
+// re-exec loops.
+//
+// Re-execution is a fallback for environments where the process cannot
+// be set as a subreaper (see WithReexec) or runs processes in a new PID
+// namespace (see WithNewPidNamespace):
+//
+//   - the current executable is run with the arguments of the current
+//     process (os.Args): the command passed to Supervise is ignored
+//
+//   - the re-executed process runs in new PID and mount namespaces. If
+//     the process is not running as root, a user namespace mapping root
+//     to the current uid and gid is also created: the command runs as
+//     root in the namespace. Creating namespaces may be disallowed by
+//     the system.
+//
+//   - the re-executed process mounts a private procfs on /proc. If the
+//     mount fails, the re-executed process exits without running the
+//     command.
+const ReexecEnv = "GOREAP_REEXEC"
+
+func (r *Reap) needsReexec() bool {
+	if os.Getenv(ReexecEnv) != "" {
+		return false
+	}
+	return r.pidns || r.hostname != "" || r.reexec && !subreaperGet()
+}
+
+// reexecInit runs the command in a re-executed process. Process
+// enumeration requires the procfs for the PID namespace: if the procfs
+// cannot be mounted, the command is not run.
+func (r *supervision) reexecInit(argv []string, env []string) (int, error) {
+	if os.Getpid() == 1 {
+		if err := mountProc(); err != nil {
+			return 111, fmt.Errorf("%s: %w", ReexecEnv, err)
+		}
+		if err := r.sethostname(); err != nil {
+			return 111, fmt.Errorf("%s: %w", ReexecEnv, err)
+		}
+	}
+
+	return r.exec(argv, withoutEnv(env, ReexecEnv))
+}
+
+func withoutEnv(env []string, key string) []string {
+	e := make([]string, 0, len(env))
+	for _, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			continue
+		}
+		e = append(e, kv)
+	}
+	return e
+}
@@ -0,0 +1,54 @@
+package reap
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// reexecv runs the current executable as the init process of a new PID
+// namespace.
+func (r *supervision) reexecv(env []string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 127, err
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = r.stdout
+	cmd.Stderr = r.stderr
+	cmd.Env = append(withoutEnv(env, ReexecEnv), ReexecEnv+"=1")
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Pdeathsig:  syscall.SIGKILL,
+		Cloneflags: syscall.CLONE_NEWPID | syscall.CLONE_NEWNS | cloneflags(r.namespaces),
+	}
+
+	if r.hostname != "" {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUTS
+	}
+
+	if uid, gid := os.Getuid(), os.Getgid(); uid != 0 {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: uid, Size: 1},
+		}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: gid, Size: 1},
+		}
+	}
+
+	return r.run(cmd)
+}
+
+func mountProc() error {
+	if err := syscall.Mount("none", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("mount: /: %w", err)
+	}
+	if err := syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount: /proc: %w", err)
+	}
+	return nil
+}
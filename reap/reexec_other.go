@@ -0,0 +1,18 @@
+//go:build !linux
+
+package reap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// reexecv is not supported on this platform: PID namespaces are
+// specific to Linux.
+func (r *supervision) reexecv(env []string) (int, error) {
+	return 111, fmt.Errorf("%s: %w", ReexecEnv, syscall.ENOSYS)
+}
+
+func mountProc() error {
+	return syscall.ENOSYS
+}
@@ -0,0 +1,26 @@
+package reap
+
+// RlimitInfinity is an unlimited resource limit (see WithRlimit).
+const RlimitInfinity = ^uint64(0)
+
+// rlimit is a resource limit of the foreground process.
+type rlimit struct {
+	resource int
+	soft     uint64
+	hard     uint64
+}
+
+// WithRlimit sets a resource limit of the foreground process, e.g.,
+// unix.RLIMIT_NOFILE or unix.RLIMIT_CORE (see setrlimit(2)). The option
+// may be repeated.
+//
+// The foreground process is started by running the current executable
+// as a shim: the shim sets the limits and executes the command. The
+// limits of this process are not changed. The executable must be
+// reachable by the foreground process: resource limits cannot be used
+// with WithChroot. Resource limits are not supported on Windows.
+func WithRlimit(resource int, soft, hard uint64) Option {
+	return func(r *Reap) {
+		r.rlimits = append(r.rlimits, rlimit{resource: resource, soft: soft, hard: hard})
+	}
+}
@@ -0,0 +1,11 @@
+//go:build dragonfly || freebsd
+
+package reap
+
+import "syscall"
+
+// sysRlimit returns a resource limit: limits are signed on this
+// platform.
+func sysRlimit(soft, hard uint64) *syscall.Rlimit {
+	return &syscall.Rlimit{Cur: int64(soft), Max: int64(hard)}
+}
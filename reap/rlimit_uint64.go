@@ -0,0 +1,10 @@
+//go:build !windows && !dragonfly && !freebsd
+
+package reap
+
+import "syscall"
+
+// sysRlimit returns a resource limit.
+func sysRlimit(soft, hard uint64) *syscall.Rlimit {
+	return &syscall.Rlimit{Cur: soft, Max: hard}
+}
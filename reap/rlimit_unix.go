@@ -0,0 +1,67 @@
+//go:build !windows
+
+package reap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// rlimitExecEnv is set in the environment of the shim (see
+// shimCommand). The value is a comma separated list of resource limits:
+// "<resource>:<soft>:<hard>".
+const rlimitExecEnv = "GOREAP_RLIMIT"
+
+// rlimValue returns the system value of a resource limit.
+func rlimValue(v uint64) uint64 {
+	if v == RlimitInfinity {
+		return unix.RLIM_INFINITY
+	}
+	return v
+}
+
+// rlimitEncode encodes the resource limits of the foreground process.
+func (r *Reap) rlimitEncode() string {
+	limits := make([]string, 0, len(r.rlimits))
+	for _, lim := range r.rlimits {
+		limits = append(limits, fmt.Sprintf("%d:%d:%d",
+			lim.resource, rlimValue(lim.soft), rlimValue(lim.hard)))
+	}
+	return strings.Join(limits, ",")
+}
+
+// rlimitShim sets the resource limits of the shim: the limits are
+// inherited by the executed command.
+func rlimitShim(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		f := strings.Split(s, ":")
+		if len(f) != 3 {
+			return fmt.Errorf("%s: invalid value: %s", rlimitExecEnv, s)
+		}
+
+		resource, err := strconv.Atoi(f[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", rlimitExecEnv, err)
+		}
+
+		soft, err := strconv.ParseUint(f[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rlimitExecEnv, err)
+		}
+
+		hard, err := strconv.ParseUint(f[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rlimitExecEnv, err)
+		}
+
+		if err := syscall.Setrlimit(resource, sysRlimit(soft, hard)); err != nil {
+			return fmt.Errorf("rlimit: %d: %w", resource, err)
+		}
+	}
+
+	return nil
+}
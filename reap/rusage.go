@@ -0,0 +1,30 @@
+package reap
+
+import (
+	"syscall"
+	"time"
+)
+
+// Rusage is the resource usage of a reaped process.
+type Rusage struct {
+	// User is the CPU time spent in user mode.
+	User time.Duration
+	// System is the CPU time spent in kernel mode.
+	System time.Duration
+	// MaxRSS is the maximum resident set size in bytes (0 if not
+	// supported by the operating system).
+	MaxRSS int64
+}
+
+// WithRusageHandler sets a function called with the pid, wait status and
+// resource usage of each subprocess reaped by this process. See
+// WithExitHandler.
+func WithRusageHandler(f func(pid int, status syscall.WaitStatus, ru Rusage)) Option {
+	return func(r *Reap) {
+		if f == nil {
+			r.rusageHandler = func(int, syscall.WaitStatus, Rusage) {}
+			return
+		}
+		r.rusageHandler = f
+	}
+}
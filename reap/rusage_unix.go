@@ -0,0 +1,24 @@
+//go:build !windows
+
+package reap
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// rusageOf converts the resource usage returned by wait4.
+func rusageOf(ru *syscall.Rusage) Rusage {
+	maxrss := int64(ru.Maxrss)
+	// ru_maxrss is in kilobytes except on darwin
+	if runtime.GOOS != "darwin" {
+		maxrss *= 1024
+	}
+
+	return Rusage{
+		User:   time.Duration(ru.Utime.Nano()),
+		System: time.Duration(ru.Stime.Nano()),
+		MaxRSS: maxrss,
+	}
+}
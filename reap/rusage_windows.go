@@ -0,0 +1,19 @@
+package reap
+
+import (
+	"syscall"
+	"time"
+)
+
+// rusageOf converts the process times returned by GetProcessTimes.
+func rusageOf(ru *syscall.Rusage) Rusage {
+	return Rusage{
+		User:   filetimeDuration(ru.UserTime),
+		System: filetimeDuration(ru.KernelTime),
+	}
+}
+
+// filetimeDuration converts a FILETIME interval in 100 nanosecond units.
+func filetimeDuration(ft syscall.Filetime) time.Duration {
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
@@ -0,0 +1,120 @@
+package reap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SeccompAction is the action taken when a process makes a system call
+// matched by a seccomp filter. Actions are named as in the OCI runtime
+// specification.
+type SeccompAction string
+
+const (
+	// SeccompActAllow allows the system call.
+	SeccompActAllow SeccompAction = "SCMP_ACT_ALLOW"
+
+	// SeccompActErrno fails the system call with an error (default
+	// EPERM).
+	SeccompActErrno SeccompAction = "SCMP_ACT_ERRNO"
+
+	// SeccompActKill kills the thread making the system call.
+	SeccompActKill SeccompAction = "SCMP_ACT_KILL"
+
+	// SeccompActKillProcess kills the process making the system call.
+	SeccompActKillProcess SeccompAction = "SCMP_ACT_KILL_PROCESS"
+
+	// SeccompActTrap sends SIGSYS to the thread making the system call.
+	SeccompActTrap SeccompAction = "SCMP_ACT_TRAP"
+
+	// SeccompActLog allows and logs the system call.
+	SeccompActLog SeccompAction = "SCMP_ACT_LOG"
+)
+
+// SeccompProfile is a seccomp filter applied to the foreground process.
+// The profile is a subset of the seccomp profiles used by container
+// runtimes: system calls are matched by name only.
+type SeccompProfile struct {
+	// DefaultAction is the action for system calls not matched by a
+	// rule.
+	DefaultAction SeccompAction `json:"defaultAction"`
+
+	// DefaultErrnoRet is the error returned by the default action if
+	// the action is SeccompActErrno (0 for EPERM).
+	DefaultErrnoRet uint `json:"defaultErrnoRet,omitempty"`
+
+	// Syscalls are the rules matching system calls. The first rule
+	// matching a system call applies.
+	Syscalls []SeccompSyscall `json:"syscalls"`
+}
+
+// SeccompSyscall is a seccomp rule applying an action to system calls.
+type SeccompSyscall struct {
+	Names  []string      `json:"names"`
+	Action SeccompAction `json:"action"`
+
+	// ErrnoRet is the error returned if the action is SeccompActErrno
+	// (0 for EPERM).
+	ErrnoRet uint `json:"errnoRet,omitempty"`
+}
+
+// WithSeccompProfile installs a seccomp filter in the foreground process
+// (see seccomp(2)). Processes are started with NO_NEW_PRIVS set (see
+// WithDisableSetuid). Seccomp filters are supported on Linux (amd64 and
+// arm64).
+//
+// The foreground process is started by running the current executable
+// as a shim: the shim installs the filter and executes the command. The
+// profile must allow the system calls made after the filter is
+// installed, i.e., execve and prlimit64. The executable must be
+// reachable by the foreground process: seccomp filters cannot be used
+// with WithChroot.
+func WithSeccompProfile(p *SeccompProfile) Option {
+	return func(r *Reap) {
+		r.seccomp = p
+	}
+}
+
+// LoadSeccompProfile reads a seccomp profile in JSON format:
+//
+//	{
+//	  "defaultAction": "SCMP_ACT_ALLOW",
+//	  "syscalls": [
+//	    {"names": ["mount", "ptrace"], "action": "SCMP_ACT_ERRNO"}
+//	  ]
+//	}
+//
+// Rules with conditions (args, includes or excludes) are not supported.
+func LoadSeccompProfile(path string) (*SeccompProfile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile struct {
+		SeccompProfile
+		Syscalls []struct {
+			SeccompSyscall
+			Args     []json.RawMessage          `json:"args"`
+			Includes map[string]json.RawMessage `json:"includes"`
+			Excludes map[string]json.RawMessage `json:"excludes"`
+		} `json:"syscalls"`
+	}
+
+	if err := json.Unmarshal(b, &profile); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	p := profile.SeccompProfile
+	p.Syscalls = make([]SeccompSyscall, 0, len(profile.Syscalls))
+
+	for _, sc := range profile.Syscalls {
+		if len(sc.Args) > 0 || len(sc.Includes) > 0 || len(sc.Excludes) > 0 {
+			return nil, fmt.Errorf("%s: %v: conditional rules are not supported", path, sc.Names)
+		}
+		p.Syscalls = append(p.Syscalls, sc.SeccompSyscall)
+	}
+
+	return &p, nil
+}
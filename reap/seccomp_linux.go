@@ -0,0 +1,219 @@
+package reap
+
+//go:generate go run mkseccomp.go
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// seccomp filter return values (see seccomp(2))
+const (
+	seccompRetKillProcess = 0x80000000
+	seccompRetKillThread  = 0x00000000
+	seccompRetTrap        = 0x00030000
+	seccompRetErrno       = 0x00050000
+	seccompRetLog         = 0x7ffc0000
+	seccompRetAllow       = 0x7fff0000
+)
+
+// bpfMaxInsns is the maximum length of a seccomp filter.
+const bpfMaxInsns = 4096
+
+// seccompRet returns the filter return value of an action.
+func seccompRet(action SeccompAction, errno uint) (uint32, error) {
+	switch action {
+	case SeccompActAllow:
+		return seccompRetAllow, nil
+	case SeccompActErrno:
+		if errno == 0 {
+			errno = uint(syscall.EPERM)
+		}
+		if errno > 0xffff {
+			return 0, fmt.Errorf("invalid errno: %d", errno)
+		}
+		return seccompRetErrno | uint32(errno), nil
+	case SeccompActKill:
+		return seccompRetKillThread, nil
+	case SeccompActKillProcess:
+		return seccompRetKillProcess, nil
+	case SeccompActTrap:
+		return seccompRetTrap, nil
+	case SeccompActLog:
+		return seccompRetLog, nil
+	default:
+		return 0, fmt.Errorf("invalid action: %q", action)
+	}
+}
+
+func bpfStmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
+
+// compile returns the BPF program of the profile. System calls made
+// using another architecture are killed.
+func (p *SeccompProfile) compile() ([]unix.SockFilter, error) {
+	if seccompSyscalls == nil {
+		return nil, fmt.Errorf("%s: %w", runtime.GOARCH, syscall.ENOSYS)
+	}
+
+	def, err := seccompRet(p.DefaultAction, p.DefaultErrnoRet)
+	if err != nil {
+		return nil, fmt.Errorf("default action: %w", err)
+	}
+
+	// struct seccomp_data: nr is at offset 0, arch at offset 4
+	prog := []unix.SockFilter{
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, 4),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, seccompAuditArch, 1, 0),
+		bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess),
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, 0),
+	}
+
+	if seccompSyscallLimit > 0 {
+		prog = append(prog,
+			bpfJump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, seccompSyscallLimit, 0, 1),
+			bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess),
+		)
+	}
+
+	seen := make(map[uint32]struct{})
+
+	for _, sc := range p.Syscalls {
+		ret, err := seccompRet(sc.Action, sc.ErrnoRet)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", sc.Names, err)
+		}
+		for _, name := range sc.Names {
+			nr, ok := seccompSyscalls[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown system call: %s", name)
+			}
+			if _, ok := seen[nr]; ok {
+				continue
+			}
+			seen[nr] = struct{}{}
+			prog = append(prog,
+				bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, nr, 0, 1),
+				bpfStmt(unix.BPF_RET|unix.BPF_K, ret),
+			)
+		}
+	}
+
+	prog = append(prog, bpfStmt(unix.BPF_RET|unix.BPF_K, def))
+
+	if len(prog) > bpfMaxInsns {
+		return nil, fmt.Errorf("filter too large: %d instructions", len(prog))
+	}
+
+	return prog, nil
+}
+
+// check returns an error if the profile is invalid.
+func (p *SeccompProfile) check() error {
+	if seccompSyscalls == nil {
+		// unsupported: an error is returned when the process is started
+		return nil
+	}
+	_, err := p.compile()
+	return err
+}
+
+// seccompInstall installs a seccomp filter on the calling thread. The
+// filter is inherited by executed processes.
+func seccompInstall(prog []unix.SockFilter) error {
+	if err := noNewPrivs(); err != nil {
+		return err
+	}
+
+	fprog := unix.SockFprog{
+		Len:    uint16(len(prog)),
+		Filter: &prog[0],
+	}
+
+	err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER,
+		uintptr(unsafe.Pointer(&fprog)), 0, 0)
+	runtime.KeepAlive(prog)
+	if err != nil {
+		return fmt.Errorf("prctl(PR_SET_SECCOMP): %w", err)
+	}
+
+	return nil
+}
+
+// seccompExecEnv is set in the environment of the shim (see
+// shimCommand). The value is the hex encoded BPF program of the filter.
+const seccompExecEnv = "GOREAP_SECCOMP"
+
+// seccompEnv returns the encoded seccomp filter of the foreground
+// process or an empty string if a filter is not set.
+func (r *Reap) seccompEnv() (string, error) {
+	if r.seccomp == nil {
+		return "", nil
+	}
+
+	prog, err := r.seccomp.compile()
+	if err != nil {
+		return "", fmt.Errorf("seccomp: %w", err)
+	}
+
+	return seccompEncode(prog), nil
+}
+
+// seccompShim installs the seccomp filter on the calling thread of the
+// shim.
+func seccompShim(v string) error {
+	prog, err := seccompDecode(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", seccompExecEnv, err)
+	}
+
+	if err := seccompInstall(prog); err != nil {
+		return fmt.Errorf("seccomp: %w", err)
+	}
+
+	return nil
+}
+
+// seccompEncode encodes a BPF program as a hex string.
+func seccompEncode(prog []unix.SockFilter) string {
+	b := make([]byte, 8*len(prog))
+	for i, f := range prog {
+		binary.BigEndian.PutUint16(b[8*i:], f.Code)
+		b[8*i+2] = f.Jt
+		b[8*i+3] = f.Jf
+		binary.BigEndian.PutUint32(b[8*i+4:], f.K)
+	}
+	return hex.EncodeToString(b)
+}
+
+func seccompDecode(s string) ([]unix.SockFilter, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 || len(b)%8 != 0 || len(b)/8 > bpfMaxInsns {
+		return nil, fmt.Errorf("invalid filter length: %d", len(b))
+	}
+
+	prog := make([]unix.SockFilter, len(b)/8)
+	for i := range prog {
+		prog[i] = unix.SockFilter{
+			Code: binary.BigEndian.Uint16(b[8*i:]),
+			Jt:   b[8*i+2],
+			Jf:   b[8*i+3],
+			K:    binary.BigEndian.Uint32(b[8*i+4:]),
+		}
+	}
+	return prog, nil
+}
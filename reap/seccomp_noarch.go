@@ -0,0 +1,11 @@
+//go:build !linux || (!amd64 && !arm64)
+
+package reap
+
+// seccomp filters are not supported on this platform.
+const (
+	seccompAuditArch    = 0
+	seccompSyscallLimit = 0
+)
+
+var seccompSyscalls map[string]uint32
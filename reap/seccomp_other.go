@@ -0,0 +1,25 @@
+//go:build !linux
+
+package reap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+const seccompExecEnv = "GOREAP_SECCOMP"
+
+func (p *SeccompProfile) check() error {
+	return nil
+}
+
+func (r *Reap) seccompEnv() (string, error) {
+	if r.seccomp == nil {
+		return "", nil
+	}
+	return "", fmt.Errorf("seccomp: %w", syscall.ENOSYS)
+}
+
+func seccompShim(v string) error {
+	return fmt.Errorf("seccomp: %w", syscall.ENOSYS)
+}
@@ -0,0 +1,118 @@
+//go:build !windows
+
+package reap
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"syscall"
+)
+
+// shimExecEnv is set in the environment of the current executable run
+// as a shim by the foreground process (see shimCommand). The value is
+// the disposition of SIGPIPE: "ignore" or "default".
+const shimExecEnv = "GOREAP_SHIM"
+
+// shimCommand runs the command using the current executable as a shim
+// if the foreground process has resource limits or a seccomp filter: the
+// shim sets the attributes and executes the command. The attributes of
+// this process are not changed.
+func (r *Reap) shimCommand(cmd *exec.Cmd) error {
+	if cmd.Err != nil {
+		return nil
+	}
+
+	filter, err := r.seccompEnv()
+	if err != nil {
+		return err
+	}
+
+	if len(r.rlimits) == 0 && filter == "" {
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("shim: %w", err)
+	}
+
+	// The runtime of the shim replaces an ignored SIGPIPE disposition
+	// inherited from this process (see WithIgnoreSigpipe).
+	sigpipe := "default"
+	if signal.Ignored(syscall.SIGPIPE) {
+		sigpipe = "ignore"
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+
+	for _, key := range []string{shimExecEnv, rlimitExecEnv, seccompExecEnv} {
+		env = withoutEnv(env, key)
+	}
+
+	env = append(env, shimExecEnv+"="+sigpipe)
+
+	if len(r.rlimits) > 0 {
+		env = append(env, rlimitExecEnv+"="+r.rlimitEncode())
+	}
+
+	if filter != "" {
+		env = append(env, seccompExecEnv+"="+filter)
+	}
+
+	cmd.Args = append([]string{exe, cmd.Path}, cmd.Args...)
+	cmd.Path = exe
+	cmd.Env = env
+
+	return nil
+}
+
+// shimExec sets the resource limits, installs the seccomp filter and
+// executes the command if the process is run as a shim. The arguments
+// are the path of the executable and the arguments of the command.
+func shimExec() {
+	sigpipe, ok := os.LookupEnv(shimExecEnv)
+	if !ok {
+		return
+	}
+
+	limits, hasLimits := os.LookupEnv(rlimitExecEnv)
+	filter, hasFilter := os.LookupEnv(seccompExecEnv)
+
+	for _, key := range []string{shimExecEnv, rlimitExecEnv, seccompExecEnv} {
+		os.Unsetenv(key)
+	}
+
+	if len(os.Args) < 3 {
+		os.Exit(127)
+	}
+
+	// The filter is installed on the thread calling execve.
+	runtime.LockOSThread()
+
+	if sigpipe == "ignore" {
+		signal.Ignore(syscall.SIGPIPE)
+	}
+
+	var err error
+
+	if hasLimits {
+		err = rlimitShim(limits)
+	}
+
+	if err == nil && hasFilter {
+		err = seccompShim(filter)
+	}
+
+	if err == nil {
+		err = syscall.Exec(os.Args[1], os.Args[2:], os.Environ())
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[2], err)
+	os.Exit(127)
+}
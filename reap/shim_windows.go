@@ -0,0 +1,5 @@
+package reap
+
+// shimExec is disabled on Windows: resource limits and seccomp filters
+// are not supported.
+func shimExec() {}
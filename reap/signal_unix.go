@@ -0,0 +1,30 @@
+//go:build !windows
+
+package reap
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// signals not defined on all platforms
+const (
+	sigchld  = syscall.SIGCHLD
+	sigcont  = syscall.SIGCONT
+	sigio    = syscall.SIGIO
+	sigurg   = syscall.SIGURG
+	sigwinch = syscall.SIGWINCH
+)
+
+// sysSignalName returns the name of a signal, e.g., "SIGTERM", or an
+// empty string if the signal is unknown.
+func sysSignalName(sig syscall.Signal) string {
+	return unix.SignalName(sig)
+}
+
+// sysSignalNum returns the signal for a signal name, e.g., "SIGTERM", or
+// 0 if the signal is unknown.
+func sysSignalNum(name string) syscall.Signal {
+	return unix.SignalNum(name)
+}
@@ -0,0 +1,59 @@
+package reap
+
+import "syscall"
+
+// Signals not defined on Windows are never received: the values are
+// the Linux signal numbers.
+const (
+	sigchld  = syscall.Signal(0x11)
+	sigcont  = syscall.Signal(0x12)
+	sigurg   = syscall.Signal(0x17)
+	sigwinch = syscall.Signal(0x1c)
+	sigio    = syscall.Signal(0x1d)
+)
+
+var signalNames = []struct {
+	sig  syscall.Signal
+	name string
+}{
+	{syscall.SIGHUP, "SIGHUP"},
+	{syscall.SIGINT, "SIGINT"},
+	{syscall.SIGQUIT, "SIGQUIT"},
+	{syscall.SIGILL, "SIGILL"},
+	{syscall.SIGTRAP, "SIGTRAP"},
+	{syscall.SIGABRT, "SIGABRT"},
+	{syscall.SIGBUS, "SIGBUS"},
+	{syscall.SIGFPE, "SIGFPE"},
+	{syscall.SIGKILL, "SIGKILL"},
+	{syscall.SIGSEGV, "SIGSEGV"},
+	{syscall.SIGPIPE, "SIGPIPE"},
+	{syscall.SIGALRM, "SIGALRM"},
+	{syscall.SIGTERM, "SIGTERM"},
+	{sigchld, "SIGCHLD"},
+	{sigcont, "SIGCONT"},
+	{sigurg, "SIGURG"},
+	{sigwinch, "SIGWINCH"},
+	{sigio, "SIGIO"},
+}
+
+// sysSignalName returns the name of a signal, e.g., "SIGTERM", or an
+// empty string if the signal is unknown.
+func sysSignalName(sig syscall.Signal) string {
+	for _, s := range signalNames {
+		if s.sig == sig {
+			return s.name
+		}
+	}
+	return ""
+}
+
+// sysSignalNum returns the signal for a signal name, e.g., "SIGTERM", or
+// 0 if the signal is unknown.
+func sysSignalNum(name string) syscall.Signal {
+	for _, s := range signalNames {
+		if s.name == name {
+			return s.sig
+		}
+	}
+	return 0
+}
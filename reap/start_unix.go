@@ -0,0 +1,50 @@
+//go:build !windows
+
+package reap
+
+import (
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// attrMu serializes changing the umask and OOM score adjustment of this
+// process while starting the foreground process.
+var attrMu sync.Mutex
+
+// startProcess starts a process with the umask set by WithUmask and the
+// OOM score adjustment set by WithOOMScoreAdj. A process with dropped
+// capabilities is started by the start thread and a process with
+// resource limits or a seccomp filter is run by a shim, except when
+// re-executing: the attributes are set by the re-executed process.
+func (r *Reap) startProcess(cmd *exec.Cmd) error {
+	start := cmd.Start
+	if !r.needsReexec() {
+		if err := r.shimCommand(cmd); err != nil {
+			return err
+		}
+		if r.threadAttr() {
+			start = func() error { return r.startOnThread(cmd) }
+		}
+	}
+
+	if r.umask < 0 && r.oom == nil {
+		return start()
+	}
+
+	attrMu.Lock()
+	defer attrMu.Unlock()
+
+	if r.umask >= 0 {
+		old := syscall.Umask(r.umask)
+		defer syscall.Umask(old)
+	}
+
+	restoreOOM, err := r.setChildOOMScoreAdj()
+	if err != nil {
+		return err
+	}
+	defer restoreOOM()
+
+	return start()
+}
@@ -0,0 +1,38 @@
+package reap
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// startProcess starts a suspended process: the process is resumed after
+// being assigned to the job object (see addGroup). Setting the umask,
+// resource limits, seccomp filters and capabilities is not supported on
+// Windows.
+func (r *Reap) startProcess(cmd *exec.Cmd) error {
+	if r.umask >= 0 {
+		return fmt.Errorf("umask: %w", syscall.ENOSYS)
+	}
+
+	if len(r.rlimits) > 0 {
+		return fmt.Errorf("rlimit: %w", syscall.ENOSYS)
+	}
+
+	if _, err := r.seccompEnv(); err != nil {
+		return err
+	}
+
+	if r.threadAttr() {
+		return r.startOnThread(cmd)
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = sysProcAttr()
+	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_SUSPENDED
+
+	return cmd.Start()
+}
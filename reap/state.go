@@ -0,0 +1,250 @@
+package reap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// State is the process state saved before this process is restarted.
+// After restarting, the foreground process continues to be supervised
+// and saved descendants are signaled when reaping.
+//
+// A process is identified by the pid and start time: a process with a
+// different start time is a new process reusing the pid.
+type State struct {
+	Foreground  Proc   `json:"foreground"`
+	Descendants []Proc `json:"descendants"`
+}
+
+// Proc identifies a process.
+type Proc struct {
+	Pid       int    `json:"pid"`
+	StartTime uint64 `json:"start_time"`
+}
+
+type starter interface {
+	StartTime(pid int) (uint64, error)
+}
+
+// WithStateFile sets the path of the file used to save the process state
+// when restarting. See WithRestartSignal.
+func WithStateFile(path string) Option {
+	return func(r *Reap) {
+		r.stateFile = path
+	}
+}
+
+// WithRestartSignal sets a signal to restart this process without
+// terminating subprocesses, for example, to upgrade the executable.
+//
+// When the signal is received, the process state is written to the
+// state file and the current executable is re-executed with the same
+// arguments. On startup, Supervise reads and removes the state file and
+// waits for the running foreground process instead of starting the
+// command.
+//
+// Restarting requires a state file (see WithStateFile). A signal of 0
+// (the default) disables restarting.
+func WithRestartSignal(sig int) Option {
+	return func(r *Reap) {
+		r.restartSig = syscall.Signal(sig)
+	}
+}
+
+// WriteState atomically writes the process state to a file.
+func WriteState(path string, st *State) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// ReadState reads the process state from a file.
+func ReadState(path string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &State{}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return st, nil
+}
+
+// startTime returns the start time of a process or 0 if the start time
+// is not available.
+func (r *Reap) startTime(pid int) uint64 {
+	s, ok := r.Process.(starter)
+	if !ok {
+		return 0
+	}
+	t, err := s.StartTime(pid)
+	if err != nil {
+		return 0
+	}
+	return t
+}
+
+// alive returns true if the process is running.
+func (r *Reap) alive(p Proc) bool {
+	if err := kill(p.Pid, 0); err != nil && !errors.Is(err, syscall.EPERM) {
+		return false
+	}
+	return r.startTime(p.Pid) == p.StartTime
+}
+
+// restart saves the process state and re-executes this process. If the
+// restart fails, the error is logged and the foreground process
+// continues to be supervised.
+func (r *Reap) restart(fg int) {
+	if r.stateFile == "" {
+		r.log(fmt.Errorf("%d: restart: state file not set", r.Pid()))
+		return
+	}
+
+	st := &State{
+		Foreground:  Proc{Pid: fg, StartTime: r.startTime(fg)},
+		Descendants: make([]Proc, 0),
+	}
+
+	pids, err := r.Children()
+	if err != nil {
+		r.log(err)
+		return
+	}
+
+	for _, pid := range union(pids, r.unreachable()) {
+		if pid == fg {
+			continue
+		}
+		st.Descendants = append(st.Descendants, Proc{Pid: pid, StartTime: r.startTime(pid)})
+	}
+
+	if err := WriteState(r.stateFile, st); err != nil {
+		r.log(fmt.Errorf("%d: restart: %w", r.Pid(), err))
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		r.log(fmt.Errorf("%d: restart: %w", r.Pid(), err))
+		return
+	}
+
+	r.notify("%d: restart: %s", r.Pid(), exe)
+
+	// The foreground process is sent the parent death signal when the
+	// thread which started it exits: exec from the same (locked) thread.
+	err = syscall.Exec(exe, os.Args, os.Environ())
+	r.log(fmt.Errorf("%d: restart: %w", r.Pid(), err))
+	_ = os.Remove(r.stateFile)
+}
+
+// restore reads and removes the state file. Saved processes which are
+// no longer running are discarded. If the foreground process is not
+// running, restore returns nil.
+func (r *Reap) restore() *State {
+	if r.stateFile == "" {
+		return nil
+	}
+
+	st, err := ReadState(r.stateFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			r.log(fmt.Errorf("%d: restore: %w", r.Pid(), err))
+		}
+		return nil
+	}
+
+	if err := os.Remove(r.stateFile); err != nil {
+		r.log(fmt.Errorf("%d: restore: %w", r.Pid(), err))
+	}
+
+	r.orphans.mu.Lock()
+	if r.orphans.pids == nil {
+		r.orphans.pids = make(map[int]struct{})
+	}
+	for _, p := range st.Descendants {
+		if r.alive(p) {
+			r.orphans.pids[p.Pid] = struct{}{}
+		}
+	}
+	r.orphans.mu.Unlock()
+
+	if !r.alive(st.Foreground) {
+		return nil
+	}
+
+	return st
+}
+
+// exitStatus is the status of an exited process.
+type exitStatus syscall.WaitStatus
+
+func (ws exitStatus) Error() string {
+	return DescribeStatus(syscall.WaitStatus(ws))
+}
+
+func (ws exitStatus) Sys() interface{} {
+	return syscall.WaitStatus(ws)
+}
+
+// resume supervises a running foreground process.
+func (r *supervision) resume(st *State) (int, error) {
+	fg := st.Foreground.Pid
+
+	r.notify("%d: resume: foreground %d", r.Pid(), fg)
+
+	return r.waitpid(fg, waitChild(fg), nil)
+}
+
+// waitChild waits for a child process to exit. The error sent to the
+// channel is nil if the process exited with status 0 or contains the
+// process status.
+func waitChild(pid int) <-chan error {
+	waitch := make(chan error, 1)
+	go func() {
+		for {
+			var ws syscall.WaitStatus
+			_, err := wait4(pid, &ws, 0, nil)
+			switch {
+			case errors.Is(err, syscall.EINTR):
+				continue
+			case err != nil:
+				waitch <- err
+			case ws.Exited() && ws.ExitStatus() == 0:
+				waitch <- nil
+			default:
+				waitch <- exitStatus(ws)
+			}
+			return
+		}
+	}()
+	return waitch
+}
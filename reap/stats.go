@@ -0,0 +1,172 @@
+package reap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Stats summarizes a supervised run. If the foreground process is
+// restarted (see WithRestart), the reaped subprocesses, exit statuses
+// and resource usage accumulate across restarts: Pid and Status refer
+// to the last run of the foreground process.
+type Stats struct {
+	// Pid is the process ID of the foreground process or 0 if the
+	// process was not started.
+	Pid int
+	// Status is the exit status returned by Supervise.
+	Status int
+	// Reaped is the number of subprocesses reaped after the foreground
+	// process exited.
+	Reaped int
+	// Duration is the time spent reaping subprocesses.
+	Duration time.Duration
+	// DeadlineExceeded is true if subprocesses were running after the
+	// deadline.
+	DeadlineExceeded bool
+	// Killed is true if subprocesses were sent SIGKILL after the
+	// deadline, the maximum number of signal passes or a repeated
+	// shutdown signal.
+	Killed bool
+	// Restarts is the number of times the foreground process was
+	// restarted (see WithRestart).
+	Restarts int
+	// Exited is the number of reaped subprocesses by exit status.
+	Exited map[int]int
+	// Signaled is the number of reaped subprocesses by the signal
+	// terminating the process.
+	Signaled map[syscall.Signal]int
+	// UserTime is the total CPU time in user mode of reaped
+	// subprocesses.
+	UserTime time.Duration
+	// SystemTime is the total CPU time in kernel mode of reaped
+	// subprocesses.
+	SystemTime time.Duration
+	// MaxRSS is the largest maximum resident set size in bytes of the
+	// reaped subprocesses.
+	MaxRSS int64
+}
+
+type stats struct {
+	mu sync.Mutex
+	Stats
+}
+
+func (s *stats) update(f func(*Stats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(&s.Stats)
+}
+
+// reaped records the status and resource usage of a reaped subprocess.
+func (s *stats) reaped(ws syscall.WaitStatus, ru Rusage) {
+	s.update(func(s *Stats) {
+		s.Reaped++
+		s.UserTime += ru.User
+		s.SystemTime += ru.System
+		if ru.MaxRSS > s.MaxRSS {
+			s.MaxRSS = ru.MaxRSS
+		}
+		switch {
+		case ws.Exited():
+			if s.Exited == nil {
+				s.Exited = make(map[int]int)
+			}
+			s.Exited[ws.ExitStatus()]++
+		case ws.Signaled():
+			if s.Signaled == nil {
+				s.Signaled = make(map[syscall.Signal]int)
+			}
+			s.Signaled[ws.Signal()]++
+		}
+	})
+}
+
+// Stats returns a summary of the last call to Supervise.
+func (r *Reap) Stats() Stats {
+	return r.stats.load()
+}
+
+// load returns a copy of the stats.
+func (s *stats) load() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.Stats
+
+	if st.Exited != nil {
+		st.Exited = make(map[int]int, len(s.Exited))
+		for k, v := range s.Exited {
+			st.Exited[k] = v
+		}
+	}
+
+	if st.Signaled != nil {
+		st.Signaled = make(map[syscall.Signal]int, len(s.Signaled))
+		for k, v := range s.Signaled {
+			st.Signaled[k] = v
+		}
+	}
+
+	return st
+}
+
+// Statuses returns a human readable summary of the exit statuses of
+// reaped subprocesses:
+//
+//	exited 0: 2, killed by SIGTERM: 1
+func (s Stats) Statuses() string {
+	codes := make([]int, 0, len(s.Exited))
+	for code := range s.Exited {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	sigs := make([]int, 0, len(s.Signaled))
+	for sig := range s.Signaled {
+		sigs = append(sigs, int(sig))
+	}
+	sort.Ints(sigs)
+
+	statuses := make([]string, 0, len(codes)+len(sigs))
+
+	for _, code := range codes {
+		statuses = append(statuses, fmt.Sprintf("exited %d: %d", code, s.Exited[code]))
+	}
+
+	for _, sig := range sigs {
+		name := sysSignalName(syscall.Signal(sig))
+		if name == "" {
+			name = fmt.Sprintf("signal %d", sig)
+		}
+		statuses = append(statuses, fmt.Sprintf("killed by %s: %d", name, s.Signaled[syscall.Signal(sig)]))
+	}
+
+	if len(statuses) == 0 {
+		return "none"
+	}
+
+	return strings.Join(statuses, ", ")
+}
+
+// String returns a one-line, human readable summary:
+//
+//	child pid 1234 exited 0; reaped 3 orphans in 1.2s (deadline not hit)
+func (s Stats) String() string {
+	orphans := "orphans"
+	if s.Reaped == 1 {
+		orphans = "orphan"
+	}
+
+	deadline := "deadline not hit"
+	if s.DeadlineExceeded {
+		deadline = "deadline hit"
+	}
+
+	return fmt.Sprintf("child pid %d exited %d; reaped %d %s in %s (%s)",
+		s.Pid, s.Status, s.Reaped, orphans,
+		s.Duration.Round(100*time.Millisecond), deadline)
+}
@@ -0,0 +1,67 @@
+package reap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DescribeStatus returns a human readable description of a process
+// status.
+func DescribeStatus(ws syscall.WaitStatus) string {
+	switch {
+	case ws.Exited():
+		return fmt.Sprintf("exited: %d", ws.ExitStatus())
+	case ws.Signaled():
+		s := fmt.Sprintf("killed by %s", signalName(ws.Signal()))
+		if ws.CoreDump() {
+			s += ", dumped core"
+		}
+		return s
+	case ws.Stopped():
+		return fmt.Sprintf("stopped by %s", signalName(ws.StopSignal()))
+	case ws.Continued():
+		return "continued"
+	default:
+		return fmt.Sprintf("unknown status: %#x", ws)
+	}
+}
+
+func signalName(sig syscall.Signal) string {
+	name := sysSignalName(sig)
+	if name == "" {
+		return fmt.Sprintf("signal %d", int(sig))
+	}
+	return fmt.Sprintf("%s (%d)", name, int(sig))
+}
+
+// validSignal returns true if the signal number is in the range of
+// signals supported by the operating system (0 to check a process
+// exists).
+func validSignal(sig syscall.Signal) bool {
+	return sig >= 0 && sig <= 64
+}
+
+// ParseSignal returns the signal for a signal name ("TERM" or "SIGTERM")
+// or number.
+func ParseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if !validSignal(syscall.Signal(n)) {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidSignal, s)
+		}
+		return syscall.Signal(n), nil
+	}
+
+	name := strings.ToUpper(s)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+
+	sig := sysSignalNum(name)
+	if sig == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidSignal, s)
+	}
+
+	return sig, nil
+}
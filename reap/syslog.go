@@ -0,0 +1,40 @@
+//go:build !windows && !plan9
+
+package reap
+
+import (
+	"errors"
+	"fmt"
+	"log/syslog"
+)
+
+// syslog address: the default is the local syslog service
+var (
+	syslogNetwork = ""
+	syslogRaddr   = ""
+)
+
+// WithSyslog writes log messages to syslog. Informational messages such
+// as signals sent and processes reaped are logged at LOG_INFO, errors at
+// LOG_WARNING.
+//
+// If the connection to syslog fails, the error is written to the
+// previously configured log function and logging is unchanged.
+func WithSyslog(tag string, facility syslog.Priority) Option {
+	return func(r *Reap) {
+		w, err := syslog.Dial(syslogNetwork, syslogRaddr, facility|syslog.LOG_INFO, tag)
+		if err != nil {
+			r.log(fmt.Errorf("syslog: %w", err))
+			return
+		}
+
+		r.logger = func(err error) {
+			var n notice
+			if errors.As(err, &n) {
+				_ = w.Info(err.Error())
+				return
+			}
+			_ = w.Warning(err.Error())
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package reap
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// groupKill is set if process groups of started processes are signaled
+// when reaping.
+const groupKill = false
+
+// sysProcAttr returns the process attributes of a started process: the
+// process is sent SIGKILL if the supervisor exits.
+func sysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		Pdeathsig: syscall.SIGKILL,
+	}
+}
+
+// noNewPrivs disallows gaining privileges using setuid executables.
+func noNewPrivs() error {
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("prctl(PR_SET_NO_NEW_PRIVS): %w", err)
+	}
+	return nil
+}
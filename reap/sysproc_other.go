@@ -0,0 +1,29 @@
+//go:build !linux && !windows
+
+package reap
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// groupKill is set if process groups of started processes are signaled
+// when reaping: on platforms without a subreaper facility, orphaned
+// subprocesses are reparented to init (or launchd) and cannot be found
+// by walking the process tree.
+const groupKill = true
+
+// sysProcAttr returns the process attributes of a started process: the
+// process is started in a new process group.
+func sysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+}
+
+// noNewPrivs is not supported on this platform.
+func noNewPrivs() error {
+	return fmt.Errorf("disable setuid: %w", unix.ENOSYS)
+}
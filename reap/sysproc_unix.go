@@ -0,0 +1,30 @@
+//go:build !windows
+
+package reap
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// credential is the user and groups of the foreground process.
+type credential = syscall.Credential
+
+// commandAttr returns the process attributes of the foreground process.
+func (r *Reap) commandAttr() (*syscall.SysProcAttr, error) {
+	attr := sysProcAttr()
+	attr.Credential = r.credential
+	attr.Chroot = r.chroot
+	if err := r.namespaceAttr(attr); err != nil {
+		return nil, err
+	}
+	if err := r.capabilityAttr(attr); err != nil {
+		return nil, err
+	}
+	return attr, nil
+}
+
+func getpgid(pid int) (int, error) {
+	return unix.Getpgid(pid)
+}
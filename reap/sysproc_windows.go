@@ -0,0 +1,50 @@
+package reap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// credential is the user and groups of the foreground process: setting
+// the user is not supported on Windows.
+type credential struct {
+	Uid    uint32
+	Gid    uint32
+	Groups []uint32
+}
+
+// sysProcAttr returns the process attributes of a started process.
+// Descendants are terminated if the supervisor exits by the job object
+// (see addGroup).
+func sysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{}
+}
+
+// commandAttr returns the process attributes of the foreground process.
+func (r *Reap) commandAttr() (*syscall.SysProcAttr, error) {
+	switch {
+	case r.credential != nil:
+		return nil, fmt.Errorf("user: %w", syscall.ENOSYS)
+	case r.chroot != "":
+		return nil, fmt.Errorf("chroot: %w", syscall.ENOSYS)
+	}
+	attr := sysProcAttr()
+	if err := r.namespaceAttr(attr); err != nil {
+		return nil, err
+	}
+	if err := r.capabilityAttr(attr); err != nil {
+		return nil, err
+	}
+	return attr, nil
+}
+
+// noNewPrivs is not supported on Windows.
+func noNewPrivs() error {
+	return fmt.Errorf("disable setuid: %w", syscall.ENOSYS)
+}
+
+// getpgid is not supported on Windows: processes are not in process
+// groups.
+func getpgid(pid int) (int, error) {
+	return 0, syscall.ENOSYS
+}
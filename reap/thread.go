@@ -0,0 +1,37 @@
+package reap
+
+import "sync"
+
+// threadMu serializes starting and stopping the start thread.
+var threadMu sync.Mutex
+
+// threadAttr returns true if the foreground process is started by the
+// start thread: the thread has process attributes which cannot be
+// restored, such as a reduced capability bounding set.
+func (r *Reap) threadAttr() bool {
+	return len(r.capDrop) > 0
+}
+
+// startSession holds the start thread while supervising. The returned
+// function ends the session: the thread exits when the last session
+// ends.
+func (r *Reap) startSession() func() {
+	if !r.threadAttr() {
+		return func() {}
+	}
+
+	threadMu.Lock()
+	r.threadUsers++
+	threadMu.Unlock()
+
+	return func() {
+		threadMu.Lock()
+		defer threadMu.Unlock()
+
+		r.threadUsers--
+		if r.threadUsers == 0 && r.thread != nil {
+			r.thread.stop()
+			r.thread = nil
+		}
+	}
+}
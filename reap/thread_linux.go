@@ -0,0 +1,80 @@
+package reap
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// startThread is a goroutine locked to a thread with process attributes
+// set by init. Processes started by the thread inherit the attributes
+// and are sent the parent death signal when the thread exits.
+type startThread struct {
+	startch chan *exec.Cmd
+	errch   chan error
+}
+
+func newStartThread(init func() error) (*startThread, error) {
+	t := &startThread{
+		startch: make(chan *exec.Cmd),
+		errch:   make(chan error),
+	}
+
+	go func() {
+		// The thread is not unlocked: the thread exits with the
+		// goroutine and is not reused by other goroutines.
+		runtime.LockOSThread()
+
+		if err := init(); err != nil {
+			t.errch <- err
+			return
+		}
+
+		t.errch <- nil
+
+		for cmd := range t.startch {
+			t.errch <- cmd.Start()
+		}
+	}()
+
+	if err := <-t.errch; err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *startThread) start(cmd *exec.Cmd) error {
+	t.startch <- cmd
+	return <-t.errch
+}
+
+func (t *startThread) stop() {
+	close(t.startch)
+}
+
+// threadInit sets the attributes of the start thread.
+func (r *Reap) threadInit() error {
+	if err := r.dropCapabilities(); err != nil {
+		return fmt.Errorf("capabilities: %w", err)
+	}
+	return nil
+}
+
+// startOnThread starts a process from the start thread, created if it is
+// not running.
+func (r *Reap) startOnThread(cmd *exec.Cmd) error {
+	threadMu.Lock()
+	if r.thread == nil {
+		t, err := newStartThread(r.threadInit)
+		if err != nil {
+			threadMu.Unlock()
+			return err
+		}
+		r.thread = t
+	}
+	t := r.thread
+	threadMu.Unlock()
+
+	return t.start(cmd)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package reap
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// startThread is not supported on this platform.
+type startThread struct{}
+
+func (t *startThread) stop() {}
+
+func (r *Reap) startOnThread(cmd *exec.Cmd) error {
+	return fmt.Errorf("capabilities: %w", syscall.ENOSYS)
+}
@@ -0,0 +1,151 @@
+package reap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/msantos/goreap/subreaper"
+)
+
+// ErrInvalidOption is returned by NewWithError if an option value is
+// invalid.
+var ErrInvalidOption = errors.New("invalid option")
+
+// subreaperErr is the error returned when setting the process as a
+// subreaper.
+var subreaperErr error
+
+// NewWithError is like New but validates the options and returns an
+// error if an option value is invalid or the process could not be set
+// as a subreaper (unless re-executing in a PID namespace: see
+// WithReexec).
+func NewWithError(opts ...Option) (*Reap, error) {
+	r := New(opts...)
+
+	if err := r.validate(); err != nil {
+		signal.Stop(r.sigch)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// validate returns an error if the configuration is invalid.
+func (r *Reap) validate() error {
+	invalid := func(format string, a ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidOption, fmt.Sprintf(format, a...))
+	}
+
+	if subreaperErr != nil && !r.reexec && !r.pidns && r.hostname == "" && subreaper.Supported() {
+		return fmt.Errorf("subreaper: %w", subreaperErr)
+	}
+
+	switch {
+	case r.deadline < 0:
+		return invalid("deadline: %s", r.deadline)
+	case r.delay < 0:
+		return invalid("delay: %s", r.delay)
+	case r.reapTimeout < 0:
+		return invalid("reap timeout: %s", r.reapTimeout)
+	case r.minGrace < 0:
+		return invalid("minimum grace period: %s", r.minGrace)
+	case r.checkpoint < 0:
+		return invalid("checkpoint log interval: %s", r.checkpoint)
+	case r.umask > 0o777:
+		return invalid("umask: %#o", r.umask)
+	case r.namespaces&^(NamespaceMount|NamespaceUTS|NamespaceIPC) != 0:
+		return invalid("namespaces: %s", r.namespaces)
+	case r.maxPasses < 0:
+		return invalid("maximum signal passes: %d", r.maxPasses)
+	case r.timeoutStatus < 0 || r.timeoutStatus > 255:
+		return invalid("reap timeout status: %d", r.timeoutStatus)
+	case r.killedStatus < -1 || r.killedStatus > 255:
+		return invalid("forced kill exit status: %d", r.killedStatus)
+	case r.signalStatus < -1 || r.signalStatus > 255:
+		return invalid("exit status on signal: %d", r.signalStatus)
+	case r.restartPolicy.MaxRetries < 0:
+		return invalid("restart: maximum retries: %d", r.restartPolicy.MaxRetries)
+	case r.restartPolicy.Backoff < 0:
+		return invalid("restart: backoff: %s", r.restartPolicy.Backoff)
+	case r.restartPolicy.MaxBackoff < 0:
+		return invalid("restart: maximum backoff: %s", r.restartPolicy.MaxBackoff)
+	case len(r.health.argv) > 0 && r.health.interval <= 0:
+		return invalid("health check: interval: %s", r.health.interval)
+	case r.health.retries < 1:
+		return invalid("health check: retries: %d", r.health.retries)
+	case r.health.onFail < ActionSignal || r.health.onFail > ActionExit:
+		return invalid("health check: action: %s", r.health.onFail)
+	case r.stdinOwner == StdinSupervisor && r.pty && r.stdinFile == "" && r.stdin == os.Stdin:
+		return ErrStdinConflict
+	}
+
+	for name, sig := range map[string]syscall.Signal{
+		"signal":                 r.sig,
+		"forced shutdown signal": r.forceSig,
+		"restart signal":         r.restartSig,
+	} {
+		if !validSignal(sig) {
+			return invalid("%s: %d", name, int(sig))
+		}
+	}
+
+	for status := range r.successStatus {
+		if status < 0 || status > 255 {
+			return invalid("success status: %d", status)
+		}
+	}
+
+	for _, lim := range r.rlimits {
+		if lim.soft > lim.hard {
+			return invalid("rlimit: %d: soft limit %d exceeds hard limit %d", lim.resource, lim.soft, lim.hard)
+		}
+	}
+
+	if len(r.rlimits) > 0 && r.chroot != "" {
+		return invalid("rlimit: not supported with chroot")
+	}
+
+	if r.oom != nil {
+		for _, adj := range []int{r.oom.self, r.oom.child} {
+			if adj < -1000 || adj > 1000 {
+				return invalid("oom score adj: %d", adj)
+			}
+		}
+	}
+
+	if r.seccomp != nil {
+		if err := r.seccomp.check(); err != nil {
+			return invalid("seccomp: %s", err)
+		}
+		if r.chroot != "" {
+			return invalid("seccomp: not supported with chroot")
+		}
+	}
+
+	if err := r.checkCapabilities(); err != nil {
+		return invalid("capabilities: %s", err)
+	}
+
+	if len(r.capDrop) > 0 && r.restartSig != 0 {
+		// the foreground process is sent the parent death signal when
+		// the start thread exits on restart
+		return invalid("capabilities: not supported with a restart signal")
+	}
+
+	for from, to := range r.signalMap {
+		if !validSignal(to) {
+			return invalid("signal map: %s: %d", from, int(to))
+		}
+	}
+
+	for _, step := range r.escalation {
+		if !validSignal(step.Signal) || step.Timeout < 0 {
+			return invalid("escalation: %d:%s", int(step.Signal), step.Timeout)
+		}
+	}
+
+	return nil
+}
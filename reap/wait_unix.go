@@ -0,0 +1,9 @@
+//go:build !windows
+
+package reap
+
+import "syscall"
+
+const wnohang = syscall.WNOHANG
+
+var wait4 = syscall.Wait4
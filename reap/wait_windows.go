@@ -0,0 +1,55 @@
+package reap
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+const wnohang = 0x1
+
+var wait4 = waitProcess
+
+// waitProcess waits for a process to exit using the process handle. Exited
+// processes do not need to be reaped on Windows: waiting for any process
+// (pid -1) returns ECHILD.
+func waitProcess(pid int, ws *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+	if pid <= 0 {
+		return 0, syscall.ECHILD
+	}
+
+	h, err := windows.OpenProcess(windows.SYNCHRONIZE|windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return 0, syscall.ECHILD
+	}
+	defer windows.CloseHandle(h)
+
+	timeout := uint32(windows.INFINITE)
+	if options&wnohang != 0 {
+		timeout = 0
+	}
+
+	event, err := windows.WaitForSingleObject(h, timeout)
+	switch {
+	case err != nil:
+		return 0, err
+	case event == uint32(windows.WAIT_TIMEOUT):
+		return 0, nil
+	}
+
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return 0, err
+	}
+
+	*ws = syscall.WaitStatus{ExitCode: code}
+
+	if rusage != nil {
+		if err := syscall.GetProcessTimes(syscall.Handle(h), &rusage.CreationTime,
+			&rusage.ExitTime, &rusage.KernelTime, &rusage.UserTime); err != nil {
+			return 0, err
+		}
+	}
+
+	return pid, nil
+}
@@ -0,0 +1,43 @@
+package subreaper
+
+const (
+	REAPER_STATUS_OWNED    = 0x00000001 // process has acquired reaper status
+	REAPER_STATUS_REALINIT = 0x00000002 // process is the root of the reaper tree
+)
+
+// ReapStatus is the reaper status of a process. On FreeBSD, ReapStatus
+// is the procctl(2) PROC_REAP_STATUS result. On Linux, the status is
+// computed from the subreaper flag. On Linux and DragonFly, the number
+// of children and descendants is not reported (see
+// process.Descendants).
+type ReapStatus struct {
+	Flags       uint32 // REAPER_STATUS_* flags
+	Children    uint32 // number of children of the reaper
+	Descendants uint32 // total number of descendants of the reaper
+	Reaper      int32  // pid of the reaper for the process
+	Pid         int32  // pid of the first child of the reaper
+	pad0        [15]uint32
+}
+
+// Owned indicates the process has acquired reaper status.
+func (s *ReapStatus) Owned() bool {
+	return s.Flags&REAPER_STATUS_OWNED != 0
+}
+
+// RealInit indicates the process is the root of the reaper tree (the
+// real init).
+func (s *ReapStatus) RealInit() bool {
+	return s.Flags&REAPER_STATUS_REALINIT != 0
+}
+
+// Reaper returns the pid of the reaper for the current process and
+// whether the reaper is the real init. If the current process has
+// acquired reaper status, the pid is the process ID of the current
+// process.
+func Reaper() (pid int, realinit bool, err error) {
+	status, err := Status()
+	if err != nil {
+		return 0, false, err
+	}
+	return int(status.Reaper), status.RealInit(), nil
+}
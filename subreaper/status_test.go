@@ -0,0 +1,75 @@
+//go:build linux || freebsd || dragonfly
+
+package subreaper_test
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/msantos/goreap/subreaper"
+)
+
+func TestStatus(t *testing.T) {
+	status, err := subreaper.Status()
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if !status.Owned() {
+		t.Errorf("flags = %x, want REAPER_STATUS_OWNED", status.Flags)
+	}
+	if status.RealInit() {
+		t.Errorf("flags = %x, unexpected REAPER_STATUS_REALINIT", status.Flags)
+	}
+}
+
+func TestReaper(t *testing.T) {
+	pid, realinit, err := subreaper.Reaper()
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if pid != os.Getpid() {
+		t.Errorf("reaper = %d, want %d", pid, os.Getpid())
+	}
+	if realinit {
+		t.Errorf("reaper is real init")
+	}
+}
+
+func TestStatusDescendants(t *testing.T) {
+	if runtime.GOOS != "freebsd" {
+		t.Skip("descendants not reported")
+	}
+
+	cmd := exec.Command("sh", "-c", "sleep 60 & wait")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer func() {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		_ = cmd.Wait()
+	}()
+
+	// wait for the grandchild to start
+	for i := 0; i < 50; i++ {
+		status, err := subreaper.Status()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if status.Descendants >= 2 {
+			if status.Children < 1 || status.Pid == 0 {
+				t.Errorf("unexpected status: %+v", status)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Errorf("descendants not found")
+}
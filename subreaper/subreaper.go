@@ -1,19 +1,45 @@
-//go:build !linux && !freebsd
+//go:build !linux && !freebsd && !dragonfly
 
 // Package subreaper sets the process as the init for descendant
 // processes.
 package subreaper
 
-import (
-	"golang.org/x/sys/unix"
-)
+import "syscall"
 
-// Set is disabled on this platform.
+// Supported returns false on this platform: orphaned subprocesses are
+// reparented to init and cannot be waited for.
+func Supported() bool {
+	return false
+}
+
+// Set is disabled on this platform. Platforms without a subreaper
+// facility, e.g., NetBSD and OpenBSD, reparent orphaned processes to
+// init.
 func Set() error {
-	return unix.ENOSYS
+	return syscall.ENOSYS
+}
+
+// Release is disabled on this platform.
+func Release() error {
+	return syscall.ENOSYS
 }
 
 // Get always returns false on this platform.
 func Get() bool {
 	return false
 }
+
+// Kill is disabled on this platform.
+func Kill(sig syscall.Signal) (int, error) {
+	return 0, syscall.ENOSYS
+}
+
+// Pids is disabled on this platform.
+func Pids() ([]int, error) {
+	return nil, syscall.ENOSYS
+}
+
+// Status is disabled on this platform.
+func Status() (*ReapStatus, error) {
+	return &ReapStatus{}, syscall.ENOSYS
+}
@@ -0,0 +1,124 @@
+// Package subreaper sets the process as the init for descendant
+// processes.
+package subreaper
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	P_PID = 0
+
+	PROC_REAP_ACQUIRE = 1 // reaping enable
+	PROC_REAP_RELEASE = 2 // reaping disable
+	PROC_REAP_STATUS  = 3 // reaping status
+)
+
+// reaperStatus is the procctl(2) PROC_REAP_STATUS result.
+type reaperStatus struct {
+	Flags     uint32 // REAPER_STATUS_* flags
+	Refs      uint32 // number of processes under the reaper
+	reserved1 [15]int64
+	PidHead   int32 // pid of the first process under the reaper
+	reserved2 [15]int32
+}
+
+// Supported indicates whether the platform supports setting the process
+// as a subreaper.
+func Supported() bool {
+	return true
+}
+
+// Set configures the process as a subreaper.
+func Set() error {
+	_, _, errno := syscall.Syscall6(
+		unix.SYS_PROCCTL,  // trap
+		P_PID,             // idtype
+		0,                 // id
+		PROC_REAP_ACQUIRE, // cmd
+		0,                 // data
+		0,
+		0,
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Release clears the reaper status of the process. The reaper status is
+// preserved across execve(2).
+func Release() error {
+	_, _, errno := syscall.Syscall6(
+		unix.SYS_PROCCTL,  // trap
+		P_PID,             // idtype
+		0,                 // id
+		PROC_REAP_RELEASE, // cmd
+		0,                 // data
+		0,
+		0,
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Get indicates whether the current process is the init process
+// for descendant processes.
+func Get() bool {
+	status, err := Status()
+	return err == nil && status.Owned()
+}
+
+// Status returns the reaper status of the current process. The number
+// of children and descendants is not available on this platform.
+//
+// If the current process is not a reaper, the reaper is reported as
+// init (pid 1).
+func Status() (*ReapStatus, error) {
+	rs := &reaperStatus{}
+
+	_, _, errno := syscall.Syscall6(
+		unix.SYS_PROCCTL,            // trap
+		P_PID,                       // idtype
+		0,                           // id
+		PROC_REAP_STATUS,            // cmd
+		uintptr(unsafe.Pointer(rs)), // data
+		0,
+		0,
+	)
+
+	status := &ReapStatus{
+		Flags:  rs.Flags,
+		Reaper: 1,
+		Pid:    rs.PidHead,
+	}
+
+	if errno != 0 {
+		return status, errno
+	}
+
+	if status.Owned() {
+		status.Reaper = int32(os.Getpid())
+	}
+
+	return status, nil
+}
+
+// Kill is not supported on this platform: DragonFly procctl(2) does not
+// implement PROC_REAP_KILL. Subprocesses are signaled by pid.
+func Kill(sig syscall.Signal) (int, error) {
+	return 0, unix.ENOSYS
+}
+
+// Pids is not supported on this platform: DragonFly procctl(2) does not
+// implement PROC_REAP_GETPIDS. Subprocesses are discovered using
+// procfs.
+func Pids() ([]int, error) {
+	return nil, unix.ENOSYS
+}
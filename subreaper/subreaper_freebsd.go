@@ -18,10 +18,20 @@ const (
 	PROC_REAP_GETPIDS = 5 // get descendants
 	PROC_REAP_KILL    = 6 // kill descendants
 
-	REAPER_STATUS_OWNED    = 0x00000001 // process has acquired reaper status
-	REAPER_STATUS_REALINIT = 0x00000002 // process is the root of the reaper tree
+	REAPER_KILL_CHILDREN = 0x00000001 // kill direct children only
+	REAPER_KILL_SUBTREE  = 0x00000002 // kill the subtree of a child
+
+	REAPER_PIDINFO_VALID  = 0x00000001 // entry is valid
+	REAPER_PIDINFO_CHILD  = 0x00000002 // process is a direct child of the reaper
+	REAPER_PIDINFO_REAPER = 0x00000004 // process is a reaper
 )
 
+// Supported indicates whether the platform supports setting the process
+// as a subreaper.
+func Supported() bool {
+	return true
+}
+
 // Set configures the process as a subreaper.
 func Set() error {
 	_, _, errno := syscall.Syscall6(
@@ -39,22 +49,32 @@ func Set() error {
 	return nil
 }
 
+// Release clears the reaper status of the process. The reaper status is
+// preserved across execve(2).
+func Release() error {
+	_, _, errno := syscall.Syscall6(
+		unix.SYS_PROCCTL,  // trap
+		P_PID,             // idtype
+		0,                 // id
+		PROC_REAP_RELEASE, // cmd
+		0,                 // data
+		0,
+		0,
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
 // Get indicates whether the current process is the init process
 // for descendant processes.
 func Get() bool {
 	status, err := Status()
-	return err == nil && (status.Flags&REAPER_STATUS_OWNED != 0)
-}
-
-type ReapStatus struct {
-	Flags       uint
-	Children    uint
-	Descendants uint
-	Reaper      int
-	Pid         int
-	pad0        [15]uint
+	return err == nil && status.Owned()
 }
 
+// Status returns the reaper status of the current process.
 func Status() (*ReapStatus, error) {
 	status := &ReapStatus{}
 
@@ -73,3 +93,90 @@ func Status() (*ReapStatus, error) {
 	}
 	return status, nil
 }
+
+// reapKill is the procctl(2) PROC_REAP_KILL argument.
+type reapKill struct {
+	Sig     int32  // signal
+	Flags   uint32 // REAPER_KILL_* flags
+	Subtree int32  // pid of the child heading the subtree
+	Killed  uint32 // number of processes signaled
+	Fpid    int32  // pid of the first process failing to be signaled
+	pad0    [15]uint32
+}
+
+// Kill sends a signal to all descendants of the reaper and returns the
+// number of processes signaled.
+func Kill(sig syscall.Signal) (int, error) {
+	rk := &reapKill{Sig: int32(sig)}
+
+	_, _, errno := syscall.Syscall6(
+		unix.SYS_PROCCTL,            // trap
+		P_PID,                       // idtype
+		0,                           // id
+		PROC_REAP_KILL,              // cmd
+		uintptr(unsafe.Pointer(rk)), // data
+		0,
+		0,
+	)
+
+	if errno != 0 {
+		return int(rk.Killed), errno
+	}
+	return int(rk.Killed), nil
+}
+
+// reapPidInfo is a procctl(2) PROC_REAP_GETPIDS entry.
+type reapPidInfo struct {
+	Pid     int32  // pid of the descendant
+	Subtree int32  // pid of the child heading the subtree
+	Flags   uint32 // REAPER_PIDINFO_* flags
+	pad0    [15]uint32
+}
+
+// reapPids is the procctl(2) PROC_REAP_GETPIDS argument.
+type reapPids struct {
+	Count uint32
+	pad0  [15]uint32
+	Pids  *reapPidInfo
+}
+
+// Pids returns the process IDs of the descendants of the reaper.
+func Pids() ([]int, error) {
+	status, err := Status()
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Descendants == 0 {
+		return []int{}, nil
+	}
+
+	// descendants may be created between calls: the list is truncated
+	// to the number of entries
+	info := make([]reapPidInfo, status.Descendants+16)
+	rp := &reapPids{Count: uint32(len(info)), Pids: &info[0]}
+
+	_, _, errno := syscall.Syscall6(
+		unix.SYS_PROCCTL,            // trap
+		P_PID,                       // idtype
+		0,                           // id
+		PROC_REAP_GETPIDS,           // cmd
+		uintptr(unsafe.Pointer(rp)), // data
+		0,
+		0,
+	)
+
+	if errno != 0 {
+		return nil, errno
+	}
+
+	pids := make([]int, 0, len(info))
+	for _, p := range info {
+		if p.Flags&REAPER_PIDINFO_VALID == 0 {
+			continue
+		}
+		pids = append(pids, int(p.Pid))
+	}
+
+	return pids, nil
+}
@@ -0,0 +1,46 @@
+package subreaper_test
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/msantos/goreap/subreaper"
+)
+
+func TestPidsKill(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	pids, err := subreaper.Pids()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	found := false
+	for _, pid := range pids {
+		if pid == cmd.Process.Pid {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("pids = %v: %d not found", pids, cmd.Process.Pid)
+	}
+
+	n, err := subreaper.Kill(syscall.SIGKILL)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if n < 1 {
+		t.Errorf("killed = %d, want at least 1", n)
+	}
+
+	_ = cmd.Wait()
+
+	ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() || ws.Signal() != syscall.SIGKILL {
+		t.Errorf("unexpected exit: %v", cmd.ProcessState)
+	}
+}
@@ -3,16 +3,30 @@
 package subreaper
 
 import (
+	"os"
+	"syscall"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
 
+// Supported indicates whether the platform supports setting the process
+// as a subreaper.
+func Supported() bool {
+	return true
+}
+
 // Set configures the process as a subreaper.
 func Set() error {
 	return unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0)
 }
 
+// Release clears the subreaper attribute of the process. The attribute
+// is preserved across execve(2).
+func Release() error {
+	return unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 0, 0, 0, 0)
+}
+
 // Get indicates whether the current process is the init process
 // for descendant processes.
 func Get() bool {
@@ -23,3 +37,37 @@ func Get() bool {
 
 	return err == nil && arg2 == 1
 }
+
+// Status returns the reaper status of the current process. The number
+// of children and descendants is not reported on this platform.
+//
+// The subreaper attribute of other processes is not available: if the
+// current process is not a subreaper, the reaper is not reported (0).
+func Status() (*ReapStatus, error) {
+	status := &ReapStatus{}
+
+	pid := os.Getpid()
+
+	switch {
+	case pid == 1:
+		status.Flags = REAPER_STATUS_OWNED | REAPER_STATUS_REALINIT
+		status.Reaper = int32(pid)
+	case Get():
+		status.Flags = REAPER_STATUS_OWNED
+		status.Reaper = int32(pid)
+	}
+
+	return status, nil
+}
+
+// Kill is not supported on this platform: subprocesses are signaled
+// by pid.
+func Kill(sig syscall.Signal) (int, error) {
+	return 0, unix.ENOSYS
+}
+
+// Pids is not supported on this platform: subprocesses are discovered
+// using procfs.
+func Pids() ([]int, error) {
+	return nil, unix.ENOSYS
+}
@@ -1,6 +1,16 @@
 #!/usr/bin/env bats
 
-export PATH="$PWD:$PWD/cmd/goreap:$PATH"
+export PATH="$PWD:$PWD/cmd/goreap:$PWD/cmd/pstree:$PWD/cmd/subreaper:$PATH"
+
+# unshare_supported checks if namespaces can be created the way goreap
+# creates them: an unprivileged user also creates a user namespace
+# mapping root to the current user.
+unshare_supported() {
+    if [ "$(id -u)" -ne 0 ]; then
+        set -- -r "$@"
+    fi
+    unshare -p -f --mount-proc "$@" true 2>/dev/null
+}
 
 @test "exit: subprocesses terminated" {
     run goreap bash -c "(while :; do (exec -a goreaptest sleep 120) & done) & sleep 2"
@@ -22,3 +32,318 @@ export PATH="$PWD:$PWD/cmd/goreap:$PATH"
     run pgrep goreaptest
     [ "$status" -eq 1 ]
 }
+
+@test "signal: signal name" {
+    run timeout 5 goreap --deadline=10s --signal=SIGKILL bash -c "trap '' TERM; (exec -a goreaptest sleep 120) & sleep 0.2"
+    [ "$status" -eq 0 ]
+    run pgrep goreaptest
+    [ "$status" -eq 1 ]
+    run goreap --signal=FOO true
+    [ "$status" -eq 2 ]
+}
+
+@test "config: read options and command from a file" {
+    config="$BATS_TMPDIR/goreap.toml"
+    cat >"$config" <<'EOF'
+# goreap
+exit-status-on-signal = 7
+env = ["GOREAPTEST=1", "GOREAPTEST_X=2"]
+command = ["bash", "-c", "[ \"$GOREAPTEST$GOREAPTEST_X\" = 12 ] && kill -TERM $$"]
+EOF
+    run goreap -config "$config"
+    [ "$status" -eq 7 ]
+    run goreap -config "$config" -exit-status-on-signal 8
+    [ "$status" -eq 8 ]
+    run goreap -config "$config" true
+    [ "$status" -eq 0 ]
+    echo "unknown = 1" >"$config"
+    run goreap -config "$config" true
+    [ "$status" -eq 2 ]
+}
+
+@test "hooks: run commands on lifecycle events" {
+    run goreap -pre 'echo $GOREAP_EVENT' -post 'echo $GOREAP_EVENT:$GOREAP_STATUS' -on-child-exit 'echo $GOREAP_EVENT:$GOREAP_STATUS' sh -c 'sleep 120 & exit 3'
+    [ "$status" -eq 3 ]
+    [ "$output" = "$(printf 'pre-start\npost-stop:3\nchild-exit:143')" ]
+}
+
+@test "socket activation: pass file descriptors to the command" {
+    if ! command -v systemd-socket-activate >/dev/null; then
+        skip "requires systemd-socket-activate"
+    fi
+    systemd-socket-activate -l 127.0.0.1:18089 goreap sh -c 'echo $LISTEN_FDS $(test "$LISTEN_PID" = $$ && echo pid) $(test -S /dev/fd/3 && echo socket)' >"$BATS_TMPDIR/goreap.listen" &
+    sleep 0.5
+    exec 9<>/dev/tcp/127.0.0.1/18089
+    wait $!
+    exec 9>&-
+    [ "$(cat "$BATS_TMPDIR/goreap.listen")" = "1 pid socket" ]
+}
+
+@test "socket activation: the command is not a subreaper" {
+    run sh -c 'exec env LISTEN_FDS=1 LISTEN_PID=$$ goreap subreaper 3</dev/null'
+    [ "$status" -eq 1 ]
+    [ "${lines[0]}" = "subreaper: false" ]
+}
+
+@test "reap timeout: exit status" {
+    run goreap --wait --reap-timeout=1s --reap-timeout-status=99 bash -c "(exec -a goreaptest-timeout sleep 5) &"
+    pkill -f goreaptest-timeout || true
+    [ "$status" -eq 99 ]
+}
+
+@test "shell: run command string" {
+    run goreap -c "(exec -a goreaptest sleep 120) & echo hi && sleep 0.1"
+    [ "$status" -eq 0 ]
+    [ "$output" = "hi" ]
+    run pgrep goreaptest
+    [ "$status" -eq 1 ]
+}
+
+@test "subreaper: status" {
+    run subreaper
+    [ "$status" -eq 1 ]
+    [ "${lines[0]}" = "subreaper: false" ]
+}
+
+@test "pstree: select processes by name" {
+    bash -c "(exec -a goreaptest-pstree sleep 120) & exec -a goreaptest-pstree sleep 120" &
+    sleep 0.2
+    run pstree -name '^sleep$' -format json
+    pkill -f goreaptest-pstree
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ \"pid\":\ $! ]]
+    run pstree -name '^goreaptest-nomatch$'
+    [ "$status" -eq 1 ]
+}
+
+@test "summary: one-line summary on exit" {
+    run goreap -summary bash -c "(exec -a goreaptest sleep 120) & exit 3"
+    [ "$status" -eq 3 ]
+    [[ "$output" =~ ^goreap:\ child\ pid\ [0-9]+\ exited\ 3\;\ reaped\ 1\ orphan\ in\ .*\ \(deadline\ not\ hit\)$ ]]
+}
+
+@test "stdin: read from file" {
+    run goreap -stdin /dev/null cat
+    [ "$status" -eq 0 ]
+    [ "$output" = "" ]
+}
+
+@test "pid-fd: write pid to file descriptor" {
+    run goreap -pid-fd 3 sh -c 'echo $$ >&2' 3>"$BATS_TMPDIR/goreap.pid"
+    [ "$status" -eq 0 ]
+    [ "$output" = "$(cat "$BATS_TMPDIR/goreap.pid")" ]
+}
+
+@test "pdeathsig: foreground process terminated when goreap is killed" {
+    for i in $(seq 20); do
+        goreap bash -c "exec -a goreaptest sleep 120" &
+        kill -9 $!
+    done
+    sleep 1
+    run pgrep -f goreaptest
+    [ "$status" -eq 1 ]
+}
+
+@test "restart: restart command on failure" {
+    run goreap -restart on-failure -restart-max-retries 2 -restart-backoff 10ms -c 'echo run; exit 3'
+    [ "$status" -eq 3 ]
+    [ "${#lines[@]}" -eq 3 ]
+}
+
+@test "signal-map: translate forwarded signals" {
+    run goreap -signal-map INT:TERM bash -c "trap 'exit 7' TERM; trap 'exit 2' INT; (sleep 0.2; kill -INT \$PPID) & sleep 5 & wait"
+    [ "$status" -eq 7 ]
+}
+
+@test "escalation: send a sequence of signals" {
+    run goreap -escalation TERM:200ms,USR1 -delay 50ms -verbose bash -c "(trap '' TERM; exec -a goreaptest sleep 120) &"
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ escalating:\ SIGUSR1 ]]
+}
+
+@test "tty: run command in a pseudo-terminal" {
+    run goreap -tty sh -c 'test -t 0 && test -t 1 && echo tty'
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ tty ]]
+}
+
+@test "stats: print exit statuses of reaped processes" {
+    run goreap -stats bash -c "(exec -a goreaptest sleep 120) & exit 3"
+    [ "$status" -eq 3 ]
+    [ "${lines[1]}" = "goreap: restarts: 0" ]
+    [ "${lines[2]}" = "goreap: reaped: killed by SIGTERM: 1" ]
+    [[ "${lines[3]}" == "goreap: rusage: user "*", maxrss "*" KB" ]]
+    run goreap -stats -restart on-failure -restart-max-retries 1 -restart-backoff 10ms bash -c "(exec -a goreaptest sleep 120) & exit 3"
+    [ "$status" -eq 3 ]
+    [ "${lines[1]}" = "goreap: restarts: 1" ]
+    [ "${lines[2]}" = "goreap: reaped: killed by SIGTERM: 2" ]
+}
+
+@test "exclude-comm: never signal processes by name" {
+    run goreap -exclude-comm tail -reap-timeout 500ms -stats bash -c "(exec -a goreaptest sleep 120) & (exec tail -f /dev/null) &"
+    [ "$status" -eq 112 ]
+    [ "${lines[-2]}" = "goreap: reaped: killed by SIGTERM: 1" ]
+    pkill -f 'tail -f /dev/null'
+}
+
+@test "pidfile: write and remove pidfiles" {
+    pidfile="$BATS_TMPDIR/goreap.pid"
+    run goreap -pidfile "$pidfile" -child-pidfile "$pidfile.child" sh -c "sleep 0.1; test \$(cat '$pidfile.child') = \$\$ && cat '$pidfile'"
+    [ "$status" -eq 0 ]
+    [ ! -e "$pidfile" ]
+    [ ! -e "$pidfile.child" ]
+}
+
+@test "pidfile: exit if process is running" {
+    pidfile="$BATS_TMPDIR/goreap.pid"
+    echo $$ > "$pidfile"
+    run goreap -pidfile "$pidfile" true
+    rm -f "$pidfile"
+    [ "$status" -eq 111 ]
+}
+
+@test "user: run command as a user" {
+    if [ "$(id -u)" -ne 0 ]; then
+        skip "requires root"
+    fi
+    run goreap -user 65534:65534 id -u
+    [ "$status" -eq 0 ]
+    [ "$output" = "65534" ]
+}
+
+@test "env: set, unset and clear the environment" {
+    run env GOREAPTEST_A=1 GOREAPTEST_B=2 goreap -unsetenv GOREAPTEST_A -env GOREAPTEST_C=3 sh -c 'echo "${GOREAPTEST_A:-unset} $GOREAPTEST_B $GOREAPTEST_C"'
+    [ "$status" -eq 0 ]
+    [ "$output" = "unset 2 3" ]
+
+    run goreap -clearenv -env A=1 /usr/bin/env
+    [ "$status" -eq 0 ]
+    [ "$output" = "A=1" ]
+}
+
+@test "dir, umask: set the working directory and umask" {
+    run goreap -dir / -umask 077 sh -c 'pwd; umask'
+    [ "$status" -eq 0 ]
+    [ "${lines[0]}" = "/" ]
+    [ "${lines[1]}" = "0077" ]
+}
+
+@test "rlimit: set resource limits" {
+    run goreap -rlimit nofile=64:128 -rlimit core=0 sh -c 'ulimit -Sn; ulimit -Hn; ulimit -c'
+    [ "$status" -eq 0 ]
+    [ "$output" = "$(printf '64\n128\n0')" ]
+    run goreap -rlimit nofile=64:128 sh -c 'set -- $(grep "open files" /proc/$PPID/limits); echo $5'
+    [ "$status" -eq 0 ]
+    [ "$output" = "$(ulimit -Hn)" ]
+    run goreap -rlimit nofile=128:64 true
+    [ "$status" -eq 2 ]
+    run goreap -rlimit core=0 -chroot / true
+    [ "$status" -eq 2 ]
+}
+
+@test "oom-score-adj: set the OOM score adjustment" {
+    run goreap -oom-score-adj 100:300 sh -c 'cat /proc/self/oom_score_adj /proc/$PPID/oom_score_adj'
+    [ "$status" -eq 0 ]
+    [ "$output" = "$(printf '300\n100')" ]
+    run goreap -oom-score-adj 2000 true
+    [ "$status" -eq 2 ]
+}
+
+@test "cap-drop, cap-add: drop capabilities of the command" {
+    if [ "$(id -u)" -ne 0 ]; then
+        skip "requires root"
+    fi
+    run goreap -cap-drop ALL -cap-add NET_BIND_SERVICE -user 65534:65534 grep -E '^Cap(Eff|Bnd):' /proc/self/status
+    [ "$status" -eq 0 ]
+    [ "$output" = "$(printf 'CapEff:\t0000000000000400\nCapBnd:\t0000000000000400')" ]
+    run goreap -cap-drop FOO true
+    [ "$status" -eq 127 ]
+}
+
+@test "seccomp: install a seccomp filter in the command" {
+    profile="$BATS_TMPDIR/goreap.seccomp.json"
+    cat >"$profile" <<'EOF'
+{
+  "defaultAction": "SCMP_ACT_ALLOW",
+  "syscalls": [
+    {"names": ["mkdir", "mkdirat"], "action": "SCMP_ACT_ERRNO"}
+  ]
+}
+EOF
+    rm -rf "$BATS_TMPDIR/goreap.seccomp"
+    run goreap -seccomp "$profile" mkdir "$BATS_TMPDIR/goreap.seccomp"
+    [ "$status" -eq 1 ]
+    [ ! -e "$BATS_TMPDIR/goreap.seccomp" ]
+    run goreap -seccomp "$profile" sh -c 'grep "^Seccomp:" /proc/self/status /proc/$PPID/status'
+    [ "$status" -eq 0 ]
+    [[ "${lines[0]}" =~ Seccomp:.2$ ]]
+    [[ "${lines[1]}" =~ Seccomp:.0$ ]]
+}
+
+@test "pidns: run the command in a new PID namespace" {
+    if ! unshare_supported; then
+        skip "PID namespaces not supported"
+    fi
+    run goreap -pidns bash -c 'cat /proc/1/comm; (exec -a goreaptest sleep 120) &'
+    [ "$status" -eq 0 ]
+    [ "$output" = "goreap" ]
+    run pgrep goreaptest
+    [ "$status" -eq 1 ]
+    run goreap -pidns -pidfile "$BATS_TMPDIR/goreap.pidns.pid" -post 'echo post' sh -c 'cat "$1"' sh "$BATS_TMPDIR/goreap.pidns.pid"
+    [ "$status" -eq 0 ]
+    [ "${lines[0]}" -ne 1 ]
+    [ "${lines[1]}" = "post" ]
+    [ "${#lines[@]}" -eq 2 ]
+}
+
+@test "pidns: run the command in a new PID namespace as an unprivileged user" {
+    if [ "$(id -u)" -ne 0 ] || ! command -v setpriv >/dev/null; then
+        skip "requires root and setpriv"
+    fi
+    goreap="$(command -v goreap)"
+    if ! setpriv --reuid=65534 --regid=65534 --clear-groups test -x "$goreap"; then
+        skip "goreap is not accessible by an unprivileged user"
+    fi
+    if ! setpriv --reuid=65534 --regid=65534 --clear-groups unshare -p -f --mount-proc -r true 2>/dev/null; then
+        skip "user namespaces not supported"
+    fi
+    run setpriv --reuid=65534 --regid=65534 --clear-groups "$goreap" -pidns sh -c 'id -u; cat /proc/1/comm'
+    [ "$status" -eq 0 ]
+    [ "$output" = "$(printf '0\ngoreap')" ]
+}
+
+@test "unshare, hostname: run the command in new namespaces" {
+    if ! unshare_supported -u -i; then
+        skip "namespaces not supported"
+    fi
+    run goreap -pidns -unshare ipc -hostname goreaptest bash -c 'hostname; test "$(readlink /proc/self/ns/ipc)" != "$1" && echo ipc' bash "$(readlink /proc/self/ns/ipc)"
+    [ "$status" -eq 0 ]
+    [ "$output" = "$(printf 'goreaptest\nipc')" ]
+    run goreap -unshare foo true
+    [ "$status" -eq 2 ]
+}
+
+@test "adopt: supervise a running process" {
+    bash -c "(exec -a goreaptest sleep 120) & sleep 0.5" &
+    run goreap -adopt $!
+    [ "$status" -eq 0 ]
+    run pgrep -f '^goreaptest'
+    [ "$status" -eq 1 ]
+}
+
+@test "health-cmd: terminate an unhealthy command" {
+    run goreap -health-cmd false -health-interval 100ms -health-retries 2 -health-action exit sleep 10
+    [ "$status" -eq 143 ]
+}
+
+@test "e: treat exit status as success" {
+    run goreap -e 3 -e 143 sh -c "exit 3"
+    [ "$status" -eq 0 ]
+    run goreap -exit-status-on-signal 7 sh -c 'kill -TERM $$'
+    [ "$status" -eq 7 ]
+}
+
+@test "continue-stopped: terminate stopped processes" {
+    run timeout 10 goreap -continue-stopped -deadline 5s bash -c '(exec -a goreaptest sleep 120) & kill -STOP $!'
+    [ "$status" -eq 0 ]
+}